@@ -31,9 +31,15 @@ func main() {
 
 			// OpenAI Compatible API
 			e.POST("/v1/audio/speech", ho.MonadEcho1(backend.Speech))
+			e.POST("/v1/audio/transcriptions", ho.MonadEcho1(backend.Transcription))
+			e.POST("/v1/audio/translations", ho.MonadEcho1(backend.Translation))
 
 			// unSpeech API
 			e.GET("/api/voices", ho.MonadEcho1(backend.Voices))
+			e.POST("/api/transcriptions", ho.MonadEcho1(backend.CreateTranscriptionJob))
+			e.GET("/api/transcriptions/:id", ho.MonadEcho1(backend.GetTranscriptionJob))
+			e.GET("/api/realtime/transcriptions", ho.MonadEcho1(backend.RealtimeTranscription))
+			e.GET("/api/realtime/speech", ho.MonadEcho1(backend.RealtimeSpeech))
 
 			e.RouteNotFound("/*", ho.MonadEcho1(middlewares.NotFound))
 
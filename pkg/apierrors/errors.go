@@ -81,3 +81,9 @@ func NewUpstreamError(statusCode int) *Error {
 		WithTitle("Upstream Error").
 		WithReason("An error occurred while processing the request from the upstream service")
 }
+
+func NewErrUnsupportedCapability() *Error {
+	return NewError(http.StatusBadRequest, "UNSUPPORTED_CAPABILITY").
+		WithTitle("Unsupported Capability").
+		WithDetail("The requested capability is not supported by the provider")
+}
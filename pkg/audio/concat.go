@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Concat joins audio synthesized in chunks in order. WAV gets a single header covering every chunk, MP3
+// is joined frame by frame without the tags and VBR headers that would only describe a single chunk, the
+// other formats are joined as they are, which is fine for raw audio, ADTS AAC and chained Ogg streams.
+func Concat(format Format, chunks ...[]byte) ([]byte, error) {
+	switch format {
+	case FormatWAV:
+		return concatWAV(chunks)
+	case FormatMP3:
+		out := make([]byte, 0)
+		for _, chunk := range chunks {
+			out = append(out, MP3Frames(chunk)...)
+		}
+
+		return out, nil
+	default:
+		return bytes.Join(chunks, nil), nil
+	}
+}
+
+func concatWAV(chunks [][]byte) ([]byte, error) {
+	var format WAVFormat
+
+	data := make([]byte, 0)
+
+	for i, chunk := range chunks {
+		chunkFormat, chunkData, err := DecodeWAV(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", i, err)
+		}
+		if i > 0 && chunkFormat != format {
+			return nil, fmt.Errorf("chunk %d: format %+v differs from %+v", i, chunkFormat, format)
+		}
+
+		format = chunkFormat
+		data = append(data, chunkData...)
+	}
+
+	header := WAVHeader(len(data), format.SampleRate, format.Channels, format.BitsPerSample)
+	binary.LittleEndian.PutUint16(header[20:22], uint16(format.AudioFormat)) //nolint:gosec
+
+	return append(header, data...), nil
+}
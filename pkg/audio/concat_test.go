@@ -0,0 +1,70 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mp3Frame builds a 417 bytes MPEG-1 Layer III frame at 128kbps and 44.1kHz, with tag after the side
+// information when given.
+func mp3Frame(fill byte, tag string) []byte {
+	frame := bytes.Repeat([]byte{fill}, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+
+	if tag != "" {
+		copy(frame[36:], tag)
+	}
+
+	return frame
+}
+
+func TestConcat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WAV", func(t *testing.T) {
+		t.Parallel()
+
+		first := Samples{Data: []int16{1, 2, 3}, SampleRate: 24000, Channels: 1}
+		second := Samples{Data: []int16{4, 5}, SampleRate: 24000, Channels: 1}
+
+		out, err := Concat(FormatWAV, EncodeWAV(first.PCM(), 24000, 1), EncodeWAV(second.PCM(), 24000, 1))
+		require.NoError(t, err)
+
+		decoded, err := Decode(out, Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, []int16{1, 2, 3, 4, 5}, decoded.Data)
+		assert.Equal(t, EncodeWAV(append(first.PCM(), second.PCM()...), 24000, 1), out)
+	})
+
+	t.Run("WAVMismatch", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Concat(FormatWAV, EncodeWAV([]byte{0, 0}, 24000, 1), EncodeWAV([]byte{0, 0}, 16000, 1))
+		require.Error(t, err)
+	})
+
+	t.Run("MP3", func(t *testing.T) {
+		t.Parallel()
+
+		id3v2 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), make([]byte, 5)...)
+		id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+
+		first := bytes.Join([][]byte{id3v2, mp3Frame(0, "Info"), mp3Frame(1, ""), id3v1}, nil)
+		second := bytes.Join([][]byte{mp3Frame(0, "Xing"), mp3Frame(2, ""), mp3Frame(3, "")}, nil)
+
+		out, err := Concat(FormatMP3, first, second)
+		require.NoError(t, err)
+		assert.Equal(t, bytes.Join([][]byte{mp3Frame(1, ""), mp3Frame(2, ""), mp3Frame(3, "")}, nil), out)
+	})
+
+	t.Run("PCM", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Concat(FormatPCM, []byte{1, 2}, []byte{3, 4})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3, 4}, out)
+	})
+}
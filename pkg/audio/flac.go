@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+const (
+	flacBlockSize     = 4096
+	flacBitsPerSample = 16
+)
+
+// FLAC channel assignments of independently coded channels, indexed by the number of channels.
+//
+// FLAC - Format: FRAME_HEADER
+// https://xiph.org/flac/format.html#frame_header
+var flacChannels = []frame.Channels{
+	1: frame.ChannelsMono,
+	2: frame.ChannelsLR,
+	3: frame.ChannelsLRC,
+	4: frame.ChannelsLRLsRs,
+	5: frame.ChannelsLRCLsRs,
+	6: frame.ChannelsLRCLfeLsRs,
+	7: frame.ChannelsLRCLfeCsSlSr,
+	8: frame.ChannelsLRCLfeLsRsSlSr,
+}
+
+// encodeFLAC encodes the samples losslessly into FLAC, the MD5 signature in STREAMINFO is left unset
+// which decoders treat as unknown.
+func encodeFLAC(samples Samples) ([]byte, error) {
+	if samples.Channels < 1 || samples.Channels >= len(flacChannels) {
+		return nil, fmt.Errorf("FLAC supports 1 to %d channels, got %d", len(flacChannels)-1, samples.Channels)
+	}
+
+	frames := samples.Frames()
+	buffer := new(bytes.Buffer)
+
+	encoder, err := flac.NewEncoder(buffer, &meta.StreamInfo{
+		BlockSizeMin:  flacBlockSize,
+		BlockSizeMax:  flacBlockSize,
+		SampleRate:    uint32(samples.SampleRate),
+		NChannels:     uint8(samples.Channels),
+		BitsPerSample: flacBitsPerSample,
+		NSamples:      uint64(frames),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < frames; start += flacBlockSize {
+		blockSize := min(flacBlockSize, frames-start)
+
+		subframes := make([]*frame.Subframe, samples.Channels)
+		for channel := range subframes {
+			data := make([]int32, blockSize)
+			for i := range data {
+				data[i] = int32(samples.Data[(start+i)*samples.Channels+channel])
+			}
+
+			// Verbatim subframes are turned into constant or fixed predicted ones by the analysis of the encoder.
+			subframes[channel] = &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   data,
+				NSamples:  blockSize,
+			}
+		}
+
+		err = encoder.WriteFrame(&frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(blockSize),
+				SampleRate:        uint32(samples.SampleRate),
+				Channels:          flacChannels[samples.Channels],
+				BitsPerSample:     flacBitsPerSample,
+			},
+			Subframes: subframes,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
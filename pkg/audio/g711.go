@@ -0,0 +1,122 @@
+package audio
+
+// G.711 companding of signed 16-bit linear PCM, ported from the reference implementation of Sun
+// Microsystems which is the base of most implementations, e.g. the one of SoX and FFmpeg.
+//
+// G.711 : Pulse code modulation (PCM) of voice frequencies
+// https://www.itu.int/rec/T-REC-G.711
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+// Upper bounds of the 13-bit magnitude of each A-law segment.
+var alawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// EncodeULaw compands a linear sample into μ-law.
+func EncodeULaw(sample int16) byte {
+	value := int(sample)
+	sign := 0
+
+	if value < 0 {
+		value = -value
+		sign = 0x80
+	}
+
+	value = min(value, ulawClip) + ulawBias
+
+	exponent := 7 //nolint:mnd
+	for mask := 0x4000; value&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+
+	mantissa := (value >> (exponent + 3)) & 0x0F //nolint:mnd
+
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// DecodeULaw expands a μ-law sample into linear.
+func DecodeULaw(ulaw byte) int16 {
+	ulaw = ^ulaw
+
+	exponent := int(ulaw>>4) & 0x07 //nolint:mnd
+	mantissa := int(ulaw) & 0x0F    //nolint:mnd
+	value := ((mantissa << 3) + ulawBias) << exponent
+
+	if ulaw&0x80 != 0 {
+		return int16(ulawBias - value)
+	}
+
+	return int16(value - ulawBias)
+}
+
+// EncodeALaw compands a linear sample into A-law.
+func EncodeALaw(sample int16) byte {
+	value := int(sample) >> 3 //nolint:mnd
+	mask := 0xD5
+
+	if value < 0 {
+		value = -value - 1
+		mask = 0x55
+	}
+
+	segment := 0
+	for segment < len(alawSegmentEnds) && value > alawSegmentEnds[segment] {
+		segment++
+	}
+
+	if segment >= len(alawSegmentEnds) {
+		return byte(0x7F ^ mask)
+	}
+
+	alaw := segment << 4 //nolint:mnd
+	if segment < 2 {     //nolint:mnd
+		alaw |= (value >> 1) & 0x0F
+	} else {
+		alaw |= (value >> segment) & 0x0F
+	}
+
+	return byte(alaw ^ mask)
+}
+
+// DecodeALaw expands an A-law sample into linear.
+func DecodeALaw(alaw byte) int16 {
+	alaw ^= 0x55
+
+	value := int(alaw&0x0F) << 4   //nolint:mnd
+	segment := int(alaw&0x70) >> 4 //nolint:mnd
+
+	switch segment {
+	case 0:
+		value += 0x08
+	case 1:
+		value += 0x108
+	default:
+		value = (value + 0x108) << (segment - 1)
+	}
+
+	if alaw&0x80 != 0 {
+		return int16(value)
+	}
+
+	return int16(-value)
+}
+
+func decodeCompanded(data []byte, decode func(byte) int16) []int16 {
+	out := make([]int16, len(data))
+	for i, value := range data {
+		out[i] = decode(value)
+	}
+
+	return out
+}
+
+func encodeCompanded(data []int16, encode func(int16) byte) []byte {
+	out := make([]byte, len(data))
+	for i, sample := range data {
+		out[i] = encode(sample)
+	}
+
+	return out
+}
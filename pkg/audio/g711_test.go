@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestULaw(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, byte(0xFF), EncodeULaw(0))
+	assert.Equal(t, byte(0x80), EncodeULaw(32767))
+	assert.Equal(t, byte(0x00), EncodeULaw(-32768))
+	assert.Equal(t, int16(0), DecodeULaw(0xFF))
+	assert.Equal(t, int16(32124), DecodeULaw(0x80))
+	assert.Equal(t, int16(-32124), DecodeULaw(0x00))
+
+	for value := byte(0); ; value++ {
+		// 0x7F and 0xFF both decode into 0, which encodes into 0xFF
+		if value != 0x7F {
+			assert.Equal(t, value, EncodeULaw(DecodeULaw(value)))
+		}
+
+		if value == 0xFF {
+			break
+		}
+	}
+}
+
+func TestALaw(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, byte(0xD5), EncodeALaw(0))
+	assert.Equal(t, byte(0xAA), EncodeALaw(32767))
+	assert.Equal(t, byte(0x2A), EncodeALaw(-32768))
+	assert.Equal(t, int16(8), DecodeALaw(0xD5))
+	assert.Equal(t, int16(32256), DecodeALaw(0xAA))
+	assert.Equal(t, int16(-32256), DecodeALaw(0x2A))
+
+	for value := byte(0); ; value++ {
+		assert.Equal(t, value, EncodeALaw(DecodeALaw(value)))
+
+		if value == 0xFF {
+			break
+		}
+	}
+}
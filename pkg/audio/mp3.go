@@ -0,0 +1,159 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"slices"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+const (
+	id3v2HeaderSize = 10
+	id3v1TagSize    = 128
+
+	mp3ChannelModeMono = 0x03
+	mp3VersionMPEG1    = 0x03
+	mp3LayerIII        = 0x01
+)
+
+var (
+	// Bitrates of Layer III in kbps by the bitrate index, for MPEG-1 and for MPEG-2 and 2.5.
+	mp3Bitrates = [2][16]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+	// Sample rates by the version bits and the sample rate index, version 1 is reserved.
+	mp3SampleRates = map[byte][3]int{
+		mp3VersionMPEG1: {44100, 48000, 32000},
+		0x02:            {22050, 24000, 16000},
+		0x00:            {11025, 12000, 8000},
+	}
+)
+
+// mp3FirstFrame returns the offset of the first frame, ID3v2 tags in front of it are skipped, or -1.
+//
+// MPEG Audio Layer I/II/III frame header
+// http://www.mp3-tech.org/programmer/frame_header.html
+func mp3FirstFrame(data []byte) int {
+	offset := 0
+
+	if len(data) >= id3v2HeaderSize && string(data[0:3]) == "ID3" {
+		// The tag size is a 28-bit syncsafe integer, excluding the header.
+		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9]) //nolint:mnd
+		offset = id3v2HeaderSize + size
+	}
+
+	for ; offset+4 <= len(data); offset++ { //nolint:mnd
+		if data[offset] == 0xFF && data[offset+1]&0xE0 == 0xE0 {
+			return offset
+		}
+	}
+
+	return -1
+}
+
+// mp3IsMono tells whether the first frame is single channel.
+func mp3IsMono(data []byte) bool {
+	offset := mp3FirstFrame(data)
+
+	return offset >= 0 && data[offset+3]>>6 == mp3ChannelModeMono //nolint:mnd
+}
+
+// mp3FrameSize returns the size of the Layer III frame the header starts, 0 when it isn't one.
+func mp3FrameSize(header []byte) int {
+	version := (header[1] >> 3) & 0x03         //nolint:mnd
+	layer := (header[1] >> 1) & 0x03           //nolint:mnd
+	bitrateIndex := header[2] >> 4             //nolint:mnd
+	sampleRateIndex := (header[2] >> 2) & 0x03 //nolint:mnd
+	padding := int((header[2] >> 1) & 0x01)    //nolint:mnd
+
+	sampleRates, ok := mp3SampleRates[version]
+	if !ok || layer != mp3LayerIII || sampleRateIndex == 3 { //nolint:mnd
+		return 0
+	}
+
+	if version == mp3VersionMPEG1 {
+		return 144*mp3Bitrates[0][bitrateIndex]*1000/sampleRates[sampleRateIndex] + padding //nolint:mnd
+	}
+
+	return 72*mp3Bitrates[1][bitrateIndex]*1000/sampleRates[sampleRateIndex] + padding //nolint:mnd
+}
+
+// mp3IsVBRHeader tells whether the frame carries the Xing, Info or VBRI header of the encoder instead of
+// audio, its frame count and seek table only describe the file it came from.
+func mp3IsVBRHeader(frame []byte) bool {
+	mono := frame[3]>>6 == mp3ChannelModeMono //nolint:mnd
+
+	// The Xing header follows the side information, whose size depends on the version and the channels.
+	sideInfoSize := 17 //nolint:mnd
+
+	switch {
+	case (frame[1]>>3)&0x03 == mp3VersionMPEG1 && !mono: //nolint:mnd
+		sideInfoSize = 32
+	case (frame[1]>>3)&0x03 != mp3VersionMPEG1 && mono: //nolint:mnd
+		sideInfoSize = 9
+	}
+
+	// The VBRI header of the Fraunhofer encoder is always 32 bytes after the frame header.
+	return slices.Contains([]string{"Xing", "Info"}, mp3FrameTag(frame, 4+sideInfoSize)) || mp3FrameTag(frame, 36) == "VBRI" //nolint:mnd
+}
+
+func mp3FrameTag(frame []byte, offset int) string {
+	if len(frame) < offset+4 { //nolint:mnd
+		return ""
+	}
+
+	return string(frame[offset : offset+4]) //nolint:mnd
+}
+
+// MP3Frames returns the audio frames of MP3 without the ID3 tags and the VBR header frame, so that
+// audio encoded separately can be concatenated frame by frame.
+func MP3Frames(data []byte) []byte {
+	offset := mp3FirstFrame(data)
+	if offset < 0 {
+		return nil
+	}
+
+	end := len(data)
+	if end-offset >= id3v1TagSize && string(data[end-id3v1TagSize:end-id3v1TagSize+3]) == "TAG" {
+		end -= id3v1TagSize
+	}
+
+	if size := mp3FrameSize(data[offset:]); size > 0 && offset+size <= end && mp3IsVBRHeader(data[offset:offset+size]) {
+		offset += size
+	}
+
+	return data[offset:end]
+}
+
+// samplesFromMP3 decodes MP3, the decoder always produces stereo, which is folded back into mono for
+// single channel sources.
+func samplesFromMP3(data []byte) (Samples, error) {
+	decoder, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return Samples{}, err
+	}
+
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		return Samples{}, err
+	}
+
+	samples, err := SamplesFromPCM(pcm, decoder.SampleRate(), 2) //nolint:mnd
+	if err != nil {
+		return Samples{}, err
+	}
+
+	if mp3IsMono(data) {
+		mono := make([]int16, samples.Frames())
+		for i := range mono {
+			mono[i] = samples.Data[i*2] //nolint:mnd
+		}
+
+		samples.Data = mono
+		samples.Channels = 1
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleQuality trades the quality of sample rate conversion for speed.
+type ResampleQuality = string
+
+const (
+	// Linear interpolation, cheap but without any anti-aliasing.
+	ResampleQualityLow ResampleQuality = "low"
+	// Windowed sinc with 8 zero crossings.
+	ResampleQualityMedium ResampleQuality = "medium"
+	// Windowed sinc with 32 zero crossings.
+	ResampleQualityHigh ResampleQuality = "high"
+)
+
+const (
+	DefaultResampleQuality = ResampleQualityMedium
+
+	// Resolution of the precomputed kernel, in points per zero crossing.
+	sincTableResolution = 256
+)
+
+var sincZeroCrossings = map[ResampleQuality]int{
+	ResampleQualityMedium: 8,  //nolint:mnd
+	ResampleQualityHigh:   32, //nolint:mnd
+}
+
+// IsResampleQuality tells whether the quality is known, empty means the default.
+func IsResampleQuality(quality ResampleQuality) bool {
+	return quality == "" || quality == ResampleQualityLow || sincZeroCrossings[quality] != 0
+}
+
+// Resampler converts the sample rate of interleaved samples chunk by chunk, so that audio can be converted
+// while it streams. Input is kept as long as the kernel needs it, output is produced as soon as possible.
+//
+// Digital Audio Resampling Home Page
+// https://ccrma.stanford.edu/~jos/resample/
+type Resampler struct {
+	from     int
+	to       int
+	channels int
+
+	// Low pass cutoff relative to the input Nyquist frequency, below 1 when downsampling to avoid aliasing.
+	cutoff float64
+	// Kernel half width in input frames.
+	halfWidth int
+	// Windowed sinc sampled from 0 to the last zero crossing, nil for linear interpolation.
+	table      []float64
+	tableLimit float64
+
+	// Input frames not needed anymore are dropped, buffer starts at the input frame offset.
+	buffer   []float64
+	offset   int64
+	consumed int64
+	produced int64
+}
+
+// NewResampler creates a resampler from one sample rate to another for the given number of channels.
+func NewResampler(from int, to int, channels int, quality ResampleQuality) (*Resampler, error) {
+	if from <= 0 || to <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("invalid sample rates %d to %d with %d channels", from, to, channels)
+	}
+	if !IsResampleQuality(quality) {
+		return nil, fmt.Errorf("unknown resample quality %s", quality)
+	}
+
+	r := &Resampler{
+		from:      from,
+		to:        to,
+		channels:  channels,
+		cutoff:    min(1, float64(to)/float64(from)),
+		halfWidth: 1,
+	}
+
+	if zeroCrossings := sincZeroCrossings[quality]; zeroCrossings != 0 {
+		r.halfWidth = int(math.Ceil(float64(zeroCrossings) / r.cutoff))
+		r.tableLimit = float64(zeroCrossings)
+		r.table = make([]float64, zeroCrossings*sincTableResolution+2) //nolint:mnd
+
+		for i := range r.table {
+			x := float64(i) / sincTableResolution
+			r.table[i] = sinc(x) * blackman(x/float64(zeroCrossings))
+		}
+	}
+
+	return r, nil
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is the Blackman window from -1 to 1.
+func blackman(x float64) float64 {
+	if math.Abs(x) >= 1 {
+		return 0
+	}
+
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x) //nolint:mnd
+}
+
+func (r *Resampler) kernel(distance float64) float64 {
+	x := math.Abs(distance) * r.cutoff
+	if x >= r.tableLimit {
+		return 0
+	}
+
+	position := x * sincTableResolution
+	index := int(position)
+	fraction := position - float64(index)
+
+	return r.cutoff * (r.table[index] + (r.table[index+1]-r.table[index])*fraction)
+}
+
+// sample returns the input sample of the channel, silence outside of what has been written.
+func (r *Resampler) sample(frame int64, channel int) float64 {
+	index := frame - r.offset
+	if frame < 0 || index < 0 || index >= int64(len(r.buffer)/r.channels) {
+		return 0
+	}
+
+	return r.buffer[int(index)*r.channels+channel]
+}
+
+// produce writes output frames as long as the input they depend on is available, up to limit.
+func (r *Resampler) produce(available int64, limit int64) []int16 {
+	out := make([]int16, 0)
+
+	for r.produced < limit {
+		// Position of the output frame on the input timeline, in integers to avoid drifting.
+		position := r.produced * int64(r.from)
+		frame := position / int64(r.to)
+		fraction := float64(position%int64(r.to)) / float64(r.to)
+
+		if frame+int64(r.halfWidth) >= available {
+			break
+		}
+
+		for channel := range r.channels {
+			var value float64
+
+			if r.table == nil {
+				value = r.sample(frame, channel)*(1-fraction) + r.sample(frame+1, channel)*fraction
+			} else {
+				for k := frame - int64(r.halfWidth) + 1; k <= frame+int64(r.halfWidth); k++ {
+					value += r.sample(k, channel) * r.kernel(float64(frame-k)+fraction)
+				}
+			}
+
+			out = append(out, int16(math.Round(max(math.MinInt16, min(math.MaxInt16, value)))))
+		}
+
+		r.produced++
+	}
+
+	// Drop the input no output frame depends on anymore.
+	next := r.produced*int64(r.from)/int64(r.to) - int64(r.halfWidth)
+	if drop := next - r.offset; drop > 0 {
+		drop = min(drop, int64(len(r.buffer)/r.channels))
+		r.buffer = r.buffer[int(drop)*r.channels:]
+		r.offset += drop
+	}
+
+	return out
+}
+
+// Process consumes interleaved samples, whole frames are expected, and returns the output available so far.
+func (r *Resampler) Process(samples []int16) []int16 {
+	for _, sample := range samples {
+		r.buffer = append(r.buffer, float64(sample))
+	}
+
+	r.consumed += int64(len(samples) / r.channels)
+
+	return r.produce(r.consumed, math.MaxInt64)
+}
+
+// Flush returns the rest of the output, the input is considered to be followed by silence.
+func (r *Resampler) Flush() []int16 {
+	total := (r.consumed*int64(r.to) + int64(r.from) - 1) / int64(r.from)
+
+	return r.produce(math.MaxInt64, total)
+}
+
+// Resample converts the sample rate of the samples at once.
+func Resample(samples Samples, sampleRate int, quality ResampleQuality) (Samples, error) {
+	if samples.SampleRate == sampleRate {
+		return samples, nil
+	}
+
+	resampler, err := NewResampler(samples.SampleRate, sampleRate, samples.Channels, quality)
+	if err != nil {
+		return Samples{}, err
+	}
+
+	data := resampler.Process(samples.Data)
+	data = append(data, resampler.Flush()...)
+
+	return Samples{Data: data, SampleRate: sampleRate, Channels: samples.Channels}, nil
+}
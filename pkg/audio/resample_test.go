@@ -0,0 +1,158 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sine(frequency float64, sampleRate int, frames int) []int16 {
+	data := make([]int16, frames)
+	for i := range data {
+		data[i] = int16(10000 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+
+	return data
+}
+
+// goertzel returns the power of the frequency in the samples.
+func goertzel(data []int16, frequency float64, sampleRate int) float64 {
+	coefficient := 2 * math.Cos(2*math.Pi*frequency/float64(sampleRate))
+
+	var previous, beforePrevious float64
+
+	for _, sample := range data {
+		previous, beforePrevious = float64(sample)+coefficient*previous-beforePrevious, previous
+	}
+
+	return previous*previous + beforePrevious*beforePrevious - coefficient*previous*beforePrevious
+}
+
+func TestResample(t *testing.T) {
+	t.Parallel()
+
+	for _, quality := range []ResampleQuality{ResampleQualityLow, ResampleQualityMedium, ResampleQualityHigh} {
+		t.Run(quality, func(t *testing.T) {
+			t.Parallel()
+
+			for _, rates := range [][2]int{{24000, 8000}, {8000, 48000}, {44100, 16000}, {22050, 24000}} {
+				from, to := rates[0], rates[1]
+
+				samples := Samples{Data: sine(440, from, from/10), SampleRate: from, Channels: 1}
+
+				resampled, err := Resample(samples, to, quality)
+				require.NoError(t, err)
+				assert.Equal(t, to, resampled.SampleRate)
+				assert.Len(t, resampled.Data, int(math.Ceil(float64(len(samples.Data))*float64(to)/float64(from))))
+
+				// The tone is kept, away from the edges.
+				middle := resampled.Data[len(resampled.Data)/4 : len(resampled.Data)*3/4]
+				assert.Greater(t, goertzel(middle, 440, to), 100*goertzel(middle, 1000, to), "%d to %d", from, to)
+			}
+		})
+	}
+
+	t.Run("DC", func(t *testing.T) {
+		t.Parallel()
+
+		data := make([]int16, 2000)
+		for i := range data {
+			data[i] = 1234
+		}
+
+		resampled, err := Resample(Samples{Data: data, SampleRate: 16000, Channels: 1}, 44100, ResampleQualityHigh)
+		require.NoError(t, err)
+
+		for _, sample := range resampled.Data[200 : len(resampled.Data)-200] {
+			assert.InDelta(t, 1234, sample, 2)
+		}
+	})
+
+	t.Run("AntiAliasing", func(t *testing.T) {
+		t.Parallel()
+
+		// 6 kHz is above the Nyquist frequency of 8 kHz and must not fold back into 2 kHz.
+		samples := Samples{Data: sine(6000, 24000, 2400), SampleRate: 24000, Channels: 1}
+
+		resampled, err := Resample(samples, 8000, ResampleQualityHigh)
+		require.NoError(t, err)
+
+		reference := goertzel(sine(2000, 8000, len(resampled.Data)), 2000, 8000)
+		assert.Less(t, goertzel(resampled.Data, 2000, 8000), reference/1000)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		t.Parallel()
+
+		left := sine(440, 22050, 3000)
+		right := sine(880, 22050, 3000)
+		data := make([]int16, 0, len(left)*2)
+
+		for i := range left {
+			data = append(data, left[i], right[i])
+		}
+
+		whole, err := Resample(Samples{Data: data, SampleRate: 22050, Channels: 2}, 48000, ResampleQualityMedium)
+		require.NoError(t, err)
+
+		resampler, err := NewResampler(22050, 48000, 2, ResampleQualityMedium)
+		require.NoError(t, err)
+
+		streamed := make([]int16, 0, len(whole.Data))
+		for start := 0; start < len(data); start += 2 * 37 {
+			streamed = append(streamed, resampler.Process(data[start:min(len(data), start+2*37)])...)
+		}
+
+		streamed = append(streamed, resampler.Flush()...)
+		assert.Equal(t, whole.Data, streamed)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewResampler(0, 8000, 1, ResampleQualityLow)
+		require.Error(t, err)
+
+		_, err = NewResampler(16000, 8000, 1, "best")
+		require.Error(t, err)
+	})
+}
+
+func TestRemix(t *testing.T) {
+	t.Parallel()
+
+	stereo := Samples{Data: []int16{100, 300, -32768, -32768}, SampleRate: 8000, Channels: 2}
+	mono := Remix(stereo, 1)
+	assert.Equal(t, Samples{Data: []int16{200, -32768}, SampleRate: 8000, Channels: 1}, mono)
+	assert.Equal(t, []int16{200, 200, -32768, -32768}, Remix(mono, 2).Data)
+	assert.Equal(t, stereo, Remix(stereo, 2))
+}
+
+func TestConvertReader(t *testing.T) {
+	t.Parallel()
+
+	samples := Samples{Data: sine(440, 24000, 4801), SampleRate: 24000, Channels: 1}
+
+	expected, err := Transcode(samples.PCM(), Spec{Format: FormatPCM, SampleRate: 24000, Channels: 1}, Spec{Format: FormatULaw, SampleRate: 8000, Channels: 2}, ResampleQualityMedium)
+	require.NoError(t, err)
+
+	reader, err := NewConvertReader(
+		iotest.OneByteReader(bytes.NewReader(samples.PCM())),
+		Spec{Format: FormatPCM, SampleRate: 24000, Channels: 1},
+		Spec{Format: FormatULaw, SampleRate: 8000, Channels: 2},
+		ResampleQualityMedium,
+	)
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, expected, out)
+
+	_, err = NewConvertReader(bytes.NewReader(nil), Spec{Format: FormatWAV}, Spec{Format: FormatPCM}, ResampleQualityLow)
+	require.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Samples is decoded audio as interleaved signed 16-bit linear PCM, the intermediate representation
+// every format is converted from and into.
+type Samples struct {
+	Data       []int16
+	SampleRate int
+	Channels   int
+}
+
+// Frames returns the number of samples per channel.
+func (s Samples) Frames() int {
+	if s.Channels == 0 {
+		return 0
+	}
+
+	return len(s.Data) / s.Channels
+}
+
+// PCM returns the samples as signed 16-bit little-endian bytes.
+func (s Samples) PCM() []byte {
+	out := make([]byte, len(s.Data)*2) //nolint:mnd
+	for i, sample := range s.Data {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(sample)) //nolint:mnd
+	}
+
+	return out
+}
+
+// SamplesFromPCM reads signed 16-bit little-endian bytes, a trailing odd byte is dropped.
+func SamplesFromPCM(pcm []byte, sampleRate int, channels int) (Samples, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return Samples{}, errors.New("sample rate and channels are required for raw audio")
+	}
+
+	data := make([]int16, len(pcm)/2) //nolint:mnd
+	for i := range data {
+		data[i] = int16(binary.LittleEndian.Uint16(pcm[i*2:])) //nolint:mnd
+	}
+
+	return Samples{Data: data, SampleRate: sampleRate, Channels: channels}, nil
+}
+
+// Remix changes the number of channels, downmixing into mono averages every channel, otherwise output
+// channels are taken from the input ones in turn, e.g. mono is duplicated into stereo.
+func Remix(samples Samples, channels int) Samples {
+	if samples.Channels == channels || samples.Channels == 0 || channels <= 0 {
+		return samples
+	}
+
+	frames := samples.Frames()
+	data := make([]int16, frames*channels)
+
+	for frame := range frames {
+		in := samples.Data[frame*samples.Channels : (frame+1)*samples.Channels]
+
+		if channels == 1 {
+			var sum int
+
+			for _, sample := range in {
+				sum += int(sample)
+			}
+
+			data[frame] = int16(sum / samples.Channels)
+
+			continue
+		}
+
+		for channel := range channels {
+			data[frame*channels+channel] = in[channel%samples.Channels]
+		}
+	}
+
+	return Samples{Data: data, SampleRate: samples.SampleRate, Channels: channels}
+}
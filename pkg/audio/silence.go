@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Silence generates a pause of the duration in the same format, sample rate and channels as reference,
+// audio synthesized by the provider, so that it can be concatenated with it. Only WAV and MP3 carry
+// enough about their layout to do so.
+func Silence(format Format, reference []byte, duration time.Duration) ([]byte, error) {
+	switch format {
+	case FormatWAV:
+		return wavSilence(reference, duration)
+	case FormatMP3:
+		return mp3Silence(reference, duration)
+	default:
+		return nil, fmt.Errorf("generating silence in %s is not supported", format)
+	}
+}
+
+func wavSilence(reference []byte, duration time.Duration) ([]byte, error) {
+	format, _, err := DecodeWAV(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := int(math.Round(duration.Seconds() * float64(format.SampleRate)))
+	data := make([]byte, frames*format.Channels*format.BitsPerSample/8) //nolint:mnd
+
+	// Zero is silence for linear PCM and floats, but not for G.711.
+	switch format.AudioFormat {
+	case wavFormatALaw:
+		data = bytes.Repeat([]byte{EncodeALaw(0)}, len(data))
+	case wavFormatULaw:
+		data = bytes.Repeat([]byte{EncodeULaw(0)}, len(data))
+	}
+
+	header := WAVHeader(len(data), format.SampleRate, format.Channels, format.BitsPerSample)
+	binary.LittleEndian.PutUint16(header[20:22], uint16(format.AudioFormat)) //nolint:gosec
+
+	return append(header, data...), nil
+}
+
+// mp3Silence repeats a frame with the header of the first audio frame of reference and nothing in its
+// side information and main data, which decodes into silence. CRC protection is turned off and padding
+// is dropped, so every frame has the same size.
+func mp3Silence(reference []byte, duration time.Duration) ([]byte, error) {
+	frames := MP3Frames(reference)
+	if len(frames) < 4 { //nolint:mnd
+		return nil, errors.New("no MP3 frame found")
+	}
+
+	header := []byte{frames[0], frames[1] | 0x01, frames[2] &^ 0x02, frames[3]} //nolint:mnd
+
+	size := mp3FrameSize(header)
+	if size <= 4 { //nolint:mnd
+		return nil, errors.New("unsupported MP3 frame")
+	}
+
+	version := (header[1] >> 3) & 0x03 //nolint:mnd
+	sampleRate := mp3SampleRates[version][(header[2]>>2)&0x03]
+	samplesPerFrame := 1152 //nolint:mnd
+
+	if version != mp3VersionMPEG1 {
+		samplesPerFrame = 576
+	}
+
+	frame := append(header, make([]byte, size-len(header))...)
+	count := int(math.Round(duration.Seconds() * float64(sampleRate) / float64(samplesPerFrame)))
+
+	return bytes.Repeat(frame, count), nil
+}
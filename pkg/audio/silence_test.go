@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WAV", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Silence(FormatWAV, EncodeWAV([]byte{1, 0}, 8000, 2), 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, EncodeWAV(make([]byte, 80*2*2), 8000, 2), out)
+	})
+
+	t.Run("WAVULaw", func(t *testing.T) {
+		t.Parallel()
+
+		header := WAVHeader(1, 8000, 1, 8)
+		header[20] = wavFormatULaw
+		ulaw := append(header, EncodeULaw(1000))
+
+		out, err := Silence(FormatWAV, ulaw, time.Millisecond)
+		require.NoError(t, err)
+
+		decoded, data, err := DecodeWAV(out)
+		require.NoError(t, err)
+		assert.Equal(t, wavFormatULaw, decoded.AudioFormat)
+		assert.Equal(t, 8000, decoded.SampleRate)
+		assert.Equal(t, bytes.Repeat([]byte{0xFF}, 8), data)
+	})
+
+	t.Run("MP3", func(t *testing.T) {
+		t.Parallel()
+
+		reference := append(mp3Frame(0, "Xing"), mp3Frame(1, "")...)
+		reference[417+1] = 0xFA // CRC protected
+
+		// 1152 samples per frame at 44.1kHz are about 26ms.
+		out, err := Silence(FormatMP3, reference, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, out, 4*417)
+
+		frame := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 413)...)
+		assert.Equal(t, bytes.Repeat(frame, 4), out)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Silence(FormatPCM, []byte{0, 0}, time.Second)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Bytes per sample of the headerless formats, the only ones that can be converted while streaming.
+var streamableFormats = map[Format]int{
+	FormatPCM:  2, //nolint:mnd
+	FormatULaw: 1,
+	FormatALaw: 1,
+}
+
+// CanStream tells whether audio of the format can be converted chunk by chunk.
+func CanStream(format Format) bool {
+	_, ok := streamableFormats[format]
+
+	return ok
+}
+
+// Converter converts headerless audio chunk by chunk, e.g. to resample a raw PCM stream from a provider
+// while it is being relayed.
+type Converter struct {
+	from      Spec
+	to        Spec
+	quality   ResampleQuality
+	resampler *Resampler
+
+	// Trailing bytes of an incomplete frame, kept until the next chunk.
+	pending []byte
+}
+
+// NewConverter creates a converter between the headerless specs, zero sample rate and channels of the
+// target keep the source ones.
+func NewConverter(from Spec, to Spec, quality ResampleQuality) (*Converter, error) {
+	if !CanStream(from.Format) || !CanStream(to.Format) {
+		return nil, fmt.Errorf("converting %s into %s while streaming is not supported", from.Format, to.Format)
+	}
+	if from.SampleRate <= 0 || from.Channels <= 0 {
+		return nil, errors.New("sample rate and channels are required for raw audio")
+	}
+
+	if to.SampleRate <= 0 {
+		to.SampleRate = from.SampleRate
+	}
+	if to.Channels <= 0 {
+		to.Channels = from.Channels
+	}
+
+	converter := &Converter{from: from, to: to, quality: quality}
+
+	if to.SampleRate != from.SampleRate {
+		resampler, err := NewResampler(from.SampleRate, to.SampleRate, to.Channels, quality)
+		if err != nil {
+			return nil, err
+		}
+
+		converter.resampler = resampler
+	}
+
+	return converter, nil
+}
+
+// Convert converts a chunk of audio and returns the output available so far.
+func (c *Converter) Convert(chunk []byte) ([]byte, error) {
+	frameSize := streamableFormats[c.from.Format] * c.from.Channels
+
+	data := append(c.pending, chunk...) //nolint:gocritic
+	complete := len(data) - len(data)%frameSize
+	c.pending = append([]byte(nil), data[complete:]...)
+
+	samples, err := Decode(data[:complete], Spec{Format: c.from.Format, SampleRate: c.from.SampleRate, Channels: c.from.Channels})
+	if err != nil {
+		return nil, err
+	}
+
+	samples = Remix(samples, c.to.Channels)
+
+	if c.resampler != nil {
+		samples = Samples{Data: c.resampler.Process(samples.Data), SampleRate: c.to.SampleRate, Channels: c.to.Channels}
+	}
+
+	return Encode(samples, c.to.Format)
+}
+
+// Flush returns the rest of the output once the input has ended.
+func (c *Converter) Flush() ([]byte, error) {
+	if c.resampler == nil {
+		return nil, nil
+	}
+
+	return Encode(Samples{Data: c.resampler.Flush(), SampleRate: c.to.SampleRate, Channels: c.to.Channels}, c.to.Format)
+}
+
+type convertReader struct {
+	reader    io.Reader
+	converter *Converter
+	chunk     []byte
+	buffer    []byte
+	err       error
+}
+
+// NewConvertReader wraps the reader of a headerless audio stream, what is read from it is converted into
+// the target spec as soon as it arrives.
+func NewConvertReader(reader io.Reader, from Spec, to Spec, quality ResampleQuality) (io.Reader, error) {
+	converter, err := NewConverter(from, to, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	return &convertReader{reader: reader, converter: converter, chunk: make([]byte, 4096)}, nil //nolint:mnd
+}
+
+func (r *convertReader) Read(p []byte) (int, error) {
+	for len(r.buffer) == 0 && r.err == nil {
+		n, err := r.reader.Read(r.chunk)
+
+		out, convertErr := r.converter.Convert(r.chunk[:n])
+		if convertErr != nil {
+			r.err = convertErr
+
+			break
+		}
+
+		r.buffer = out
+
+		if err != nil {
+			r.err = err
+
+			if err == io.EOF {
+				rest, flushErr := r.converter.Flush()
+				if flushErr != nil {
+					r.err = flushErr
+				}
+
+				r.buffer = append(r.buffer, rest...)
+			}
+		}
+	}
+
+	if len(r.buffer) > 0 {
+		n := copy(p, r.buffer)
+		r.buffer = r.buffer[n:]
+
+		return n, nil
+	}
+
+	return 0, r.err
+}
@@ -0,0 +1,192 @@
+package audio
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Format is the name of an audio format, the same as response_format of the speech API.
+type Format = string
+
+const (
+	// Headerless signed 16-bit little-endian linear PCM.
+	FormatPCM Format = "pcm"
+	FormatWAV Format = "wav"
+	// Encode only.
+	FormatFLAC Format = "flac"
+	// Headerless G.711 μ-law.
+	FormatULaw Format = "ulaw"
+	// Headerless G.711 A-law.
+	FormatALaw Format = "alaw"
+	// Decode only.
+	FormatMP3 Format = "mp3"
+)
+
+var (
+	contentTypes = map[Format]string{
+		FormatPCM:  "audio/pcm",
+		FormatWAV:  "audio/wav",
+		FormatFLAC: "audio/flac",
+		FormatULaw: "audio/basic",
+		FormatALaw: "audio/x-alaw-basic",
+		FormatMP3:  "audio/mpeg",
+	}
+
+	// Other MIME types seen in the wild.
+	contentTypeAliases = map[string]Format{
+		"audio/mp3":           FormatMP3,
+		"audio/x-wav":         FormatWAV,
+		"audio/wave":          FormatWAV,
+		"audio/vnd.wave":      FormatWAV,
+		"audio/x-flac":        FormatFLAC,
+		"audio/x-mulaw-basic": FormatULaw,
+	}
+
+	decoders = map[Format]func(data []byte, from Spec) (Samples, error){
+		FormatPCM: func(data []byte, from Spec) (Samples, error) {
+			return SamplesFromPCM(data, from.SampleRate, from.Channels)
+		},
+		FormatWAV: func(data []byte, _ Spec) (Samples, error) {
+			return samplesFromWAV(data)
+		},
+		FormatULaw: func(data []byte, from Spec) (Samples, error) {
+			return samplesFromCompanded(data, from, DecodeULaw)
+		},
+		FormatALaw: func(data []byte, from Spec) (Samples, error) {
+			return samplesFromCompanded(data, from, DecodeALaw)
+		},
+		FormatMP3: func(data []byte, _ Spec) (Samples, error) {
+			return samplesFromMP3(data)
+		},
+	}
+
+	encoders = map[Format]func(samples Samples) ([]byte, error){
+		FormatPCM: func(samples Samples) ([]byte, error) {
+			return samples.PCM(), nil
+		},
+		FormatWAV: func(samples Samples) ([]byte, error) {
+			return EncodeWAV(samples.PCM(), samples.SampleRate, samples.Channels), nil
+		},
+		FormatFLAC: encodeFLAC,
+		FormatULaw: func(samples Samples) ([]byte, error) {
+			return encodeCompanded(samples.Data, EncodeULaw), nil
+		},
+		FormatALaw: func(samples Samples) ([]byte, error) {
+			return encodeCompanded(samples.Data, EncodeALaw), nil
+		},
+	}
+)
+
+// Spec describes audio to decode, SampleRate and Channels are only needed by headerless formats, i.e.
+// pcm, ulaw and alaw, the others carry them along.
+type Spec struct {
+	Format     Format
+	SampleRate int
+	Channels   int
+}
+
+// ContentType returns the MIME type of the format, application/octet-stream for unknown ones.
+func ContentType(format Format) string {
+	return lo.ValueOr(contentTypes, format, "application/octet-stream")
+}
+
+// FormatFromContentType returns the format of the MIME type, parameters like charset are ignored.
+func FormatFromContentType(contentType string) (Format, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	if format, ok := contentTypeAliases[mediaType]; ok {
+		return format, true
+	}
+
+	return lo.FindKey(contentTypes, mediaType)
+}
+
+// CanDecode tells whether audio of the format can be decoded.
+func CanDecode(format Format) bool {
+	return lo.HasKey(decoders, format)
+}
+
+// CanEncode tells whether audio can be encoded into the format.
+func CanEncode(format Format) bool {
+	return lo.HasKey(encoders, format)
+}
+
+// EncodableFormats returns the formats audio can be encoded into, sorted.
+func EncodableFormats() []Format {
+	return slices.Sorted(maps.Keys(encoders))
+}
+
+// Decode decodes the audio into samples.
+func Decode(data []byte, from Spec) (Samples, error) {
+	decode, ok := decoders[from.Format]
+	if !ok {
+		return Samples{}, fmt.Errorf("decoding %s is not supported", from.Format)
+	}
+
+	return decode(data, from)
+}
+
+// Encode encodes the samples into the format, the sample rate and channels are kept as they are.
+func Encode(samples Samples, to Format) ([]byte, error) {
+	encode, ok := encoders[to]
+	if !ok {
+		return nil, fmt.Errorf("encoding %s is not supported", to)
+	}
+
+	return encode(samples)
+}
+
+// Convert converts the samples into the sample rate and channels of the spec, zero values keep them as
+// they are.
+func Convert(samples Samples, to Spec, quality ResampleQuality) (Samples, error) {
+	if to.Channels > 0 {
+		samples = Remix(samples, to.Channels)
+	}
+
+	if to.SampleRate > 0 {
+		return Resample(samples, to.SampleRate, quality)
+	}
+
+	return samples, nil
+}
+
+// Matches tells whether audio of the spec is already what the target asks for, zero sample rate and
+// channels of the target match anything.
+func (s Spec) Matches(to Spec) bool {
+	return s.Format == to.Format &&
+		(to.SampleRate == 0 || to.SampleRate == s.SampleRate) &&
+		(to.Channels == 0 || to.Channels == s.Channels)
+}
+
+// Transcode converts the audio into another format, sample rate and channels, zero sample rate and
+// channels of the target keep the source ones. Data is returned untouched when nothing needs to change.
+func Transcode(data []byte, from Spec, to Spec, quality ResampleQuality) ([]byte, error) {
+	if from.Matches(to) {
+		return data, nil
+	}
+
+	samples, err := Decode(data, from)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err = Convert(samples, to, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	return Encode(samples, to.Format)
+}
+
+func samplesFromCompanded(data []byte, from Spec, decode func(byte) int16) (Samples, error) {
+	if from.SampleRate <= 0 || from.Channels <= 0 {
+		return Samples{}, fmt.Errorf("sample rate and channels are required for %s", from.Format)
+	}
+
+	return Samples{Data: decodeCompanded(data, decode), SampleRate: from.SampleRate, Channels: from.Channels}, nil
+}
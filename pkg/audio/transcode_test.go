@@ -0,0 +1,226 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscode(t *testing.T) {
+	t.Parallel()
+
+	samples := Samples{Data: []int16{0, 1000, -1000, 32767, -32768, 42}, SampleRate: 16000, Channels: 2}
+	wav := EncodeWAV(samples.PCM(), samples.SampleRate, samples.Channels)
+
+	t.Run("SameFormat", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatWAV}, DefaultResampleQuality)
+		require.NoError(t, err)
+		assert.Equal(t, wav, out)
+	})
+
+	t.Run("WAVToPCM", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatPCM}, DefaultResampleQuality)
+		require.NoError(t, err)
+		assert.Equal(t, samples.PCM(), out)
+	})
+
+	t.Run("PCMToWAV", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(samples.PCM(), Spec{Format: FormatPCM, SampleRate: 16000, Channels: 2}, Spec{Format: FormatWAV}, DefaultResampleQuality)
+		require.NoError(t, err)
+		assert.Equal(t, wav, out)
+	})
+
+	t.Run("ResampleAndRemix", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatWAV, SampleRate: 8000, Channels: 1}, ResampleQualityLow)
+		require.NoError(t, err)
+
+		decoded, err := Decode(out, Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, 8000, decoded.SampleRate)
+		assert.Equal(t, 1, decoded.Channels)
+		assert.Equal(t, []int16{500, -16363}, decoded.Data)
+	})
+
+	t.Run("PCMWithoutSpec", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Transcode(samples.PCM(), Spec{Format: FormatPCM}, Spec{Format: FormatWAV}, DefaultResampleQuality)
+		require.Error(t, err)
+	})
+
+	t.Run("ULaw", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatULaw}, DefaultResampleQuality)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xFF, 0xCE, 0x4E, 0x80, 0x00, 0xFA}, out)
+
+		decoded, err := Decode(out, Spec{Format: FormatULaw, SampleRate: 16000, Channels: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 16000, decoded.SampleRate)
+		assert.Equal(t, 2, decoded.Channels)
+		assert.InDeltaSlice(t, samples.Data, decoded.Data, 1024)
+	})
+
+	t.Run("ALaw", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatALaw}, DefaultResampleQuality)
+		require.NoError(t, err)
+		require.Len(t, out, len(samples.Data))
+
+		decoded, err := Decode(out, Spec{Format: FormatALaw, SampleRate: 16000, Channels: 2})
+		require.NoError(t, err)
+		assert.InDeltaSlice(t, samples.Data, decoded.Data, 1024)
+	})
+
+	t.Run("FLAC", func(t *testing.T) {
+		t.Parallel()
+
+		// Longer than a block to get several frames.
+		long := Samples{Data: make([]int16, 2*flacBlockSize+10), SampleRate: 24000, Channels: 1}
+		for i := range long.Data {
+			long.Data[i] = int16(i * 7)
+		}
+
+		out, err := Encode(long, FormatFLAC)
+		require.NoError(t, err)
+		assert.Equal(t, "fLaC", string(out[0:4]))
+
+		stream, err := flac.New(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, uint32(24000), stream.Info.SampleRate)
+		assert.Equal(t, uint8(1), stream.Info.NChannels)
+		assert.Equal(t, uint64(len(long.Data)), stream.Info.NSamples)
+
+		decoded := make([]int16, 0, len(long.Data))
+
+		for {
+			frame, err := stream.ParseNext()
+			if err == io.EOF {
+				break
+			}
+
+			require.NoError(t, err)
+
+			for _, sample := range frame.Subframes[0].Samples {
+				decoded = append(decoded, int16(sample))
+			}
+		}
+
+		assert.Equal(t, long.Data, decoded)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Transcode(wav, Spec{Format: FormatWAV}, Spec{Format: FormatMP3}, DefaultResampleQuality)
+		require.Error(t, err)
+
+		_, err = Transcode(wav, Spec{Format: "opus"}, Spec{Format: FormatWAV}, DefaultResampleQuality)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeWAVSamples(t *testing.T) {
+	t.Parallel()
+
+	wavOf := func(audioFormat int, bitsPerSample int, data []byte) []byte {
+		wav := WAVHeader(len(data), 8000, 1, bitsPerSample)
+		binary.LittleEndian.PutUint16(wav[20:22], uint16(audioFormat))
+
+		return append(wav, data...)
+	}
+
+	t.Run("8Bit", func(t *testing.T) {
+		t.Parallel()
+
+		samples, err := Decode(wavOf(wavFormatPCM, 8, []byte{0x80, 0xFF, 0x00}), Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, []int16{0, 127 << 8, -128 << 8}, samples.Data)
+	})
+
+	t.Run("24Bit", func(t *testing.T) {
+		t.Parallel()
+
+		samples, err := Decode(wavOf(wavFormatPCM, 24, []byte{0xFF, 0x34, 0x12, 0x00, 0x00, 0x80}), Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, []int16{0x1234, -32768}, samples.Data)
+	})
+
+	t.Run("Float", func(t *testing.T) {
+		t.Parallel()
+
+		data := binary.LittleEndian.AppendUint32(nil, 0x3F000000) // 0.5
+		data = binary.LittleEndian.AppendUint32(data, 0xC0000000) // -2.0, clipped
+
+		samples, err := Decode(wavOf(wavFormatFloat, 32, data), Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, []int16{16384, -32767}, samples.Data)
+	})
+
+	t.Run("ULaw", func(t *testing.T) {
+		t.Parallel()
+
+		samples, err := Decode(wavOf(wavFormatULaw, 8, []byte{0xFF, 0x80}), Spec{Format: FormatWAV})
+		require.NoError(t, err)
+		assert.Equal(t, []int16{0, 32124}, samples.Data)
+		assert.Equal(t, 8000, samples.SampleRate)
+		assert.Equal(t, 1, samples.Channels)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Decode(wavOf(0x55, 16, []byte{0x00, 0x00}), Spec{Format: FormatWAV})
+		require.Error(t, err)
+	})
+}
+
+func TestMP3IsMono(t *testing.T) {
+	t.Parallel()
+
+	// MPEG-1 Layer III, 128kbps, 44.1kHz
+	mono := []byte{0xFF, 0xFB, 0x90, 0xC4}
+	stereo := []byte{0xFF, 0xFB, 0x90, 0x44}
+	tag := []byte{'I', 'D', '3', 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xFF, 0xFB}
+
+	assert.True(t, mp3IsMono(mono))
+	assert.False(t, mp3IsMono(stereo))
+	assert.True(t, mp3IsMono(append(tag, mono...)))
+	assert.False(t, mp3IsMono(append(tag, stereo...)))
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	t.Parallel()
+
+	for contentType, expected := range map[string]Format{
+		"audio/mpeg":           FormatMP3,
+		"audio/mp3":            FormatMP3,
+		"audio/wav":            FormatWAV,
+		"audio/x-wav; codec=1": FormatWAV,
+		"Audio/Basic":          FormatULaw,
+		"audio/x-alaw-basic":   FormatALaw,
+		"audio/flac":           FormatFLAC,
+	} {
+		format, ok := FormatFromContentType(contentType)
+		assert.True(t, ok, contentType)
+		assert.Equal(t, expected, format, contentType)
+	}
+
+	_, ok := FormatFromContentType("audio/ogg")
+	assert.False(t, ok)
+}
@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	wavHeaderSize = 44
+
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatALaw       = 6
+	wavFormatULaw       = 7
+	wavFormatExtensible = 0xFFFE
+)
+
+// WAVHeader builds the canonical 44 bytes RIFF/WAVE header for linear PCM samples.
+//
+// WAVE PCM soundfile format
+// http://soundfile.sapp.org/doc/WaveFormat/
+func WAVHeader(dataSize int, sampleRate int, channels int, bitsPerSample int) []byte {
+	blockAlign := channels * bitsPerSample / 8 //nolint:mnd
+	byteRate := sampleRate * blockAlign
+
+	buffer := bytes.NewBuffer(make([]byte, 0, wavHeaderSize))
+
+	buffer.WriteString("RIFF")
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(36+dataSize)) //nolint:mnd
+	buffer.WriteString("WAVE")
+
+	buffer.WriteString("fmt ")
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(16)) //nolint:mnd
+	_ = binary.Write(buffer, binary.LittleEndian, uint16(wavFormatPCM))
+	_ = binary.Write(buffer, binary.LittleEndian, uint16(channels))
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(buffer, binary.LittleEndian, uint16(blockAlign))
+	_ = binary.Write(buffer, binary.LittleEndian, uint16(bitsPerSample))
+
+	buffer.WriteString("data")
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(dataSize))
+
+	return buffer.Bytes()
+}
+
+// EncodeWAV wraps signed 16-bit little-endian PCM samples into a WAV container.
+func EncodeWAV(pcm []byte, sampleRate int, channels int) []byte {
+	out := make([]byte, 0, wavHeaderSize+len(pcm))
+	out = append(out, WAVHeader(len(pcm), sampleRate, channels, 16)...) //nolint:mnd
+	out = append(out, pcm...)
+
+	return out
+}
+
+// WAVFormat describes the samples of a decoded WAV file.
+type WAVFormat struct {
+	AudioFormat   int
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+}
+
+// DecodeWAV walks through the RIFF chunks and returns the format and the samples of the data chunk,
+// chunks other than fmt and data, e.g. LIST, are skipped.
+func DecodeWAV(wav []byte) (WAVFormat, []byte, error) {
+	var format WAVFormat
+
+	if len(wav) < 12 || string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" { //nolint:mnd
+		return format, nil, errors.New("not a RIFF/WAVE file")
+	}
+
+	hasFormat := false
+	offset := 12 //nolint:mnd
+
+	for offset+8 <= len(wav) { //nolint:mnd
+		id := string(wav[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(wav[offset+4 : offset+8]))
+		offset += 8 //nolint:mnd
+
+		switch id {
+		case "fmt ":
+			if size < 16 || offset+16 > len(wav) { //nolint:mnd
+				return format, nil, errors.New("malformed fmt chunk")
+			}
+
+			format = WAVFormat{
+				AudioFormat:   int(binary.LittleEndian.Uint16(wav[offset : offset+2])),
+				Channels:      int(binary.LittleEndian.Uint16(wav[offset+2 : offset+4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(wav[offset+4 : offset+8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(wav[offset+14 : offset+16])),
+			}
+			// WAVE_FORMAT_EXTENSIBLE carries the actual format in the first 2 bytes of the SubFormat GUID.
+			if format.AudioFormat == wavFormatExtensible && size >= 26 && offset+26 <= len(wav) { //nolint:mnd
+				format.AudioFormat = int(binary.LittleEndian.Uint16(wav[offset+24 : offset+26]))
+			}
+
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return format, nil, errors.New("data chunk before fmt chunk")
+			}
+
+			// Streamed WAV files may have the size left as 0 or 0xFFFFFFFF, take whatever is left then.
+			end := offset + size
+			if size == 0 || end > len(wav) {
+				end = len(wav)
+			}
+
+			return format, wav[offset:end], nil
+		}
+
+		// Chunks are padded to even sizes.
+		offset += size + size%2 //nolint:mnd
+	}
+
+	return format, nil, errors.New("missing data chunk")
+}
+
+// samplesFromWAV decodes linear PCM of 8, 16, 24 and 32 bits, 32-bit float, μ-law and A-law WAV files.
+func samplesFromWAV(wav []byte) (Samples, error) {
+	format, data, err := DecodeWAV(wav)
+	if err != nil {
+		return Samples{}, err
+	}
+	if format.Channels <= 0 || format.SampleRate <= 0 {
+		return Samples{}, errors.New("invalid channels or sample rate in fmt chunk")
+	}
+
+	samples := Samples{SampleRate: format.SampleRate, Channels: format.Channels}
+
+	switch {
+	case format.AudioFormat == wavFormatALaw:
+		samples.Data = decodeCompanded(data, DecodeALaw)
+	case format.AudioFormat == wavFormatULaw:
+		samples.Data = decodeCompanded(data, DecodeULaw)
+	case format.AudioFormat == wavFormatFloat && format.BitsPerSample == 32:
+		samples.Data = make([]int16, len(data)/4) //nolint:mnd
+		for i := range samples.Data {
+			value := math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])) //nolint:mnd
+			samples.Data[i] = int16(math.Round(float64(max(-1, min(1, value))) * math.MaxInt16))
+		}
+	case format.AudioFormat == wavFormatPCM:
+		bytesPerSample := format.BitsPerSample / 8    //nolint:mnd
+		if bytesPerSample < 1 || bytesPerSample > 4 { //nolint:mnd
+			return Samples{}, fmt.Errorf("unsupported bits per sample %d", format.BitsPerSample)
+		}
+
+		samples.Data = make([]int16, len(data)/bytesPerSample)
+		for i := range samples.Data {
+			sample := data[i*bytesPerSample : (i+1)*bytesPerSample]
+			// 8-bit samples are unsigned, wider ones are signed and only the most significant 16 bits are kept.
+			if bytesPerSample == 1 {
+				samples.Data[i] = int16(int(sample[0])-128) << 8 //nolint:mnd
+			} else {
+				samples.Data[i] = int16(binary.LittleEndian.Uint16(sample[bytesPerSample-2:])) //nolint:mnd
+			}
+		}
+	default:
+		return Samples{}, fmt.Errorf("unsupported WAV audio format %d", format.AudioFormat)
+	}
+
+	return samples, nil
+}
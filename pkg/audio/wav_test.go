@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeWAV(t *testing.T) {
+	t.Parallel()
+
+	pcm := []byte{0x01, 0x00, 0xff, 0x7f}
+
+	wav := EncodeWAV(pcm, 24000, 1)
+	require.Len(t, wav, 44+len(pcm))
+
+	assert.Equal(t, "RIFF", string(wav[0:4]))
+	assert.Equal(t, uint32(36+len(pcm)), binary.LittleEndian.Uint32(wav[4:8]))
+	assert.Equal(t, "WAVE", string(wav[8:12]))
+	assert.Equal(t, "fmt ", string(wav[12:16]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(wav[20:22]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(wav[22:24]))
+	assert.Equal(t, uint32(24000), binary.LittleEndian.Uint32(wav[24:28]))
+	assert.Equal(t, uint32(48000), binary.LittleEndian.Uint32(wav[28:32]))
+	assert.Equal(t, uint16(2), binary.LittleEndian.Uint16(wav[32:34]))
+	assert.Equal(t, uint16(16), binary.LittleEndian.Uint16(wav[34:36]))
+	assert.Equal(t, "data", string(wav[36:40]))
+	assert.Equal(t, uint32(len(pcm)), binary.LittleEndian.Uint32(wav[40:44]))
+	assert.Equal(t, pcm, wav[44:])
+}
+
+func TestDecodeWAV(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Encoded", func(t *testing.T) {
+		t.Parallel()
+
+		pcm := []byte{0x01, 0x00, 0xff, 0x7f}
+
+		format, data, err := DecodeWAV(EncodeWAV(pcm, 24000, 2))
+		require.NoError(t, err)
+
+		assert.Equal(t, WAVFormat{AudioFormat: 1, Channels: 2, SampleRate: 24000, BitsPerSample: 16}, format)
+		assert.Equal(t, pcm, data)
+	})
+
+	t.Run("SkipsOtherChunks", func(t *testing.T) {
+		t.Parallel()
+
+		pcm := []byte{0x01, 0x00, 0xff, 0x7f}
+		wav := EncodeWAV(pcm, 16000, 1)
+
+		// Insert an odd sized LIST chunk, which is padded, between fmt and data.
+		list := []byte{'L', 'I', 'S', 'T', 3, 0, 0, 0, 'a', 'b', 'c', 0}
+		withList := append(append(append([]byte{}, wav[:36]...), list...), wav[36:]...)
+
+		format, data, err := DecodeWAV(withList)
+		require.NoError(t, err)
+
+		assert.Equal(t, 16000, format.SampleRate)
+		assert.Equal(t, pcm, data)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := DecodeWAV([]byte("ID3\x04"))
+		require.Error(t, err)
+
+		_, _, err = DecodeWAV(EncodeWAV(nil, 16000, 1)[:36])
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,47 @@
+package alibaba
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/mo"
+)
+
+// dial connects to the DashScope duplex inference WebSocket shared by both speech synthesis and recognition.
+func dial(c echo.Context, apiKey string) mo.Result[*websocket.Conn] {
+	headers := http.Header{}
+
+	headers.Add("Authorization", apiKey)
+	headers.Add("X-DashScope-DataInspection", "enable") //nolint:canonicalheader
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(c.Request().Context(), "wss://dashscope.aliyuncs.com/api-ws/v1/inference", headers)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[*websocket.Conn](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer resp.Body.Close()
+
+		upstreamErrResult := utils.NewJSONResponseError(resp.StatusCode, resp.Body)
+		jsonErr, upstreamErr := upstreamErrResult.Get()
+
+		var detail string
+
+		if upstreamErr == nil {
+			detail = jsonErr.Error()
+		} else {
+			// The error from parsing the response is more specific than the generic handshake error.
+			detail = upstreamErr.Error()
+		}
+
+		// Pass upstream error, local error (wss badhandshake) is not helpful
+		return mo.Err[*websocket.Conn](apierrors.NewUpstreamError(resp.StatusCode).WithDetail(detail).WithCaller())
+	}
+
+	_ = resp.Body.Close()
+
+	return mo.Ok(conn)
+}
@@ -0,0 +1,233 @@
+package alibaba
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// Qwen TTS models, e.g. qwen-tts, qwen-tts-latest and qwen3-tts-flash, are served over HTTP instead of
+// the duplex WebSocket used by CosyVoice.
+//
+// 语音合成-千问 - 大模型服务平台百炼 - 阿里云
+// https://help.aliyun.com/zh/model-studio/qwen-tts
+const (
+	qwenSpeechURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation"
+	// Qwen TTS always synthesizes 24kHz 16-bit mono audio.
+	qwenSampleRate = 24000
+)
+
+func isQwenSpeechModel(model string) bool {
+	return strings.HasPrefix(model, "qwen")
+}
+
+type QwenSpeechRequestInput struct {
+	Text         string `json:"text"`
+	Voice        string `json:"voice"`
+	LanguageType string `json:"language_type,omitempty"`
+}
+
+type QwenSpeechRequest struct {
+	Model string                 `json:"model"`
+	Input QwenSpeechRequestInput `json:"input"`
+}
+
+type QwenSpeechResponseAudio struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Data      string `json:"data"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type QwenSpeechResponse struct {
+	RequestID string `json:"request_id"`
+	Output    struct {
+		FinishReason string                  `json:"finish_reason"`
+		Audio        QwenSpeechResponseAudio `json:"audio"`
+	} `json:"output"`
+
+	// Present when failed
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func handleQwenResponseError(res *http.Response) error {
+	switch {
+	case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+		return apierrors.
+			NewUpstreamError(res.StatusCode).
+			WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error())
+	case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+		return apierrors.
+			NewUpstreamError(res.StatusCode).
+			WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error())
+	default:
+		slog.Warn("unknown upstream error with unknown Content-Type",
+			slog.Int("status", res.StatusCode),
+			slog.String("content_type", res.Header.Get("Content-Type")),
+			slog.String("content_length", res.Header.Get("Content-Length")),
+		)
+
+		return apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status)
+	}
+}
+
+// readQwenEvents decodes the server-sent events and writes the audio chunks into w.
+func readQwenEvents(body io.ReadCloser, w *io.PipeWriter) {
+	defer func() { _ = body.Close() }()
+
+	reader := bufio.NewReader(body)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if ok {
+			var event QwenSpeechResponse
+
+			decodeErr := json.Unmarshal([]byte(strings.TrimSpace(data)), &event)
+			if decodeErr != nil {
+				_ = w.CloseWithError(apierrors.NewErrBadGateway().WithDetail(decodeErr.Error()).WithCaller())
+				return
+			}
+			if event.Code != "" {
+				_ = w.CloseWithError(apierrors.NewErrBadGateway().WithDetailf("upstream returned error, code: %s, message: %s", event.Code, event.Message))
+				return
+			}
+
+			if event.Output.Audio.Data != "" {
+				chunk, decodeErr := base64.StdEncoding.DecodeString(event.Output.Audio.Data)
+				if decodeErr != nil {
+					_ = w.CloseWithError(apierrors.NewErrBadGateway().WithDetail(decodeErr.Error()).WithCaller())
+					return
+				}
+
+				_, writeErr := w.Write(chunk)
+				if writeErr != nil {
+					return
+				}
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			_ = w.Close()
+			return
+		}
+		if err != nil {
+			_ = w.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// handleQwenSpeech synthesizes speech with Qwen TTS models, pcm is streamed with server-sent events as
+// it's generated, while wav is downloaded from the URL returned once the synthesis finished.
+func handleQwenSpeech(c echo.Context, opts types.SpeechRequestOptions) mo.Result[any] {
+	// Formats other than wav and pcm are transcoded from wav.
+	format := lo.CoalesceOrEmpty(opts.ResponseFormat, "wav")
+	if !audio.CanEncode(format) {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats of qwen tts models are wav, pcm, flac, ulaw and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	payload, err := json.Marshal(QwenSpeechRequest{
+		Model: opts.Model,
+		Input: QwenSpeechRequestInput{
+			Text:         opts.Input,
+			Voice:        opts.Voice,
+			LanguageType: utils.GetByJSONPath[string](opts.ExtraBody, "{ .language_type }"),
+		},
+	})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, qwenSpeechURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req.Header.Set("Authorization", c.Request().Header.Get("Authorization"))
+	req.Header.Set("Content-Type", "application/json")
+
+	if format == "pcm" {
+		req.Header.Set("X-DashScope-SSE", "enable") //nolint:canonicalheader
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		defer func() { _ = res.Body.Close() }()
+
+		return mo.Err[any](handleQwenResponseError(res))
+	}
+
+	if format == "pcm" {
+		reader, writer := io.Pipe()
+
+		go readQwenEvents(res.Body, writer)
+
+		defer func() { _ = reader.Close() }()
+
+		return types.StreamTranscoded(c, reader, audio.Spec{Format: audio.FormatPCM, SampleRate: qwenSampleRate, Channels: 1}, audio.FormatPCM, opts.Output)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	var response QwenSpeechResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+	if response.Output.Audio.URL == "" {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail("upstream returned no audio url").WithCaller())
+	}
+
+	audioReq, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, response.Output.Audio.URL, nil)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	audioRes, err := http.DefaultClient.Do(audioReq)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = audioRes.Body.Close() }()
+
+	if audioRes.StatusCode >= 400 && audioRes.StatusCode < 600 {
+		return mo.Err[any](handleQwenResponseError(audioRes))
+	}
+
+	// The sample rate is fixed, other ones are resampled.
+	if format != "wav" || opts.Output.IsSpecified() {
+		wav, err := io.ReadAll(audioRes.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, format, opts.Output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, "audio/wav", audioRes.Body))
+}
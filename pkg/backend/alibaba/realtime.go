@@ -0,0 +1,176 @@
+package alibaba
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+type realtimeUpstream struct {
+	conn   *websocket.Conn
+	taskID string
+	mutex  sync.Mutex
+}
+
+func (u *realtimeUpstream) SendAudio(audio []byte) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.conn.WriteMessage(websocket.BinaryMessage, audio)
+}
+
+func (u *realtimeUpstream) Finish() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.conn.WriteJSON(ClientEvent[ClientEventFinishTaskPayload]{
+		Header: ClientEventHeader{
+			TaskID:    u.taskID,
+			Action:    ClientEventActionFinishTask,
+			Streaming: ClientEventHeaderStreamingDuplex,
+		},
+		Payload: ClientEventFinishTaskPayload{
+			Input: make(map[string]any),
+		},
+	})
+}
+
+func (u *realtimeUpstream) readEvent() (Event, error) {
+	var event Event
+
+	_, message, err := u.conn.ReadMessage()
+	if err != nil {
+		return event, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+
+	err = json.Unmarshal(message, &event)
+	if err != nil {
+		return event, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+
+	if event.Header.Event == ServerEventEventTaskFailed {
+		return event, apierrors.NewErrBadRequest().WithDetailf("failed to run task, task-failed event received, error_code: %s, error_message: %s", event.Header.ErrorCode, event.Header.ErrorMessage)
+	}
+
+	return event, nil
+}
+
+func (u *realtimeUpstream) Receive() ([]types.RealtimeTranscriptionEvent, error) {
+	for {
+		event, err := u.readEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		switch event.Header.Event {
+		case ServerEventEventTaskFinished:
+			return nil, io.EOF
+		case ServerEventEventResultGenerated:
+			var payload RecognitionResultPayload
+
+			err = json.Unmarshal(event.Payload, &payload)
+			if err != nil {
+				return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+			}
+
+			sentence := payload.Output.Sentence
+			if sentence.Text == "" {
+				continue
+			}
+
+			return []types.RealtimeTranscriptionEvent{
+				{
+					Type: types.RealtimeTranscriptionEventTypeTranscript,
+					Transcript: &types.RealtimeTranscript{
+						Text:    sentence.Text,
+						IsFinal: sentence.SentenceEnd,
+						Start:   millisecondsToSeconds(sentence.BeginTime),
+						End:     millisecondsToSeconds(sentence.EndTime),
+						Words: lo.Map(sentence.Words, func(item RecognitionWord, _ int) types.TranscriptionWord {
+							return types.TranscriptionWord{
+								Word:  item.Text + item.Punctuation,
+								Start: millisecondsToSeconds(item.BeginTime),
+								End:   millisecondsToSeconds(item.EndTime),
+							}
+						}),
+					},
+				},
+			}, nil
+		default:
+			continue
+		}
+	}
+}
+
+func (u *realtimeUpstream) Close() error {
+	return u.conn.Close()
+}
+
+func HandleRealtimeTranscription(c echo.Context, options mo.Option[types.RealtimeTranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	languageHints := lo.Ternary(opts.Language != "", []string{opts.Language}, nil)
+
+	connResult := dial(c, opts.Authorization(c))
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	upstream := &realtimeUpstream{
+		conn:   connResult.MustGet(),
+		taskID: uuid.New().String(),
+	}
+
+	err := upstream.conn.WriteJSON(ClientEvent[ClientEventRunTaskRecognitionPayload]{
+		Header: ClientEventHeader{
+			TaskID:    upstream.taskID,
+			Action:    ClientEventActionRunTask,
+			Streaming: ClientEventHeaderStreamingDuplex,
+		},
+		Payload: ClientEventRunTaskRecognitionPayload{
+			TaskGroup: ClientEventPayloadTaskGroupAudio,
+			Task:      ClientEventPayloadTaskASR,
+			Function:  ClientEventPayloadFunctionRecognition,
+			Model:     lo.CoalesceOrEmpty(opts.Model, defaultRecognitionModel),
+			Input:     make(map[string]any),
+			Parameters: ClientEventRunTaskRecognitionPayloadParameters{
+				Format:                     opts.Encoding,
+				SampleRate:                 opts.SampleRate,
+				LanguageHints:              languageHints,
+				VocabularyID:               opts.ExtraQuery.Get("vocabulary_id"),
+				DisfluencyRemovalEnabled:   utils.FromStringOrEmpty[bool](opts.ExtraQuery.Get("disfluency_removal_enabled")),
+				SemanticPunctuationEnabled: utils.FromStringOrEmpty[bool](opts.ExtraQuery.Get("semantic_punctuation_enabled")),
+				// VAD: silence duration in milliseconds to end a sentence
+				MaxSentenceSilence: utils.FromStringOrEmpty[int](opts.ExtraQuery.Get("max_sentence_silence")),
+			},
+		},
+	})
+	if err != nil {
+		_ = upstream.Close()
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	// Wait for task-started so that failures can still be responded as HTTP errors
+	for {
+		event, err := upstream.readEvent()
+		if err != nil {
+			_ = upstream.Close()
+			return mo.Err[any](err)
+		}
+
+		if event.Header.Event == ServerEventEventTaskStarted {
+			break
+		}
+	}
+
+	return types.ServeRealtimeTranscription(c, upstream)
+}
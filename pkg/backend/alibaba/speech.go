@@ -11,12 +11,37 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
 	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+const (
+	// Maximum number of characters of the text in a continue-task event of CosyVoice.
+	//
+	// CosyVoice WebSocket API - 大模型服务平台百炼 - 阿里云
+	// https://help.aliyun.com/zh/model-studio/cosyvoice-websocket-api
+	cosyVoiceMaxInputLength = 2000
+	// Qwen-TTS takes at most 512 tokens.
+	//
+	// 语音合成-通义千问 - 大模型服务平台百炼 - 阿里云
+	// https://help.aliyun.com/zh/model-studio/qwen-tts
+	qwenMaxInputLength = 500
+)
+
+// MaxInputLength returns the maximum number of characters of input of the model, longer text is
+// synthesized in chunks.
+func MaxInputLength(model string) int {
+	return lo.Ternary(isQwenSpeechModel(model), qwenMaxInputLength, cosyVoiceMaxInputLength)
+}
+
+// ProsodyRanges returns the prosody the model supports, Qwen TTS doesn't take any prosody controls.
+func ProsodyRanges(model string) types.ProsodyRanges {
+	return lo.Ternary(isQwenSpeechModel(model), types.ProsodyRanges{}, prosodyRanges)
+}
+
 type ServerEventEvent string
 
 const (
@@ -75,12 +100,14 @@ type ClientEventPayloadTask string
 
 const (
 	ClientEventPayloadTaskTTS ClientEventPayloadTask = "tts"
+	ClientEventPayloadTaskASR ClientEventPayloadTask = "asr"
 )
 
 type ClientEventPayloadFunction string
 
 const (
 	ClientEventPayloadFunctionSpeechSynthesizer ClientEventPayloadFunction = "SpeechSynthesizer"
+	ClientEventPayloadFunctionRecognition       ClientEventPayloadFunction = "recognition"
 )
 
 type ClientEventRunTaskPayloadParametersTextType string
@@ -128,41 +155,72 @@ type EventStructured[P any] struct {
 	Payload P                 `json:"payload"`
 }
 
+// CosyVoice WebSocket API - 大模型服务平台百炼 - 阿里云
+// https://help.aliyun.com/zh/model-studio/cosyvoice-websocket-api
+var (
+	prosodyRanges = types.ProsodyRanges{
+		Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Pitch:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Volume: mo.Some(types.ProsodyRange{Min: 0, Max: 2.0}),   //nolint:mnd
+	}
+	// volume ranges from 0 to 100 with 50 as normal.
+	volumeScale = types.ProsodyScale{Min: 0, Normal: 50, Max: 100} //nolint:mnd
+
+	// Formats CosyVoice synthesizes natively, the others, e.g. flac and ulaw, are transcoded from pcm.
+	speechContentTypes = map[string]string{
+		"mp3":  "audio/mpeg",
+		"wav":  "audio/wav",
+		"pcm":  "audio/pcm",
+		"opus": "audio/ogg",
+	}
+	// Sample rates CosyVoice synthesizes at, always in mono.
+	speechSampleRates = []int{8000, 16000, 22050, 24000, 44100, 48000} //nolint:mnd
+)
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
-	taskID := uuid.New().String()
-	headers := http.Header{}
+	if isQwenSpeechModel(options.MustGet().Model) {
+		return handleQwenSpeech(c, options.MustGet())
+	}
 
-	headers.Add("Authorization", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
-	headers.Add("X-DashScope-DataInspection", "enable") //nolint:canonicalheader
+	responseFormat := lo.CoalesceOrEmpty(options.MustGet().ResponseFormat, "mp3")
 
-	conn, resp, err := websocket.DefaultDialer.Dial("wss://dashscope.aliyuncs.com/api-ws/v1/inference", headers)
-	if err != nil {
-		if resp == nil {
-			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	format := responseFormat
+	if !lo.HasKey(speechContentTypes, responseFormat) {
+		if !audio.CanEncode(responseFormat) {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format, supported formats are mp3, wav, pcm, opus, flac, ulaw and alaw").
+				WithSourcePointer("/response_format"))
 		}
 
-		defer resp.Body.Close()
-
-		upstreamErrResult := utils.NewJSONResponseError(resp.StatusCode, resp.Body)
-		jsonErr, upstreamErr := upstreamErrResult.Get()
+		format = audio.FormatPCM
+	}
 
-		var detail string
+	sampleRate := utils.GetByJSONPath[*int](options.MustGet().ExtraBody, "{ .sample_rate }")
+	if sampleRate == nil {
+		sampleRate = lo.ToPtr(options.MustGet().Output.NativeSampleRate(speechSampleRates, 22050)) //nolint:mnd
+	}
 
-		if upstreamErr == nil {
-			detail = jsonErr.Error()
-		} else {
-			// The error from parsing the response is more specific than the generic handshake error.
-			detail = upstreamErr.Error()
+	// Other sample rates and stereo are converted from pcm.
+	output := options.MustGet().Output
+	if !(audio.Spec{Format: responseFormat, SampleRate: *sampleRate, Channels: 1}).Matches(output.Spec(responseFormat)) {
+		err := types.CheckOutput(audio.Spec{Format: responseFormat}, responseFormat, output)
+		if err != nil {
+			return mo.Err[any](err)
 		}
 
-		// Pass upstream error, local error (wss badhandshake) is not helpful
-		return mo.Err[any](apierrors.NewUpstreamError(resp.StatusCode).WithDetail(detail).WithCaller())
+		format = audio.FormatPCM
 	}
 
-	defer func() {
-		_ = resp.Body.Close()
-		_ = conn.Close()
-	}()
+	taskID := uuid.New().String()
+	connResult := dial(c, strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	conn := connResult.MustGet()
+
+	defer func() { _ = conn.Close() }()
 
 	audioBinary := new(bytes.Buffer)
 	chanResult := make(chan struct{}, 1)
@@ -243,27 +301,24 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		}
 	}()
 
+	prosody := options.MustGet().Prosody
+
 	volume := utils.GetByJSONPath[*int](options.MustGet().ExtraBody, "{ .volume }")
 	if volume == nil {
-		volume = lo.ToPtr(50) //nolint:mnd
+		volume = lo.ToPtr(volumeScale.MapInt(prosody.Volume, prosodyRanges.Volume.MustGet()))
 	}
 
 	rate := utils.GetByJSONPath[*float64](options.MustGet().ExtraBody, "{ .rate }")
 	if rate == nil {
-		rate = lo.ToPtr(float64(1))
+		rate = lo.ToPtr(prosody.Speed.OrElse(1))
 	}
 
 	pitch := utils.GetByJSONPath[*float64](options.MustGet().ExtraBody, "{ .pitch }")
 	if pitch == nil {
-		pitch = lo.ToPtr(float64(1))
+		pitch = lo.ToPtr(prosody.Pitch.OrElse(1))
 	}
 
-	sampleRate := utils.GetByJSONPath[*int](options.MustGet().ExtraBody, "{ .sample_rate }")
-	if sampleRate == nil {
-		sampleRate = lo.ToPtr(22050) //nolint:mnd
-	}
-
-	err = conn.WriteJSON(ClientEvent[ClientEventRunTaskPayload]{
+	err := conn.WriteJSON(ClientEvent[ClientEventRunTaskPayload]{
 		Header: ClientEventHeader{
 			TaskID:    taskID,
 			Action:    ClientEventActionRunTask,
@@ -278,7 +333,7 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 			Parameters: ClientEventRunTaskPayloadParameters{
 				TextType:   ClientEventRunTaskPayloadParametersTextTypePlainText,
 				Voice:      options.MustGet().Voice,
-				Format:     lo.Ternary(options.MustGet().ResponseFormat == "", "mp3", options.MustGet().ResponseFormat),
+				Format:     format,
 				SampleRate: lo.FromPtr(sampleRate),
 				Volume:     lo.FromPtr(volume),
 				Rate:       lo.FromPtr(rate),
@@ -296,6 +351,16 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 	case err := <-chanError:
 		return mo.Err[any](err)
 	case <-chanResult:
-		return mo.Ok[any](c.Blob(http.StatusOK, "audio/mp3", audioBinary.Bytes()))
+		if format != responseFormat {
+			return types.RespondTranscoded(
+				c,
+				audioBinary.Bytes(),
+				audio.Spec{Format: audio.FormatPCM, SampleRate: lo.FromPtr(sampleRate), Channels: 1},
+				responseFormat,
+				output,
+			)
+		}
+
+		return mo.Ok[any](c.Blob(http.StatusOK, speechContentTypes[format], audioBinary.Bytes()))
 	}
 }
@@ -0,0 +1,259 @@
+package alibaba
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultRecognitionModel      = "paraformer-realtime-v2"
+	defaultRecognitionSampleRate = 16000
+
+	// Size of each binary frame, 100ms of 16kHz 16-bit mono PCM as what the documentation recommends.
+	recognitionFrameSize = 3200
+)
+
+// Paraformer 实时语音识别 WebSocket API - 大模型服务平台百炼 - 阿里云
+// https://help.aliyun.com/zh/model-studio/websocket-for-paraformer-real-time-service
+var recognitionFormats = map[string]string{
+	".pcm":  "pcm",
+	".wav":  "wav",
+	".mp3":  "mp3",
+	".opus": "opus",
+	".ogg":  "opus",
+	".spx":  "speex",
+	".aac":  "aac",
+	".amr":  "amr",
+}
+
+type ClientEventRunTaskRecognitionPayloadParameters struct {
+	Format                     string   `json:"format"`
+	SampleRate                 int      `json:"sample_rate"`
+	LanguageHints              []string `json:"language_hints,omitempty"`
+	VocabularyID               string   `json:"vocabulary_id,omitempty"`
+	DisfluencyRemovalEnabled   bool     `json:"disfluency_removal_enabled,omitempty"`
+	SemanticPunctuationEnabled bool     `json:"semantic_punctuation_enabled,omitempty"`
+	MaxSentenceSilence         int      `json:"max_sentence_silence,omitempty"`
+}
+
+type ClientEventRunTaskRecognitionPayload struct {
+	TaskGroup  ClientEventPayloadTaskGroup                    `json:"task_group"`
+	Task       ClientEventPayloadTask                         `json:"task"`
+	Function   ClientEventPayloadFunction                     `json:"function"`
+	Model      string                                         `json:"model"`
+	Input      map[string]any                                 `json:"input"`
+	Parameters ClientEventRunTaskRecognitionPayloadParameters `json:"parameters"`
+}
+
+type RecognitionWord struct {
+	BeginTime   int64  `json:"begin_time"`
+	EndTime     int64  `json:"end_time"`
+	Text        string `json:"text"`
+	Punctuation string `json:"punctuation"`
+}
+
+type RecognitionSentence struct {
+	BeginTime   int64             `json:"begin_time"`
+	EndTime     int64             `json:"end_time"`
+	Text        string            `json:"text"`
+	Words       []RecognitionWord `json:"words"`
+	SentenceEnd bool              `json:"sentence_end"`
+}
+
+type RecognitionResultPayload struct {
+	Output struct {
+		Sentence RecognitionSentence `json:"sentence"`
+	} `json:"output"`
+	Usage *struct {
+		Duration float64 `json:"duration"`
+	} `json:"usage"`
+}
+
+func recognitionFormat(opts types.TranscriptionRequestOptions) string {
+	if format := utils.GetByJSONPath[string](opts.ExtraBody, "{ .format }"); format != "" {
+		return format
+	}
+
+	if format, ok := recognitionFormats[strings.ToLower(filepath.Ext(opts.File.Filename))]; ok {
+		return format
+	}
+
+	return "wav"
+}
+
+func millisecondsToSeconds(milliseconds int64) float64 {
+	return float64(milliseconds) / 1000 //nolint:mnd
+}
+
+// sendAudio streams the audio as binary frames, then tells the server no more audio will come.
+func sendAudio(conn *websocket.Conn, taskID string, audio []byte) error {
+	for _, frame := range lo.Chunk(audio, recognitionFrameSize) {
+		err := conn.WriteMessage(websocket.BinaryMessage, frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	return conn.WriteJSON(ClientEvent[ClientEventFinishTaskPayload]{
+		Header: ClientEventHeader{
+			TaskID:    taskID,
+			Action:    ClientEventActionFinishTask,
+			Streaming: ClientEventHeaderStreamingDuplex,
+		},
+		Payload: ClientEventFinishTaskPayload{
+			Input: make(map[string]any),
+		},
+	})
+}
+
+func HandleTranscription(c echo.Context, options mo.Option[types.TranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+	taskID := uuid.New().String()
+
+	audio, err := opts.ReadFile()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	var languageHints []string
+
+	if hints, ok := opts.ExtraBody["language_hints"].([]any); ok {
+		languageHints = lo.FilterMap(hints, func(item any, _ int) (string, bool) {
+			hint, ok := item.(string)
+			return hint, ok
+		})
+	}
+	if len(languageHints) == 0 && opts.Language != "" {
+		languageHints = []string{opts.Language}
+	}
+
+	sampleRate := utils.GetByJSONPath[*int](opts.ExtraBody, "{ .sample_rate }")
+	if sampleRate == nil {
+		sampleRate = lo.ToPtr(defaultRecognitionSampleRate)
+	}
+
+	connResult := dial(c, strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	conn := connResult.MustGet()
+
+	defer func() { _ = conn.Close() }()
+
+	err = conn.WriteJSON(ClientEvent[ClientEventRunTaskRecognitionPayload]{
+		Header: ClientEventHeader{
+			TaskID:    taskID,
+			Action:    ClientEventActionRunTask,
+			Streaming: ClientEventHeaderStreamingDuplex,
+		},
+		Payload: ClientEventRunTaskRecognitionPayload{
+			TaskGroup: ClientEventPayloadTaskGroupAudio,
+			Task:      ClientEventPayloadTaskASR,
+			Function:  ClientEventPayloadFunctionRecognition,
+			Model:     lo.CoalesceOrEmpty(opts.Model, defaultRecognitionModel),
+			Input:     make(map[string]any),
+			Parameters: ClientEventRunTaskRecognitionPayloadParameters{
+				Format:                     recognitionFormat(opts),
+				SampleRate:                 lo.FromPtr(sampleRate),
+				LanguageHints:              languageHints,
+				VocabularyID:               utils.GetByJSONPath[string](opts.ExtraBody, "{ .vocabulary_id }"),
+				DisfluencyRemovalEnabled:   utils.GetByJSONPath[bool](opts.ExtraBody, "{ .disfluency_removal_enabled }"),
+				SemanticPunctuationEnabled: utils.GetByJSONPath[bool](opts.ExtraBody, "{ .semantic_punctuation_enabled }"),
+			},
+		},
+	})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	result := types.VerboseTranscriptionResponse{
+		// Only a single hint tells the language, the one recognized isn't returned.
+		Language: lo.Ternary(len(languageHints) == 1, lo.FirstOrEmpty(languageHints), ""),
+		Segments: make([]types.TranscriptionSegment, 0),
+	}
+
+	chanSendError := make(chan error, 1)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		var event Event
+
+		err = json.Unmarshal(message, &event)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		switch event.Header.Event {
+		case ServerEventEventTaskStarted:
+			// Reading happens here, while the audio is being sent from another goroutine so that the
+			// result-generated events won't be blocked.
+			go func() {
+				chanSendError <- sendAudio(conn, taskID, audio)
+			}()
+		case ServerEventEventTaskFailed:
+			return mo.Err[any](apierrors.NewErrBadRequest().WithDetailf("failed to run task, task-failed event received, error_code: %s, error_message: %s", event.Header.ErrorCode, event.Header.ErrorMessage))
+		case ServerEventEventResultGenerated:
+			var payload RecognitionResultPayload
+
+			err = json.Unmarshal(event.Payload, &payload)
+			if err != nil {
+				return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+			}
+
+			if payload.Usage != nil {
+				result.Duration = payload.Usage.Duration
+			}
+
+			// Intermediate results are revised until the sentence ends
+			sentence := payload.Output.Sentence
+			if !sentence.SentenceEnd {
+				continue
+			}
+
+			result.Segments = append(result.Segments, types.TranscriptionSegment{
+				ID:    len(result.Segments),
+				Start: millisecondsToSeconds(sentence.BeginTime),
+				End:   millisecondsToSeconds(sentence.EndTime),
+				Text:  sentence.Text,
+			})
+
+			for _, word := range sentence.Words {
+				result.Words = append(result.Words, types.TranscriptionWord{
+					Word:  word.Text + word.Punctuation,
+					Start: millisecondsToSeconds(word.BeginTime),
+					End:   millisecondsToSeconds(word.EndTime),
+				})
+			}
+		case ServerEventEventTaskFinished:
+			err = <-chanSendError
+			if err != nil {
+				return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+			}
+
+			result.Text = utils.JoinSentences(lo.Map(result.Segments, func(item types.TranscriptionSegment, _ int) string {
+				return item.Text
+			}))
+
+			if len(result.Segments) > 0 {
+				result.Duration = max(result.Duration, result.Segments[len(result.Segments)-1].End)
+			}
+
+			return types.WriteTranscription(c, opts.ResponseFormat, result)
+		}
+	}
+}
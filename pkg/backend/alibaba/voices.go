@@ -1,13 +1,17 @@
 package alibaba
 
 import (
+	"bytes"
 	_ "embed"
-
 	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
@@ -27,6 +31,164 @@ type VoicesResponseItem struct {
 	Format          string   `json:"format"`
 }
 
+var (
+	// https://help.aliyun.com/zh/model-studio/cosyvoice-websocket-api
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 8000, Bitrate: 16, FormatCode: "mp3"},  //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 16000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 22050, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 24000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 44100, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 48000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 8000, Bitrate: 16, FormatCode: "pcm"},  //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 16000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 22050, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 44100, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 48000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 8000, Bitrate: 16, FormatCode: "wav"},  //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 22050, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 44100, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 48000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		// Synthesized as PCM and transcoded.
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 22050, Bitrate: 16, FormatCode: "pcm"},         //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 22050, Bitrate: 8, FormatCode: "pcm"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 22050, Bitrate: 8, FormatCode: "pcm"}, //nolint:mnd
+	}
+
+	qwenFormats = []types.VoiceFormat{
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: qwenSampleRate, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: qwenSampleRate, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
+		// Synthesized as WAV and transcoded.
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: qwenSampleRate, Bitrate: 16, FormatCode: "wav"},         //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: qwenSampleRate, Bitrate: 8, FormatCode: "wav"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: qwenSampleRate, Bitrate: 8, FormatCode: "wav"}, //nolint:mnd
+	}
+
+	// 语音合成-千问 - 大模型服务平台百炼 - 阿里云
+	// https://help.aliyun.com/zh/model-studio/qwen-tts
+	qwenVoices = []VoicesResponseItem{
+		{Name: "Cherry", Model: "qwen-tts", Voice: "Cherry", Language: "中英文"},
+		{Name: "Serena", Model: "qwen-tts", Voice: "Serena", Language: "中英文"},
+		{Name: "Ethan", Model: "qwen-tts", Voice: "Ethan", Language: "中英文"},
+		{Name: "Chelsie", Model: "qwen-tts", Voice: "Chelsie", Language: "中英文"},
+		{Name: "Dylan", Model: "qwen-tts-latest", Voice: "Dylan", Language: "北京话"},
+		{Name: "Jada", Model: "qwen-tts-latest", Voice: "Jada", Language: "吴语"},
+		{Name: "Sunny", Model: "qwen-tts-latest", Voice: "Sunny", Language: "四川话"},
+	}
+)
+
+// CosyVoice声音复刻API - 大模型服务平台百炼 - 阿里云
+// https://help.aliyun.com/zh/model-studio/cosyvoice-clone-api
+const voiceEnrollmentURL = "https://dashscope.aliyuncs.com/api/v1/services/audio/tts/customization"
+
+type VoiceEnrollmentListRequestInput struct {
+	Action    string `json:"action"`
+	Prefix    string `json:"prefix,omitempty"`
+	PageIndex int    `json:"page_index"`
+	PageSize  int    `json:"page_size"`
+}
+
+type VoiceEnrollmentListRequest struct {
+	Model string                          `json:"model"`
+	Input VoiceEnrollmentListRequestInput `json:"input"`
+}
+
+type EnrolledVoice struct {
+	VoiceID     string `json:"voice_id"`
+	GmtCreate   string `json:"gmt_create"`
+	GmtModified string `json:"gmt_modified"`
+	Status      string `json:"status"`
+}
+
+type VoiceEnrollmentListResponse struct {
+	RequestID string `json:"request_id"`
+	Output    struct {
+		VoiceList []EnrolledVoice `json:"voice_list"`
+	} `json:"output"`
+}
+
+// enrolledVoiceModel derives the model from the voice ID, which is in the form of <model>-<prefix>-<hash>,
+// e.g. cosyvoice-v2-prefix-0123456789abcdef.
+func enrolledVoiceModel(voiceID string) string {
+	parts := strings.SplitN(voiceID, "-", 3) //nolint:mnd
+	if len(parts) < 3 {                      //nolint:mnd
+		return ""
+	}
+
+	return parts[0] + "-" + parts[1]
+}
+
+// listEnrolledVoices lists the custom voices enrolled by the account with CosyVoice voice cloning.
+func listEnrolledVoices(c echo.Context, prefix string) mo.Result[[]EnrolledVoice] {
+	payload, err := json.Marshal(VoiceEnrollmentListRequest{
+		Model: "voice-enrollment",
+		Input: VoiceEnrollmentListRequestInput{
+			Action:    "list_voice",
+			Prefix:    prefix,
+			PageIndex: 0,
+			PageSize:  1000, //nolint:mnd
+		},
+	})
+	if err != nil {
+		return mo.Err[[]EnrolledVoice](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, voiceEnrollmentURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return mo.Err[[]EnrolledVoice](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req.Header.Set("Authorization", c.Request().Header.Get("Authorization"))
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[[]EnrolledVoice](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		return mo.Err[[]EnrolledVoice](handleQwenResponseError(res))
+	}
+
+	var response VoiceEnrollmentListResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[[]EnrolledVoice](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	return mo.Ok(response.Output.VoiceList)
+}
+
+func toVoice(voice VoicesResponseItem, voiceFormats []types.VoiceFormat) types.Voice {
+	return types.Voice{
+		ID:          voice.Voice,
+		Name:        voice.Name,
+		Description: voice.Name,
+		Labels: map[string]any{
+			"tailoredScenarios": voice.Scenarios,
+		},
+		Tags:             make([]string, 0),
+		Formats:          voiceFormats,
+		CompatibleModels: []string{voice.Model},
+		PreviewAudioURL:  voice.PreviewAudioURL,
+		Languages: []types.VoiceLanguage{
+			{
+				Title: voice.Language,
+				Code:  voice.Language,
+			},
+		},
+	}
+}
+
+// HandleVoices lists the prebuilt CosyVoice and Qwen TTS voices, custom voices enrolled by the account are
+// merged in when the API key is given and they can be listed, prefix query parameter filters them by the
+// prefix given on enrollment.
 func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
 	var voicesResponse []VoicesResponseItem
 
@@ -35,47 +197,40 @@ func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions])
 		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
 	}
 
-	voices := make([]types.Voice, 0, len(voicesResponse))
+	voices := make([]types.Voice, 0, len(voicesResponse)+len(qwenVoices))
 
 	for _, voice := range voicesResponse {
-		voices = append(voices, types.Voice{
-			ID:          voice.Voice,
-			Name:        voice.Name,
-			Description: voice.Name,
-			Labels: map[string]any{
-				"tailoredScenarios": voice.Scenarios,
-			},
-			Tags: make([]string, 0),
-			Formats: []types.VoiceFormat{
-				// https://www.volcengine.com/docs/6561/1257584
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 8000, Bitrate: 16, FormatCode: "mp3"},  //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 16000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 22050, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 24000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 44100, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 48000, Bitrate: 16, FormatCode: "mp3"}, //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 8000, Bitrate: 16, FormatCode: "pcm"},  //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 16000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 22050, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 44100, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 48000, Bitrate: 16, FormatCode: "pcm"}, //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 8000, Bitrate: 16, FormatCode: "wav"},  //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 22050, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 44100, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 48000, Bitrate: 16, FormatCode: "wav"}, //nolint:mnd
-			},
-			CompatibleModels: []string{voice.Model},
-			PreviewAudioURL:  voice.PreviewAudioURL,
-			Languages: []types.VoiceLanguage{
-				{
-					Title: voice.Language,
-					Code:  voice.Language,
+		voices = append(voices, toVoice(voice, formats))
+	}
+
+	for _, voice := range qwenVoices {
+		voices = append(voices, toVoice(voice, qwenFormats))
+	}
+
+	if c.Request().Header.Get("Authorization") != "" {
+		// The prebuilt voices are still useful when the key can't list enrolled voices, e.g. keys of
+		// workspaces without access to voice enrollment.
+		enrolledVoices := listEnrolledVoices(c, options.MustGet().ExtraQuery.Get("prefix"))
+		if enrolledVoices.IsError() {
+			slog.Warn("failed to list enrolled voices of alibaba", slog.String("error", enrolledVoices.Error().Error()))
+		}
+
+		for _, voice := range enrolledVoices.OrEmpty() {
+			voices = append(voices, types.Voice{
+				ID:          voice.VoiceID,
+				Name:        voice.VoiceID,
+				Description: "enrolled voice",
+				Labels: map[string]any{
+					types.VoiceLabelKeyType: "enrolled",
+					"status":                voice.Status,
+					"created_at":            voice.GmtCreate,
 				},
-			},
-		})
+				Tags:             make([]string, 0),
+				Formats:          formats,
+				CompatibleModels: lo.Compact([]string{enrolledVoiceModel(voice.VoiceID)}),
+				Languages:        make([]types.VoiceLanguage, 0),
+			})
+		}
 	}
 
 	return mo.Ok[any](types.ListVoicesResponse{
@@ -0,0 +1,113 @@
+package azureopenai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/openai"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength is the maximum length of input, the same as OpenAI.
+//
+// Azure OpenAI in Azure AI Foundry Models REST API reference | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-foundry/openai/reference#text-to-speech-preview
+const MaxInputLength = 4096
+
+// ProsodyRanges is the prosody supported, the same as OpenAI.
+var ProsodyRanges = openai.ProsodyRanges
+
+const (
+	defaultAPIVersion = "2025-03-01-preview"
+
+	AuthTypeAPIKey  = "api_key"
+	AuthTypeEntraID = "entra_id"
+)
+
+// isJWT reports whether token looks like a JSON Web Token, Microsoft Entra ID access tokens
+// are JWTs, while Azure OpenAI API keys are 32 or 84 characters long alphanumeric strings.
+func isJWT(token string) bool {
+	return strings.HasPrefix(token, "eyJ") && strings.Count(token, ".") == 2 //nolint:mnd
+}
+
+// endpointFor resolves the deployment audio speech endpoint, either from extra_body.endpoint
+// (e.g. https://my-resource.openai.azure.com or a custom domain), or from extra_body.resource.
+//
+// Azure OpenAI in Azure AI Foundry Models REST API reference | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-foundry/openai/reference#text-to-speech-preview
+func endpointFor(opts types.SpeechRequestOptions) mo.Result[string] {
+	endpoint := utils.GetByJSONPath[string](opts.ExtraBody, "{ .endpoint }")
+	if endpoint == "" {
+		resource := utils.GetByJSONPath[string](opts.ExtraBody, "{ .resource }")
+		if resource == "" {
+			return mo.Err[string](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("either extra_body.resource or extra_body.endpoint is required").
+				WithSourcePointer("/extra_body/resource"))
+		}
+
+		endpoint = fmt.Sprintf("https://%s.openai.azure.com", resource)
+	}
+
+	deployment := lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .deployment }"), opts.Model)
+	if deployment == "" {
+		return mo.Err[string](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("deployment is required, either as model (e.g. azure-openai/my-tts-deployment) or extra_body.deployment").
+			WithSourcePointer("/model"))
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return mo.Err[string](apierrors.
+			NewErrInvalidArgument().
+			WithDetail(err.Error()).
+			WithSourcePointer("/extra_body/endpoint"))
+	}
+
+	u = u.JoinPath("openai", "deployments", deployment, "audio", "speech")
+	u.RawQuery = url.Values{
+		"api-version": []string{lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .api_version }"), defaultAPIVersion)},
+	}.Encode()
+
+	return mo.Ok(u.String())
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	endpoint := endpointFor(opts)
+	if endpoint.IsError() {
+		return mo.Err[any](endpoint.Error())
+	}
+
+	token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	authType := utils.GetByJSONPath[string](opts.ExtraBody, "{ .auth_type }")
+	if authType == "" {
+		authType = lo.Ternary(isJWT(token), AuthTypeEntraID, AuthTypeAPIKey)
+	}
+
+	header := http.Header{}
+
+	switch authType {
+	case AuthTypeEntraID:
+		header.Set("Authorization", "Bearer "+token)
+	case AuthTypeAPIKey:
+		header.Set("Api-Key", token)
+	default:
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported auth_type %s, supported are %s and %s", authType, AuthTypeAPIKey, AuthTypeEntraID).
+			WithSourcePointer("/extra_body/auth_type"))
+	}
+
+	return openai.SpeechRequest(c, opts, endpoint.MustGet(), header, "endpoint", "resource", "deployment", "api_version", "auth_type")
+}
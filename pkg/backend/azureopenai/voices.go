@@ -0,0 +1,13 @@
+package azureopenai
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/backend/openai"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/mo"
+)
+
+// HandleVoices lists the same voices as OpenAI, Azure OpenAI deployments serve the same models.
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	return openai.HandleVoices(c, options)
+}
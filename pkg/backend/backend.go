@@ -2,13 +2,20 @@ package backend
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
 	"github.com/moeru-ai/unspeech/pkg/backend/alibaba"
+	"github.com/moeru-ai/unspeech/pkg/backend/azureopenai"
+	"github.com/moeru-ai/unspeech/pkg/backend/baidu"
 	"github.com/moeru-ai/unspeech/pkg/backend/deepgram"
 	"github.com/moeru-ai/unspeech/pkg/backend/elevenlabs"
+	"github.com/moeru-ai/unspeech/pkg/backend/gemini"
+	"github.com/moeru-ai/unspeech/pkg/backend/hume"
+	"github.com/moeru-ai/unspeech/pkg/backend/iflytek"
 	"github.com/moeru-ai/unspeech/pkg/backend/koemotion"
+	"github.com/moeru-ai/unspeech/pkg/backend/lmnt"
 	"github.com/moeru-ai/unspeech/pkg/backend/microsoft"
 	"github.com/moeru-ai/unspeech/pkg/backend/openai"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
@@ -22,21 +29,37 @@ func Speech(c echo.Context) mo.Result[any] {
 		return mo.Err[any](options.Error())
 	}
 
-	switch options.MustGet().Backend {
+	opts := options.MustGet()
+
+	switch opts.Backend {
 	case "openai":
-		return openai.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: openai.HandleSpeech, maxInputLength: openai.MaxInputLength, prosody: openai.ProsodyRanges})
+	case "azure-openai":
+		return synthesizeSpeech(c, opts, speechBackend{handle: azureopenai.HandleSpeech, maxInputLength: azureopenai.MaxInputLength, prosody: azureopenai.ProsodyRanges})
 	case "deepgram":
-		return deepgram.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: deepgram.HandleSpeech, maxInputLength: deepgram.MaxInputLength, prosody: deepgram.ProsodyRanges})
 	case "elevenlabs":
-		return elevenlabs.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: elevenlabs.HandleSpeech, maxInputLength: elevenlabs.MaxInputLength, markup: true, prosody: elevenlabs.ProsodyRanges})
 	case "koemotion":
-		return koemotion.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: koemotion.HandleSpeech, maxInputLength: koemotion.MaxInputLength, prosody: koemotion.ProsodyRanges})
 	case "microsoft", "azure":
-		return microsoft.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: microsoft.HandleSpeech, maxInputLength: microsoft.MaxInputLength, markup: true, prosody: microsoft.ProsodyRanges})
 	case "volcengine", "volcano":
-		return volcengine.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: volcengine.HandleSpeech, maxInputLength: volcengine.MaxInputLength, prosody: volcengine.ProsodyRanges(opts.Model)})
 	case "ali", "aliyun", "alibaba", "bailian", "alibaba-model-studio":
-		return alibaba.HandleSpeech(c, utils.ResultToOption(options))
+		return synthesizeSpeech(c, opts, speechBackend{handle: alibaba.HandleSpeech, maxInputLength: alibaba.MaxInputLength(opts.Model), prosody: alibaba.ProsodyRanges(opts.Model)})
+	case "iflytek", "xfyun":
+		return synthesizeSpeech(c, opts, speechBackend{handle: iflytek.HandleSpeech, maxInputLength: iflytek.MaxInputLength, prosody: iflytek.ProsodyRanges})
+	case "baidu":
+		// The long text API takes up to 100,000 characters on its own.
+		return synthesizeSpeech(c, opts, speechBackend{handle: baidu.HandleSpeech, maxInputLength: lo.Ternary(opts.Model == baidu.ModelLongText, 0, baidu.MaxInputLength), prosody: baidu.ProsodyRanges})
+	case "gemini", "google-gemini":
+		return synthesizeSpeech(c, opts, speechBackend{handle: gemini.HandleSpeech, maxInputLength: gemini.MaxInputLength, prosody: gemini.ProsodyRanges})
+	case "hume":
+		// Utterances given in extra_body are synthesized as they are, input is ignored.
+		return synthesizeSpeech(c, opts, speechBackend{handle: hume.HandleSpeech, maxInputLength: lo.Ternary(opts.ExtraBody["utterances"] == nil, hume.MaxInputLength, 0), prosody: hume.ProsodyRanges})
+	case "lmnt":
+		return synthesizeSpeech(c, opts, speechBackend{handle: lmnt.HandleSpeech, maxInputLength: lmnt.MaxInputLength, prosody: lmnt.ProsodyRanges})
 	default:
 		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
 	}
@@ -51,6 +74,8 @@ func Voices(c echo.Context) mo.Result[any] {
 	switch options.MustGet().Backend {
 	case "openai":
 		return openai.HandleVoices(c, utils.ResultToOption(options))
+	case "azure-openai":
+		return azureopenai.HandleVoices(c, utils.ResultToOption(options))
 	case "deepgram":
 		return deepgram.HandleVoices(c, utils.ResultToOption(options))
 	case "elevenlabs":
@@ -63,6 +88,124 @@ func Voices(c echo.Context) mo.Result[any] {
 		return volcengine.HandleVoices(c, utils.ResultToOption(options))
 	case "ali", "aliyun", "alibaba", "bailian", "alibaba-model-studio":
 		return alibaba.HandleVoices(c, utils.ResultToOption(options))
+	case "iflytek", "xfyun":
+		return iflytek.HandleVoices(c, utils.ResultToOption(options))
+	case "baidu":
+		return baidu.HandleVoices(c, utils.ResultToOption(options))
+	case "gemini", "google-gemini":
+		return gemini.HandleVoices(c, utils.ResultToOption(options))
+	case "hume":
+		return hume.HandleVoices(c, utils.ResultToOption(options))
+	case "lmnt":
+		return lmnt.HandleVoices(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func Transcription(c echo.Context) mo.Result[any] {
+	options := types.NewTranscriptionRequestOptions(c.Request())
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "microsoft", "azure":
+		return microsoft.HandleTranscription(c, utils.ResultToOption(options))
+	case "elevenlabs":
+		return elevenlabs.HandleTranscription(c, utils.ResultToOption(options))
+	case "ali", "aliyun", "alibaba", "bailian", "alibaba-model-studio":
+		return alibaba.HandleTranscription(c, utils.ResultToOption(options))
+	case "volcengine", "volcano":
+		return volcengine.HandleTranscription(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func CreateTranscriptionJob(c echo.Context) mo.Result[any] {
+	options := types.NewTranscriptionJobRequestOptions(c.Request().Body)
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "microsoft", "azure":
+		return microsoft.HandleCreateTranscriptionJob(c, utils.ResultToOption(options))
+	case "volcengine", "volcano":
+		return volcengine.HandleCreateTranscriptionJob(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func GetTranscriptionJob(c echo.Context) mo.Result[any] {
+	options := types.NewTranscriptionJobQueryOptions(c)
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "microsoft", "azure":
+		return microsoft.HandleGetTranscriptionJob(c, utils.ResultToOption(options))
+	case "volcengine", "volcano":
+		return volcengine.HandleGetTranscriptionJob(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func RealtimeTranscription(c echo.Context) mo.Result[any] {
+	options := types.NewRealtimeTranscriptionRequestOptions(c.Request())
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "deepgram":
+		return deepgram.HandleRealtimeTranscription(c, utils.ResultToOption(options))
+	case "microsoft", "azure":
+		return microsoft.HandleRealtimeTranscription(c, utils.ResultToOption(options))
+	case "volcengine", "volcano":
+		return volcengine.HandleRealtimeTranscription(c, utils.ResultToOption(options))
+	case "ali", "aliyun", "alibaba", "bailian", "alibaba-model-studio":
+		return alibaba.HandleRealtimeTranscription(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func RealtimeSpeech(c echo.Context) mo.Result[any] {
+	options := types.NewRealtimeSpeechRequestOptions(c.Request())
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "deepgram":
+		return deepgram.HandleRealtimeSpeech(c, utils.ResultToOption(options))
+	default:
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
+	}
+}
+
+func Translation(c echo.Context) mo.Result[any] {
+	options := types.NewTranscriptionRequestOptions(c.Request())
+	if options.IsError() {
+		return mo.Err[any](options.Error())
+	}
+
+	switch options.MustGet().Backend {
+	case "openai":
+		return openai.HandleTranslation(c, utils.ResultToOption(options))
+	case "microsoft", "azure",
+		"elevenlabs",
+		"volcengine", "volcano",
+		"ali", "aliyun", "alibaba", "bailian", "alibaba-model-studio":
+		return mo.Err[any](apierrors.
+			NewErrUnsupportedCapability().
+			WithDetailf("%s only supports transcriptions, use /v1/audio/transcriptions instead", options.MustGet().Backend).
+			WithSourceParameter("model"))
 	default:
 		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported backend"))
 	}
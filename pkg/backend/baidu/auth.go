@@ -0,0 +1,152 @@
+package baidu
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/samber/mo"
+)
+
+const (
+	tokenURL = "https://aip.baidubce.com/oauth/2.0/token"
+	// Refresh the access token a while before it actually expires, tokens issued by Baidu
+	// are valid for 30 days.
+	tokenRefreshAhead = 24 * time.Hour
+)
+
+type credentials struct {
+	APIKey    string
+	SecretKey string
+}
+
+// cacheKey hashes the credentials, so that the secret key isn't kept around in the token cache.
+func (c credentials) cacheKey() string {
+	hash := sha256.Sum256([]byte(c.APIKey + ":" + c.SecretKey))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// parseCredentials extracts API Key and Secret Key from the Authorization header, which is
+// expected to be in the form of `Bearer <APIKey>:<SecretKey>`.
+func parseCredentials(authorization string) mo.Option[credentials] {
+	apiKey, secretKey, ok := strings.Cut(strings.TrimPrefix(authorization, "Bearer "), ":")
+	if !ok || apiKey == "" || secretKey == "" {
+		return mo.None[credentials]()
+	}
+
+	return mo.Some(credentials{
+		APIKey:    apiKey,
+		SecretKey: secretKey,
+	})
+}
+
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type cachedToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+var (
+	tokensMutex sync.Mutex
+	tokens      = make(map[string]cachedToken)
+)
+
+// cachedAccessToken returns the token cached for the credentials unless it's about to expire.
+func cachedAccessToken(creds credentials) mo.Option[string] {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	token, ok := tokens[creds.cacheKey()]
+	if !ok || !time.Now().Add(tokenRefreshAhead).Before(token.expiresAt) {
+		return mo.None[string]()
+	}
+
+	return mo.Some(token.accessToken)
+}
+
+// storeAccessToken caches a token exchanged successfully, expired tokens of other credentials are dropped
+// along the way, so that credentials no longer used don't stay around.
+func storeAccessToken(creds credentials, token cachedToken) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	for key, cached := range tokens {
+		if time.Now().After(cached.expiresAt) {
+			delete(tokens, key)
+		}
+	}
+
+	tokens[creds.cacheKey()] = token
+}
+
+// accessToken returns a cached access token for the credentials, exchanging a new one through
+// the OAuth client credentials flow when absent or about to expire.
+//
+// 鉴权认证机制 - 百度智能云文档
+// https://ai.baidu.com/ai-doc/REFERENCE/Ck3dwjhhu
+func accessToken(ctx context.Context, creds credentials) (string, error) {
+	if token, ok := cachedAccessToken(creds).Get(); ok {
+		return token, nil
+	}
+
+	query := url.Values{}
+	query.Set("grant_type", "client_credentials")
+	query.Set("client_id", creds.APIKey)
+	query.Set("client_secret", creds.SecretKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller()
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller()
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	var response TokenResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return "", apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+	if response.Error != "" || response.AccessToken == "" {
+		return "", apierrors.
+			NewErrUnauthorized().
+			WithDetailf("failed to obtain access token from baidu, error: %s, error_description: %s", response.Error, response.ErrorDescription).
+			WithSourceHeader("Authorization")
+	}
+
+	storeAccessToken(creds, cachedToken{
+		accessToken: response.AccessToken,
+		expiresAt:   time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	})
+
+	return response.AccessToken, nil
+}
+
+// invalidateAccessToken drops the cached token so that the next call to accessToken exchanges
+// a fresh one, used when upstream reports the token as invalid or expired.
+func invalidateAccessToken(creds credentials) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	delete(tokens, creds.cacheKey())
+}
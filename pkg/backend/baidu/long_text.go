@@ -0,0 +1,250 @@
+package baidu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	longTextCreateURL = "https://aip.baidubce.com/rpc/2.0/tts/v1/create"
+	longTextQueryURL  = "https://aip.baidubce.com/rpc/2.0/tts/v1/query"
+
+	defaultPollInterval = time.Second
+	defaultPollTimeout  = 5 * time.Minute
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusCreated TaskStatus = "Created"
+	TaskStatusRunning TaskStatus = "Running"
+	TaskStatusSuccess TaskStatus = "Success"
+	TaskStatusFailure TaskStatus = "Failure"
+)
+
+type LongTextCreateRequest struct {
+	Text           string `json:"text"`
+	Format         string `json:"format"`
+	Voice          int    `json:"voice"`
+	Lang           string `json:"lang"`
+	Speed          int    `json:"speed"`
+	Pitch          int    `json:"pitch"`
+	Volume         int    `json:"volume"`
+	EnableSubtitle int    `json:"enable_subtitle,omitempty"`
+}
+
+type LongTextCreateResponse struct {
+	LogID      int64      `json:"log_id"`
+	TaskID     string     `json:"task_id"`
+	TaskStatus TaskStatus `json:"task_status"`
+	ErrorCode  int        `json:"error_code"`
+	ErrorMsg   string     `json:"error_msg"`
+}
+
+type LongTextQueryRequest struct {
+	TaskIDs []string `json:"task_ids"`
+}
+
+type LongTextTaskResult struct {
+	SpeechURL string `json:"speech_url"`
+	ErrNo     int    `json:"err_no"`
+	ErrMsg    string `json:"err_msg"`
+}
+
+type LongTextTaskInfo struct {
+	TaskID     string             `json:"task_id"`
+	TaskStatus TaskStatus         `json:"task_status"`
+	TaskResult LongTextTaskResult `json:"task_result"`
+}
+
+type LongTextQueryResponse struct {
+	LogID     int64              `json:"log_id"`
+	TasksInfo []LongTextTaskInfo `json:"tasks_info"`
+	ErrorCode int                `json:"error_code"`
+	ErrorMsg  string             `json:"error_msg"`
+}
+
+func postJSON[T any](ctx context.Context, endpoint string, token string, body any) mo.Result[T] {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		endpoint+"?"+url.Values{"access_token": []string{token}}.Encode(),
+		bytes.NewBuffer(payload),
+	)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		ct := res.Header.Get("Content-Type")
+
+		switch {
+		case strings.HasPrefix(ct, "application/json"):
+			return mo.Err[T](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(ct, "text/"):
+			return mo.Err[T](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", ct),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[T](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail("unknown Content-Type: " + ct))
+		}
+	}
+
+	var response T
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	return mo.Ok(response)
+}
+
+// handleLongText synthesizes through the asynchronous long text API, the task is created, then
+// polled until it either succeeds or fails, and the resulting audio is downloaded and streamed.
+//
+// 长文本在线合成 API - 百度智能云文档
+// https://ai.baidu.com/ai-doc/SPEECH/ulbxh8rbu
+func handleLongText(c echo.Context, creds credentials, params synthesisParams) mo.Result[any] {
+	ctx := c.Request().Context()
+
+	created := withAccessToken(ctx, creds, func(token string) mo.Result[LongTextCreateResponse] {
+		res := postJSON[LongTextCreateResponse](ctx, longTextCreateURL, token, LongTextCreateRequest{
+			Text:           params.Text,
+			Format:         params.Format.Format,
+			Voice:          params.Voice,
+			Lang:           params.Language,
+			Speed:          params.Speed,
+			Pitch:          params.Pitch,
+			Volume:         params.Volume,
+			EnableSubtitle: lo.FromPtr(utils.GetByJSONPath[*int](params.ExtraBody, "{ .enable_subtitle }")),
+		})
+		if res.IsError() {
+			return res
+		}
+		if res.MustGet().ErrorCode != 0 {
+			return mo.Err[LongTextCreateResponse](newErrFromCode(res.MustGet().ErrorCode, res.MustGet().ErrorMsg))
+		}
+
+		return res
+	})
+	if created.IsError() {
+		return mo.Err[any](created.Error())
+	}
+
+	pollInterval := defaultPollInterval
+	if ms := utils.GetByJSONPath[int](params.ExtraBody, "{ .poll_interval_ms }"); ms > 0 {
+		pollInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, defaultPollTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	taskID := created.MustGet().TaskID
+
+	for {
+		select {
+		case <-pollCtx.Done():
+			return mo.Err[any](apierrors.
+				NewErrUnavailable().
+				WithDetailf("long text synthesis task %s did not finish in time: %s", taskID, pollCtx.Err().Error()).
+				WithMeta("task_id", taskID))
+		case <-ticker.C:
+		}
+
+		queried := withAccessToken(pollCtx, creds, func(token string) mo.Result[LongTextQueryResponse] {
+			res := postJSON[LongTextQueryResponse](pollCtx, longTextQueryURL, token, LongTextQueryRequest{
+				TaskIDs: []string{taskID},
+			})
+			if res.IsError() {
+				return res
+			}
+			if res.MustGet().ErrorCode != 0 {
+				return mo.Err[LongTextQueryResponse](newErrFromCode(res.MustGet().ErrorCode, res.MustGet().ErrorMsg))
+			}
+
+			return res
+		})
+		if queried.IsError() {
+			return mo.Err[any](queried.Error())
+		}
+
+		task, ok := lo.Find(queried.MustGet().TasksInfo, func(item LongTextTaskInfo) bool {
+			return item.TaskID == taskID
+		})
+		if !ok {
+			continue
+		}
+
+		switch task.TaskStatus {
+		case TaskStatusSuccess:
+			return downloadSpeech(c, task.TaskResult.SpeechURL, params.Format, params.Output)
+		case TaskStatusFailure:
+			return mo.Err[any](newErrFromCode(task.TaskResult.ErrNo, task.TaskResult.ErrMsg).WithMeta("task_id", taskID))
+		case TaskStatusCreated, TaskStatusRunning:
+			continue
+		}
+	}
+}
+
+func downloadSpeech(c echo.Context, speechURL string, format audioFormat, output types.AudioOutput) mo.Result[any] {
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, speechURL, nil)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return mo.Err[any](apierrors.
+			NewUpstreamError(res.StatusCode).
+			WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+	}
+
+	return respondSpeech(c, format, output, res.Body)
+}
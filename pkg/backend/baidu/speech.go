@@ -0,0 +1,321 @@
+package baidu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength is the maximum number of characters of the short text API, the text is limited to
+// 1024 bytes in GBK, i.e. about 500 Chinese characters, the long text API takes up to 100,000.
+//
+// 短文本在线合成 API - 百度智能云文档
+// https://ai.baidu.com/ai-doc/SPEECH/mlbxh7xie
+const MaxInputLength = 500
+
+const (
+	text2AudioURL = "https://tsn.baidu.com/text2audio"
+
+	ModelShortText = "short"
+	ModelLongText  = "long"
+)
+
+type audioFormat struct {
+	// Aue is the format code used by the short text (text2audio) API.
+	Aue int
+	// Format is the format code used by the long text (asynchronous) API.
+	Format      string
+	ContentType string
+	// Encoding is the format the synthesized pcm is encoded into, for formats Baidu doesn't synthesize.
+	Encoding   audio.Format
+	SampleRate int
+}
+
+var (
+	// 短文本在线合成 API - 百度智能云文档
+	// https://ai.baidu.com/ai-doc/SPEECH/mlbxh7xie
+	//
+	// 长文本在线合成 API - 百度智能云文档
+	// https://ai.baidu.com/ai-doc/SPEECH/ulbxh8rbu
+	audioFormats = map[string]map[int]audioFormat{
+		"mp3": {
+			16000: {Aue: 3, Format: "mp3-16k", ContentType: "audio/mpeg"}, //nolint:mnd
+			48000: {Aue: 3, Format: "mp3-48k", ContentType: "audio/mpeg"}, //nolint:mnd
+		},
+		"pcm": {
+			16000: {Aue: 4, Format: "pcm-16k", ContentType: "audio/pcm"}, //nolint:mnd
+			8000:  {Aue: 5, Format: "pcm-8k", ContentType: "audio/pcm"},  //nolint:mnd
+		},
+		"wav": {
+			16000: {Aue: 6, Format: "wav", ContentType: "audio/wav"},                                                 //nolint:mnd
+			8000:  {Aue: 5, Format: "pcm-8k", ContentType: "audio/wav", Encoding: audio.FormatWAV, SampleRate: 8000}, //nolint:mnd
+		},
+		"flac": {
+			16000: {Aue: 4, Format: "pcm-16k", ContentType: "audio/flac", Encoding: audio.FormatFLAC, SampleRate: 16000}, //nolint:mnd
+			8000:  {Aue: 5, Format: "pcm-8k", ContentType: "audio/flac", Encoding: audio.FormatFLAC, SampleRate: 8000},   //nolint:mnd
+		},
+		"ulaw": {
+			16000: {Aue: 4, Format: "pcm-16k", ContentType: "audio/basic", Encoding: audio.FormatULaw, SampleRate: 16000}, //nolint:mnd
+			8000:  {Aue: 5, Format: "pcm-8k", ContentType: "audio/basic", Encoding: audio.FormatULaw, SampleRate: 8000},   //nolint:mnd
+		},
+		"alaw": {
+			16000: {Aue: 4, Format: "pcm-16k", ContentType: "audio/x-alaw-basic", Encoding: audio.FormatALaw, SampleRate: 16000}, //nolint:mnd
+			8000:  {Aue: 5, Format: "pcm-8k", ContentType: "audio/x-alaw-basic", Encoding: audio.FormatALaw, SampleRate: 8000},   //nolint:mnd
+		},
+	}
+
+	// 短文本在线合成 API - 百度智能云文档
+	// https://ai.baidu.com/ai-doc/SPEECH/mlbxh7xie#%E9%94%99%E8%AF%AF%E7%A0%81
+	errorCodeStatuses = map[int]int{
+		500: http.StatusBadRequest,   // unsupported input
+		501: http.StatusBadRequest,   // invalid parameters
+		502: http.StatusUnauthorized, // token verification failed
+		503: http.StatusBadGateway,   // synthesis backend error
+		110: http.StatusUnauthorized, // access token invalid
+		111: http.StatusUnauthorized, // access token expired
+		4:   http.StatusTooManyRequests,
+		17:  http.StatusTooManyRequests, // daily request limit reached
+		18:  http.StatusTooManyRequests, // QPS limit reached
+		6:   http.StatusForbidden,       // no permission to access the data
+	}
+)
+
+type ErrorResponse struct {
+	ErrNo  int    `json:"err_no"`
+	ErrMsg string `json:"err_msg"`
+	SN     string `json:"sn"`
+	LogID  int64  `json:"tts_logid"`
+}
+
+func newErrFromCode(code int, message string) *apierrors.Error {
+	status, ok := errorCodeStatuses[code]
+	if !ok {
+		status = http.StatusBadGateway
+	}
+
+	return apierrors.NewUpstreamError(status).
+		WithDetailf("baidu error code: %d, message: %s", code, message).
+		WithMeta("code", code)
+}
+
+func getAudioFormat(format string, sampleRate int) mo.Option[audioFormat] {
+	formatsWithSampleRate, ok := audioFormats[format]
+	if !ok {
+		return mo.None[audioFormat]()
+	}
+
+	f, ok := formatsWithSampleRate[sampleRate]
+	if !ok {
+		return mo.None[audioFormat]()
+	}
+
+	return mo.Some(f)
+}
+
+// withAccessToken calls fn with a cached access token, if upstream rejects the token as invalid
+// or expired, the cached token is dropped and fn is called once again with a fresh one.
+func withAccessToken[T any](ctx context.Context, creds credentials, fn func(token string) mo.Result[T]) mo.Result[T] {
+	for attempt := 0; ; attempt++ {
+		token, err := accessToken(ctx, creds)
+		if err != nil {
+			return mo.Err[T](err)
+		}
+
+		res := fn(token)
+
+		var apiErr *apierrors.Error
+		if res.IsError() && attempt == 0 && errors.As(res.Error(), &apiErr) && apiErr.Status == http.StatusUnauthorized {
+			invalidateAccessToken(creds)
+			continue
+		}
+
+		return res
+	}
+}
+
+var (
+	// ProsodyRanges is the prosody Baidu supports, spd, pit and vol range from 0 to 15 with 5 as normal.
+	ProsodyRanges = types.ProsodyRanges{
+		Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Pitch:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Volume: mo.Some(types.ProsodyRange{Min: 0, Max: 2.0}),   //nolint:mnd
+	}
+	prosodyScale = types.ProsodyScale{Min: 0, Normal: 5, Max: 15} //nolint:mnd
+)
+
+type synthesisParams struct {
+	Text      string
+	Voice     int
+	Speed     int
+	Pitch     int
+	Volume    int
+	Format    audioFormat
+	CUID      string
+	Language  string
+	Output    types.AudioOutput
+	ExtraBody map[string]any
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	creds := parseCredentials(c.Request().Header.Get("Authorization"))
+	if creds.IsAbsent() {
+		return mo.Err[any](apierrors.
+			NewErrUnauthorized().
+			WithDetail("baidu requires Authorization header in the form of `Bearer <APIKey>:<SecretKey>`").
+			WithSourceHeader("Authorization"))
+	}
+
+	voice, err := strconv.Atoi(opts.Voice)
+	if err != nil {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("voice must be a numeric speaker ID (per), e.g. 0, 1, 3, 4, 5003").
+			WithSourcePointer("/voice"))
+	}
+
+	responseFormat := lo.Ternary(opts.ResponseFormat == "", "mp3", opts.ResponseFormat)
+
+	sampleRate := utils.GetByJSONPath[*int](opts.ExtraBody, "{ .sample_rate }")
+	if sampleRate == nil {
+		sampleRate = lo.ToPtr(opts.Output.NativeSampleRate(lo.Keys(audioFormats[responseFormat]), 16000)) //nolint:mnd
+	}
+
+	format := getAudioFormat(responseFormat, *sampleRate)
+	if format.IsAbsent() {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format and sample_rate combination, supported are mp3 (16000, 48000), pcm, wav, flac, ulaw and alaw (8000, 16000)").
+			WithSourcePointer("/response_format"))
+	}
+
+	// Other sample rates and stereo are converted from pcm.
+	if !(audio.Spec{Format: responseFormat, SampleRate: *sampleRate, Channels: 1}).Matches(opts.Output.Spec(responseFormat)) {
+		err = types.CheckOutput(audio.Spec{Format: responseFormat}, responseFormat, opts.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		pcmSampleRate := opts.Output.NativeSampleRate(lo.Keys(audioFormats[audio.FormatPCM]), 16000) //nolint:mnd
+		pcm := audioFormats[audio.FormatPCM][pcmSampleRate]
+		pcm.ContentType = audio.ContentType(responseFormat)
+		pcm.Encoding = responseFormat
+		pcm.SampleRate = pcmSampleRate
+
+		format = mo.Some(pcm)
+	}
+
+	params := synthesisParams{
+		Text:      opts.Input,
+		Voice:     voice,
+		Speed:     lo.FromPtrOr(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .spd }"), prosodyScale.MapInt(opts.Prosody.Speed, ProsodyRanges.Speed.MustGet())),
+		Pitch:     lo.FromPtrOr(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .pit }"), prosodyScale.MapInt(opts.Prosody.Pitch, ProsodyRanges.Pitch.MustGet())),
+		Volume:    lo.FromPtrOr(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .vol }"), prosodyScale.MapInt(opts.Prosody.Volume, ProsodyRanges.Volume.MustGet())),
+		Format:    format.MustGet(),
+		CUID:      lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .cuid }"), uuid.New().String()),
+		Language:  lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .lan }"), "zh"),
+		Output:    opts.Output,
+		ExtraBody: opts.ExtraBody,
+	}
+
+	switch opts.Model {
+	case "", ModelShortText:
+		return handleShortText(c, creds.MustGet(), params)
+	case ModelLongText:
+		return handleLongText(c, creds.MustGet(), params)
+	default:
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported model %s, supported models are %s and %s", opts.Model, ModelShortText, ModelLongText).
+			WithSourcePointer("/model"))
+	}
+}
+
+func handleShortText(c echo.Context, creds credentials, params synthesisParams) mo.Result[any] {
+	res := withAccessToken(c.Request().Context(), creds, func(token string) mo.Result[*http.Response] {
+		form := url.Values{}
+		// NOTICE: text must be URL encoded twice according to the documentation, the form encoding
+		// below counts as the second time.
+		form.Set("tex", url.QueryEscape(params.Text))
+		form.Set("tok", token)
+		form.Set("cuid", params.CUID)
+		form.Set("ctp", "1")
+		form.Set("lan", params.Language)
+		form.Set("spd", strconv.Itoa(params.Speed))
+		form.Set("pit", strconv.Itoa(params.Pitch))
+		form.Set("vol", strconv.Itoa(params.Volume))
+		form.Set("per", strconv.Itoa(params.Voice))
+		form.Set("aue", strconv.Itoa(params.Format.Aue))
+
+		req, err := http.NewRequestWithContext(
+			c.Request().Context(),
+			http.MethodPost,
+			text2AudioURL,
+			strings.NewReader(form.Encode()),
+		)
+		if err != nil {
+			return mo.Err[*http.Response](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return mo.Err[*http.Response](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		// Successful responses come with audio/* Content-Type, while failures are JSON with
+		// 200 OK status code.
+		if strings.HasPrefix(res.Header.Get("Content-Type"), "audio/") {
+			return mo.Ok(res)
+		}
+
+		defer func() { _ = res.Body.Close() }()
+
+		var errResponse ErrorResponse
+
+		err = json.NewDecoder(res.Body).Decode(&errResponse)
+		if err != nil {
+			return mo.Err[*http.Response](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		return mo.Err[*http.Response](newErrFromCode(errResponse.ErrNo, errResponse.ErrMsg).WithMeta("sn", errResponse.SN))
+	})
+	if res.IsError() {
+		return mo.Err[any](res.Error())
+	}
+
+	defer func() { _ = res.MustGet().Body.Close() }()
+
+	return respondSpeech(c, params.Format, params.Output, res.MustGet().Body)
+}
+
+// respondSpeech streams the synthesized audio, or encodes it first for formats, sample rates and channels
+// Baidu doesn't synthesize.
+func respondSpeech(c echo.Context, format audioFormat, output types.AudioOutput, body io.Reader) mo.Result[any] {
+	if format.Encoding == "" {
+		return mo.Ok[any](c.Stream(http.StatusOK, format.ContentType, body))
+	}
+
+	pcm, err := io.ReadAll(body)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	return types.RespondTranscoded(c, pcm, audio.Spec{Format: audio.FormatPCM, SampleRate: format.SampleRate, Channels: 1}, format.Encoding, output)
+}
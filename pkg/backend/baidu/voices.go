@@ -0,0 +1,87 @@
+package baidu
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/mo"
+)
+
+var (
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 16000, FormatCode: "3"}, //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 48000, FormatCode: "3"}, //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 16000, FormatCode: "4"},  //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 8000, FormatCode: "5"},   //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, FormatCode: "6"},  //nolint:mnd
+		// Synthesized as PCM and transcoded.
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 8000, FormatCode: "5"},             //nolint:mnd
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 16000, FormatCode: "4"},         //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 8000, FormatCode: "5"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 8000, FormatCode: "5"}, //nolint:mnd
+	}
+
+	predefinedOptions = map[string]any{
+		"spd": map[string]any{"min": 0, "max": 15, "default": 5},
+		"pit": map[string]any{"min": 0, "max": 15, "default": 5},
+		"vol": map[string]any{"min": 0, "max": 15, "default": 5},
+	}
+
+	languages = []types.VoiceLanguage{
+		{Code: "zh-CN", Title: "中文普通话"},
+		{Code: "en-US", Title: "English"},
+	}
+)
+
+type voice struct {
+	ID          string
+	Name        string
+	Description string
+	Gender      string
+	Age         string
+	Type        string
+}
+
+// 短文本在线合成 API - 百度智能云文档
+// https://ai.baidu.com/ai-doc/SPEECH/mlbxh7xie#%E5%8F%91%E9%9F%B3%E4%BA%BA%E9%80%89%E6%8B%A9
+var voices = []voice{
+	// Basic voices
+	{ID: "0", Name: "度小美", Description: "标准女主播", Gender: "female", Age: "adult", Type: "basic"},
+	{ID: "1", Name: "度小宇", Description: "亲切男声", Gender: "male", Age: "adult", Type: "basic"},
+	{ID: "3", Name: "度逍遥", Description: "情感男声", Gender: "male", Age: "adult", Type: "basic"},
+	{ID: "4", Name: "度丫丫", Description: "童声", Gender: "female", Age: "child", Type: "basic"},
+
+	// Premium voices
+	{ID: "5003", Name: "度逍遥（精品）", Description: "情感男声", Gender: "male", Age: "adult", Type: "premium"},
+	{ID: "5118", Name: "度小鹿", Description: "甜美女声", Gender: "female", Age: "young adult", Type: "premium"},
+	{ID: "106", Name: "度博文", Description: "专业男主播", Gender: "male", Age: "adult", Type: "premium"},
+	{ID: "110", Name: "度小童", Description: "童声主播", Gender: "male", Age: "child", Type: "premium"},
+	{ID: "111", Name: "度小萌", Description: "软萌妹子", Gender: "female", Age: "young adult", Type: "premium"},
+	{ID: "103", Name: "度米朵", Description: "可爱童声", Gender: "female", Age: "child", Type: "premium"},
+	{ID: "5", Name: "度小娇", Description: "成熟女主播", Gender: "female", Age: "adult", Type: "premium"},
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	result := make([]types.Voice, 0, len(voices))
+
+	for _, v := range voices {
+		result = append(result, types.Voice{
+			ID:          v.ID,
+			Name:        v.Name,
+			Description: v.Description,
+			Labels: map[string]any{
+				types.VoiceLabelKeyGender: v.Gender,
+				types.VoiceLabelKeyAge:    v.Age,
+				types.VoiceLabelKeyType:   v.Type,
+			},
+			Tags:              make([]string, 0),
+			Languages:         languages,
+			Formats:           formats,
+			CompatibleModels:  []string{ModelShortText, ModelLongText},
+			PredefinedOptions: predefinedOptions,
+		})
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: result,
+	})
+}
@@ -0,0 +1,345 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/markup"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+)
+
+// Chunks of a long text synthesized at the same time, so that a single request doesn't exhaust the
+// concurrency limits of the provider.
+const maxConcurrentChunks = 4
+
+type speechHandler func(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any]
+
+// speechBackend describes how the input is handed over to a backend.
+type speechBackend struct {
+	handle speechHandler
+	// Maximum number of characters of input in a request, 0 means no limit.
+	maxInputLength int
+	// Whether the backend renders <break>, <emphasis>, <say-as> and <sub> in input by itself, otherwise the
+	// pauses are inserted as silence between the audio of the text around them.
+	markup bool
+	// Prosody the backend supports, anything else is rejected before the input is handed over.
+	prosody types.ProsodyRanges
+}
+
+// speechSegment is either a chunk of the input or a pause between them.
+type speechSegment struct {
+	input string
+	pause time.Duration
+}
+
+type chunkResult struct {
+	contentType string
+	data        []byte
+	err         error
+}
+
+func errNoTextToSynthesize() *apierrors.Error {
+	return apierrors.NewErrInvalidArgument().WithDetail("input has no text to synthesize").WithSourcePointer("/input")
+}
+
+// markupInput renders a chunk of the nodes back into input, as markup only when there is any, so that
+// text in chunks without markup isn't left escaped.
+func markupInput(nodes []markup.Node) string {
+	if lo.ContainsBy(nodes, func(item markup.Node) bool { return item.Element != "" }) {
+		return strings.TrimSpace(markup.SSML(nodes))
+	}
+
+	return strings.TrimSpace(markup.PlainText(nodes))
+}
+
+// segmentsOf splits the input into the segments synthesized one by one, i.e. at the pauses of the markup
+// and into chunks of at most maxInputLength. Backends rendering the markup by themselves get chunks of it
+// instead, split between the elements.
+func segmentsOf(input string, backend speechBackend) mo.Result[[]speechSegment] {
+	fits := backend.maxInputLength <= 0 || utf8.RuneCountInString(input) <= backend.maxInputLength
+
+	if !markup.Contains(input) || (backend.markup && fits) {
+		return mo.Ok(lo.Map(utils.ChunkText(input, backend.maxInputLength), func(item string, _ int) speechSegment {
+			return speechSegment{input: item}
+		}))
+	}
+
+	nodes := types.ParseMarkup(input)
+	if nodes.IsError() {
+		return mo.Err[[]speechSegment](nodes.Error())
+	}
+
+	if backend.markup {
+		chunks := markup.Chunk(nodes.MustGet(), backend.maxInputLength)
+		if len(chunks) == 0 {
+			return mo.Err[[]speechSegment](errNoTextToSynthesize())
+		}
+
+		return mo.Ok(lo.Map(chunks, func(item []markup.Node, _ int) speechSegment {
+			return speechSegment{input: markupInput(item)}
+		}))
+	}
+
+	segments := make([]speechSegment, 0)
+
+	for _, part := range markup.Split(nodes.MustGet()) {
+		if part.Pause > 0 {
+			segments = append(segments, speechSegment{pause: part.Pause})
+
+			continue
+		}
+
+		for _, chunk := range utils.ChunkText(part.Text, backend.maxInputLength) {
+			segments = append(segments, speechSegment{input: strings.TrimSpace(chunk)})
+		}
+	}
+
+	if !lo.ContainsBy(segments, func(item speechSegment) bool { return item.pause == 0 }) {
+		return mo.Err[[]speechSegment](errNoTextToSynthesize())
+	}
+
+	return mo.Ok(segments)
+}
+
+// synthesizeSpeech synthesizes input longer than the limit of the backend in chunks split at sentence and
+// clause boundaries, and input with pauses in pieces with silence generated in between. Chunks are
+// synthesized concurrently, and streamed back in order as soon as the chunks in front of them are done.
+//
+// Whole SSML documents can't be split without breaking them, and Server-Sent Events carry their own framing,
+// therefore both are always synthesized in a single request.
+func synthesizeSpeech(c echo.Context, opts types.SpeechRequestOptions, backend speechBackend) mo.Result[any] {
+	err := opts.Prosody.Validate(backend.prosody)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	if markup.IsSSML(opts.Input) || utils.GetByJSONPath[string](opts.AsMap(), "{ .stream_format }") == "sse" {
+		return backend.handle(c, mo.Some(opts))
+	}
+
+	result := segmentsOf(opts.Input, backend)
+	if result.IsError() {
+		return mo.Err[any](result.Error())
+	}
+
+	segments := result.MustGet()
+	if len(segments) == 1 {
+		if segments[0].input == opts.Input {
+			return backend.handle(c, mo.Some(opts))
+		}
+
+		chunkOpts := opts.WithInput(segments[0].input)
+		if chunkOpts.IsError() {
+			return mo.Err[any](chunkOpts.Error())
+		}
+
+		return backend.handle(c, mo.Some(chunkOpts.MustGet()))
+	}
+
+	responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, audio.FormatMP3)
+	hasPause := lo.ContainsBy(segments, func(item speechSegment) bool { return item.pause > 0 })
+
+	// FLAC streams can't be joined, and silence can only be generated in WAV and MP3, chunks of the other
+	// formats are synthesized as WAV and encoded once stitched together.
+	if responseFormat == audio.FormatFLAC || (hasPause && responseFormat != audio.FormatMP3 && responseFormat != audio.FormatWAV) {
+		if !audio.CanEncode(responseFormat) {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetailf("<break> is not supported with response_format %s, use mp3 or one of %v instead", responseFormat, audio.EncodableFormats()).
+				WithSourcePointer("/response_format"))
+		}
+
+		withFormat := opts.WithResponseFormat(audio.FormatWAV)
+		if withFormat.IsError() {
+			return mo.Err[any](withFormat.Error())
+		}
+
+		opts = withFormat.MustGet()
+	}
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+
+	results := lo.Times(len(segments), func(_ int) chan chunkResult { return make(chan chunkResult, 1) })
+	semaphore := make(chan struct{}, maxConcurrentChunks)
+
+	go func() {
+		for i, segment := range segments {
+			if segment.pause > 0 {
+				continue
+			}
+
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				results[i] <- chunkResult{err: ctx.Err()}
+
+				continue
+			}
+
+			go func() {
+				defer func() { <-semaphore }()
+
+				results[i] <- synthesizeChunk(ctx, c, opts, backend.handle, segment.input)
+			}()
+		}
+	}()
+
+	_, firstIndex, _ := lo.FindIndexOf(segments, func(item speechSegment) bool { return item.pause == 0 })
+
+	first := <-results[firstIndex]
+	if first.err != nil {
+		cancel()
+
+		return mo.Err[any](first.err)
+	}
+
+	results[firstIndex] <- first
+
+	// The format of what the provider actually returned, e.g. extra_body.output_format of ElevenLabs
+	// takes precedence over response_format, otherwise the one requested, mp3 when omitted.
+	format, ok := audio.FormatFromContentType(first.contentType)
+	if !ok {
+		format = lo.CoalesceOrEmpty(opts.ResponseFormat, responseFormat)
+	}
+
+	// WAV has the size of the whole audio in its header, so every chunk is needed before responding.
+	if format == audio.FormatWAV {
+		defer cancel()
+
+		data := make([][]byte, 0, len(segments))
+
+		err := stitchSegments(segments, results, format, first.data, func(chunk []byte) error {
+			data = append(data, chunk)
+
+			return nil
+		})
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		wav, err := audio.Concat(audio.FormatWAV, data...)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetailf("failed to concatenate upstream audio: %s", err.Error()).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, responseFormat, types.AudioOutput{})
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		defer cancel()
+
+		err := stitchSegments(segments, results, format, first.data, func(chunk []byte) error {
+			data, err := audio.Concat(format, chunk)
+			if err != nil {
+				return err
+			}
+
+			_, err = writer.Write(data)
+
+			return err
+		})
+
+		_ = writer.CloseWithError(err)
+	}()
+
+	err = utils.StreamWithFlush(c, http.StatusOK, first.contentType, reader)
+
+	// Unblocks the writer when the client went away before every chunk was sent.
+	_ = reader.Close()
+
+	return mo.Ok[any](err)
+}
+
+// stitchSegments passes the audio of every segment to write in order, waiting for each chunk to be
+// synthesized, pauses are generated as silence in the same format as reference.
+func stitchSegments(segments []speechSegment, results []chan chunkResult, format audio.Format, reference []byte, write func(chunk []byte) error) error {
+	for i, segment := range segments {
+		if segment.pause > 0 {
+			silence, err := audio.Silence(format, reference, segment.pause)
+			if err != nil {
+				return apierrors.NewErrBadGateway().WithDetailf("failed to insert a pause: %s", err.Error()).WithCaller()
+			}
+
+			err = write(silence)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		chunk := <-results[i]
+		if chunk.err != nil {
+			return chunk.err
+		}
+
+		err := write(chunk.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunkWriter captures the response of a chunk instead of writing it to the client.
+type chunkWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newChunkWriter() *chunkWriter {
+	return &chunkWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *chunkWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *chunkWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *chunkWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Flush does nothing, it's there for the handlers streaming their response.
+func (w *chunkWriter) Flush() {}
+
+// synthesizeChunk synthesizes a chunk of the input with the handler of the backend, capturing the response
+// instead of writing it to the client.
+func synthesizeChunk(ctx context.Context, c echo.Context, opts types.SpeechRequestOptions, handle speechHandler, input string) chunkResult {
+	chunkOpts := opts.WithInput(input)
+	if chunkOpts.IsError() {
+		return chunkResult{err: chunkOpts.Error()}
+	}
+
+	writer := newChunkWriter()
+
+	result := handle(c.Echo().NewContext(c.Request().Clone(ctx), writer), mo.Some(chunkOpts.MustGet()))
+	if result.IsError() {
+		return chunkResult{err: result.Error()}
+	}
+
+	if writer.status >= http.StatusBadRequest {
+		return chunkResult{err: apierrors.NewUpstreamError(writer.status).WithDetail(writer.body.String())}
+	}
+
+	return chunkResult{contentType: writer.Header().Get(echo.HeaderContentType), data: writer.body.Bytes()}
+}
@@ -0,0 +1,370 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+)
+
+const fakeSampleRate = 24000
+
+// Chunked into one sentence per chunk with a maxInputLength of 6.
+const fakeInput = "One. Two. Three. Four. Five. Six."
+
+// fakeSpeech synthesizes every input into PCM made of the input itself, after a random latency so that
+// chunks finish out of order.
+type fakeSpeech struct {
+	failing string
+	cancel  context.CancelFunc
+	// Sent instead of the Content-Type of the format when set, e.g. application/octet-stream.
+	contentType string
+	inFlight    atomic.Int32
+}
+
+func fakePCM(input string) []byte {
+	return bytes.Repeat([]byte(input), 2) //nolint:mnd
+}
+
+func (f *fakeSpeech) handle(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+
+	opts := options.MustGet()
+
+	if f.cancel != nil && opts.Input == f.failing {
+		f.cancel()
+		<-c.Request().Context().Done()
+
+		return mo.Err[any](c.Request().Context().Err())
+	}
+
+	select {
+	case <-time.After(rand.N(20 * time.Millisecond)): //nolint:gosec
+	case <-c.Request().Context().Done():
+		return mo.Err[any](c.Request().Context().Err())
+	}
+
+	if opts.Input == f.failing {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail("upstream failed"))
+	}
+
+	if opts.ResponseFormat == audio.FormatWAV {
+		return mo.Ok[any](c.Blob(http.StatusOK, lo.CoalesceOrEmpty(f.contentType, audio.ContentType(audio.FormatWAV)), audio.EncodeWAV(fakePCM(opts.Input), fakeSampleRate, 1)))
+	}
+
+	return mo.Ok[any](c.Blob(http.StatusOK, lo.CoalesceOrEmpty(f.contentType, audio.ContentType(audio.FormatPCM)), fakePCM(opts.Input)))
+}
+
+func newFakeSpeechOptions(t *testing.T, input string, responseFormat string) types.SpeechRequestOptions {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{
+		"model":           "fake/model",
+		"voice":           "fake",
+		"input":           input,
+		"response_format": responseFormat,
+	})
+	require.NoError(t, err)
+
+	options := types.NewSpeechRequestOptions(io.NopCloser(bytes.NewReader(body)))
+	require.NoError(t, options.Error())
+
+	return options.MustGet()
+}
+
+func newFakeSpeechContext(ctx context.Context) (echo.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/v1/audio/speech", nil)
+
+	return echo.New().NewContext(req, recorder), recorder
+}
+
+func TestSegmentsOf(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input    string
+		backend  speechBackend
+		expected []speechSegment
+	}{
+		"fits": {
+			input:    fakeInput,
+			backend:  speechBackend{maxInputLength: 100},
+			expected: []speechSegment{{input: fakeInput}},
+		},
+		"no limit": {
+			input:    fakeInput,
+			backend:  speechBackend{},
+			expected: []speechSegment{{input: fakeInput}},
+		},
+		"chunked": {
+			input:   "One. Two. Three.",
+			backend: speechBackend{maxInputLength: 6},
+			expected: []speechSegment{
+				{input: "One."},
+				{input: "Two."},
+				{input: "Three."},
+			},
+		},
+		"pause": {
+			input:   `One. <break time="500ms"/> Two.`,
+			backend: speechBackend{maxInputLength: 100},
+			expected: []speechSegment{
+				{input: "One."},
+				{pause: 500 * time.Millisecond},
+				{input: "Two."},
+			},
+		},
+		"markup chunked for the backend": {
+			input:   `<emphasis>One.</emphasis> Two. <break time="500ms"/> Three.`,
+			backend: speechBackend{maxInputLength: 6, markup: true},
+			expected: []speechSegment{
+				{input: `<emphasis level='moderate'>One.</emphasis>`},
+				{input: `Two. <break time='500ms'/>`},
+				{input: "Three."},
+			},
+		},
+		"chunk without markup unescaped": {
+			input:   `A &amp; B. <emphasis>C</emphasis>`,
+			backend: speechBackend{maxInputLength: 6, markup: true},
+			expected: []speechSegment{
+				{input: "A & B."},
+				{input: `<emphasis level='moderate'>C</emphasis>`},
+			},
+		},
+		"pause rendered by the backend": {
+			input:    `One. <break time="500ms"/> Two.`,
+			backend:  speechBackend{maxInputLength: 100, markup: true},
+			expected: []speechSegment{{input: `One. <break time="500ms"/> Two.`}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			segments := segmentsOf(tc.input, tc.backend)
+			require.NoError(t, segments.Error())
+			assert.Equal(t, tc.expected, segments.MustGet())
+		})
+	}
+
+	t.Run("only pauses", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Error(t, segmentsOf(`<break time="1s"/>`, speechBackend{}).Error())
+		assert.Error(t, segmentsOf(`<break time="1s"/> <break time="1s"/>`, speechBackend{maxInputLength: 1, markup: true}).Error())
+	})
+}
+
+func TestStitchSegments(t *testing.T) {
+	t.Parallel()
+
+	reference := audio.EncodeWAV(fakePCM("One."), fakeSampleRate, 1)
+	segments := []speechSegment{{input: "One."}, {pause: 100 * time.Millisecond}, {input: "Two."}}
+
+	results := []chan chunkResult{make(chan chunkResult, 1), nil, make(chan chunkResult, 1)}
+	results[0] <- chunkResult{data: reference}
+	results[2] <- chunkResult{data: audio.EncodeWAV(fakePCM("Two."), fakeSampleRate, 1)}
+
+	chunks := make([][]byte, 0)
+
+	err := stitchSegments(segments, results, audio.FormatWAV, reference, func(chunk []byte) error {
+		chunks = append(chunks, chunk)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	_, silence, err := audio.DecodeWAV(chunks[1])
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, fakeSampleRate/10*2), silence)
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		results := []chan chunkResult{make(chan chunkResult, 1), make(chan chunkResult, 1)}
+		results[0] <- chunkResult{err: apierrors.NewErrBadGateway()}
+
+		err := stitchSegments([]speechSegment{{input: "One."}, {input: "Two."}}, results, audio.FormatPCM, nil, func([]byte) error {
+			assert.Fail(t, "nothing should be written after a failed chunk")
+
+			return nil
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSynthesizeSpeech(t *testing.T) {
+	t.Parallel()
+
+	expected := bytes.Join([][]byte{
+		fakePCM("One."), fakePCM("Two."), fakePCM("Three."), fakePCM("Four."), fakePCM("Five."), fakePCM("Six."),
+	}, nil)
+
+	// WAV is buffered until every chunk is done, PCM is piped as the chunks finish.
+	for _, format := range []string{audio.FormatWAV, audio.FormatPCM} {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			for range 10 {
+				fake := &fakeSpeech{}
+				c, recorder := newFakeSpeechContext(context.Background())
+
+				result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, format), speechBackend{handle: fake.handle, maxInputLength: 6})
+				require.NoError(t, result.Error())
+				assert.Equal(t, audio.ContentType(format), recorder.Header().Get(echo.HeaderContentType))
+
+				data := recorder.Body.Bytes()
+				if format == audio.FormatWAV {
+					var err error
+
+					_, data, err = audio.DecodeWAV(data)
+					require.NoError(t, err)
+				}
+
+				assert.Equal(t, expected, data)
+			}
+		})
+	}
+}
+
+func TestSynthesizeSpeechSSML(t *testing.T) {
+	t.Parallel()
+
+	// Whole SSML documents are synthesized at once, other elements named alike don't stop chunking.
+	for input, chunked := range map[string]bool{
+		"<speak>" + fakeInput + "</speak>":     false,
+		"<speaker>" + fakeInput + "</speaker>": true,
+	} {
+		fake := &fakeSpeech{}
+
+		var count atomic.Int32
+
+		handle := func(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+			count.Add(1)
+
+			return fake.handle(c, options)
+		}
+
+		c, _ := newFakeSpeechContext(context.Background())
+
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, input, audio.FormatPCM), speechBackend{handle: handle, maxInputLength: 16})
+		require.NoError(t, result.Error(), input)
+		assert.Equal(t, chunked, count.Load() > 1, input)
+	}
+}
+
+func TestSynthesizeSpeechUnknownContentType(t *testing.T) {
+	t.Parallel()
+
+	// Chunks around a pause are synthesized as WAV, which is what the audio is then taken for.
+	fake := &fakeSpeech{contentType: "application/octet-stream"}
+	c, recorder := newFakeSpeechContext(context.Background())
+
+	result := synthesizeSpeech(c, newFakeSpeechOptions(t, `One. <break time="100ms"/> Two.`, audio.FormatPCM), speechBackend{handle: fake.handle})
+	require.NoError(t, result.Error())
+	assert.Equal(t, audio.ContentType(audio.FormatPCM), recorder.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, bytes.Join([][]byte{fakePCM("One."), make([]byte, fakeSampleRate/10*2), fakePCM("Two.")}, nil), recorder.Body.Bytes())
+}
+
+func TestSynthesizeSpeechChunkError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wav", func(t *testing.T) {
+		t.Parallel()
+
+		fake := &fakeSpeech{failing: "Three."}
+		c, _ := newFakeSpeechContext(context.Background())
+
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, audio.FormatWAV), speechBackend{handle: fake.handle, maxInputLength: 6})
+		assert.Error(t, result.Error())
+		assert.Eventually(t, func() bool { return fake.inFlight.Load() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("pcm", func(t *testing.T) {
+		t.Parallel()
+
+		fake := &fakeSpeech{failing: "Three."}
+		c, recorder := newFakeSpeechContext(context.Background())
+
+		// The response is committed by then, so the stream ends right before the failed chunk.
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, audio.FormatPCM), speechBackend{handle: fake.handle, maxInputLength: 6})
+		require.NoError(t, result.Error())
+		assert.Equal(t, bytes.Join([][]byte{fakePCM("One."), fakePCM("Two.")}, nil), recorder.Body.Bytes())
+		assert.Eventually(t, func() bool { return fake.inFlight.Load() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("first", func(t *testing.T) {
+		t.Parallel()
+
+		fake := &fakeSpeech{failing: "One."}
+		c, _ := newFakeSpeechContext(context.Background())
+
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, audio.FormatPCM), speechBackend{handle: fake.handle, maxInputLength: 6})
+		assert.Error(t, result.Error())
+		assert.Eventually(t, func() bool { return fake.inFlight.Load() == 0 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestSynthesizeSpeechCanceled(t *testing.T) {
+	t.Parallel()
+
+	t.Run("before", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		fake := &fakeSpeech{}
+		c, _ := newFakeSpeechContext(ctx)
+
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, audio.FormatWAV), speechBackend{handle: fake.handle, maxInputLength: 6})
+		require.ErrorIs(t, result.Error(), context.Canceled)
+		assert.Eventually(t, func() bool { return fake.inFlight.Load() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("while synthesizing", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The client goes away once the fourth chunk is requested.
+		fake := &fakeSpeech{failing: "Four.", cancel: cancel}
+		c, _ := newFakeSpeechContext(ctx)
+
+		result := synthesizeSpeech(c, newFakeSpeechOptions(t, fakeInput, audio.FormatWAV), speechBackend{handle: fake.handle, maxInputLength: 6})
+		require.ErrorIs(t, result.Error(), context.Canceled)
+		assert.Eventually(t, func() bool { return fake.inFlight.Load() == 0 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestSynthesizeSpeechProsody(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeSpeech{}
+	c, _ := newFakeSpeechContext(context.Background())
+
+	opts := newFakeSpeechOptions(t, fakeInput, audio.FormatPCM)
+	opts.Prosody.Pitch = mo.Some(1.5)
+
+	result := synthesizeSpeech(c, opts, speechBackend{handle: fake.handle})
+	assert.Error(t, result.Error())
+}
@@ -0,0 +1,195 @@
+package deepgram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultRealtimeModel = "nova-3"
+)
+
+// Query parameters consumed by unSpeech, the rest are passed through to Deepgram as they are,
+// e.g. endpointing, utterance_end_ms, smart_format, diarize, etc.
+var realtimeReservedQuery = []string{"provider", "model", "language", "encoding", "sample_rate", "channels"}
+
+// Live Audio | Deepgram's Docs
+// https://developers.deepgram.com/reference/speech-to-text-api/listen-streaming#receive
+type liveWord struct {
+	Word           string  `json:"word"`
+	PunctuatedWord string  `json:"punctuated_word"`
+	Start          float64 `json:"start"`
+	End            float64 `json:"end"`
+	Speaker        *int    `json:"speaker"`
+}
+
+type liveResponse struct {
+	Type     string  `json:"type"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	IsFinal  bool    `json:"is_final"`
+	Channel  struct {
+		Alternatives []struct {
+			Transcript string     `json:"transcript"`
+			Words      []liveWord `json:"words"`
+		} `json:"alternatives"`
+	} `json:"channel"`
+
+	// Present when the type is Error
+	Description string `json:"description"`
+	Message     string `json:"message"`
+}
+
+type realtimeUpstream struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+func (u *realtimeUpstream) SendAudio(audio []byte) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.conn.WriteMessage(websocket.BinaryMessage, audio)
+}
+
+func (u *realtimeUpstream) Finish() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.conn.WriteJSON(map[string]string{"type": "CloseStream"})
+}
+
+func (u *realtimeUpstream) Receive() ([]types.RealtimeTranscriptionEvent, error) {
+	for {
+		_, message, err := u.conn.ReadMessage()
+		if err != nil {
+			// Deepgram closes the connection normally after CloseStream
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil, io.EOF
+			}
+
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		var response liveResponse
+
+		err = json.Unmarshal(message, &response)
+		if err != nil {
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		switch response.Type {
+		case "Results":
+			if len(response.Channel.Alternatives) == 0 {
+				continue
+			}
+
+			alternative := response.Channel.Alternatives[0]
+			if alternative.Transcript == "" {
+				continue
+			}
+
+			transcript := &types.RealtimeTranscript{
+				Text:    alternative.Transcript,
+				IsFinal: response.IsFinal,
+				Start:   response.Start,
+				End:     response.Start + response.Duration,
+				Words: lo.Map(alternative.Words, func(item liveWord, _ int) types.TranscriptionWord {
+					return types.TranscriptionWord{
+						Word:    lo.CoalesceOrEmpty(item.PunctuatedWord, item.Word),
+						Start:   item.Start,
+						End:     item.End,
+						Speaker: lo.Ternary(item.Speaker != nil, strconv.Itoa(lo.FromPtr(item.Speaker)), ""),
+					}
+				}),
+			}
+
+			if len(transcript.Words) > 0 {
+				transcript.Speaker = transcript.Words[0].Speaker
+			}
+
+			return []types.RealtimeTranscriptionEvent{
+				{Type: types.RealtimeTranscriptionEventTypeTranscript, Transcript: transcript},
+			}, nil
+		case "SpeechStarted":
+			return []types.RealtimeTranscriptionEvent{{Type: types.RealtimeTranscriptionEventTypeSpeechStarted}}, nil
+		case "UtteranceEnd":
+			return []types.RealtimeTranscriptionEvent{{Type: types.RealtimeTranscriptionEventTypeSpeechEnded}}, nil
+		case "Error":
+			return nil, apierrors.NewErrBadGateway().WithDetail(lo.CoalesceOrEmpty(response.Description, response.Message))
+		default:
+			// Metadata, etc.
+			continue
+		}
+	}
+}
+
+func (u *realtimeUpstream) Close() error {
+	return u.conn.Close()
+}
+
+func HandleRealtimeTranscription(c echo.Context, options mo.Option[types.RealtimeTranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	query := url.Values{}
+	query.Set("model", lo.CoalesceOrEmpty(opts.Model, defaultRealtimeModel))
+	query.Set("interim_results", "true")
+	query.Set("vad_events", "true")
+
+	if opts.Language != "" {
+		query.Set("language", opts.Language)
+	}
+
+	// Containerized audio (Ogg Opus) is detected automatically, only raw audio needs to be described
+	if opts.Encoding == types.RealtimeAudioEncodingPCM {
+		query.Set("encoding", "linear16")
+		query.Set("sample_rate", strconv.Itoa(opts.SampleRate))
+		query.Set("channels", strconv.Itoa(opts.Channels))
+	}
+
+	for key, values := range opts.ExtraQuery {
+		if lo.Contains(realtimeReservedQuery, key) {
+			continue
+		}
+
+		query[key] = values
+	}
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Token "+opts.Authorization(c))
+
+	// Live Audio | Deepgram's Docs
+	// https://developers.deepgram.com/reference/speech-to-text-api/listen-streaming
+	conn, resp, err := websocket.DefaultDialer.DialContext(
+		c.Request().Context(),
+		fmt.Sprintf("wss://api.deepgram.com/v1/listen?%s", query.Encode()),
+		headers,
+	)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		return mo.Err[any](apierrors.
+			NewUpstreamError(resp.StatusCode).
+			WithDetail(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+			WithCaller())
+	}
+
+	return types.ServeRealtimeTranscription(c, &realtimeUpstream{conn: conn})
+}
@@ -0,0 +1,184 @@
+package deepgram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultRealtimeSpeechModel = "aura-2-thalia-en"
+)
+
+// Query parameters consumed by unSpeech, the rest are passed through to Deepgram as they are, e.g. mip_opt_out.
+var realtimeSpeechReservedQuery = []string{"provider", "model", "voice", "encoding", "sample_rate"}
+
+var (
+	realtimeSpeechEncodings = map[types.RealtimeSpeechEncoding]string{
+		types.RealtimeSpeechEncodingPCM:  "linear16",
+		types.RealtimeSpeechEncodingULaw: "mulaw",
+		types.RealtimeSpeechEncodingALaw: "alaw",
+	}
+
+	// Same as the REST API.
+	//
+	// Media Output Settings | Deepgram's Docs
+	// https://developers.deepgram.com/docs/tts-media-output-settings
+	realtimeSpeechSampleRates = map[types.RealtimeSpeechEncoding][]int{
+		types.RealtimeSpeechEncodingPCM:  linear16SampleRates,
+		types.RealtimeSpeechEncodingULaw: g711SampleRates,
+		types.RealtimeSpeechEncodingALaw: g711SampleRates,
+	}
+)
+
+// Text to Speech Streaming | Deepgram's Docs
+// https://developers.deepgram.com/reference/text-to-speech-api/speak-streaming
+type speakMessage struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type speakResponse struct {
+	Type       string `json:"type"`
+	SequenceID int    `json:"sequence_id"`
+
+	// Present when the type is Warning or Error
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Message     string `json:"err_msg"`
+}
+
+type realtimeSpeechUpstream struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+func (u *realtimeSpeechUpstream) send(message speakMessage) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.conn.WriteJSON(message)
+}
+
+func (u *realtimeSpeechUpstream) Speak(text string) error {
+	return u.send(speakMessage{Type: "Speak", Text: text})
+}
+
+func (u *realtimeSpeechUpstream) Flush() error {
+	return u.send(speakMessage{Type: "Flush"})
+}
+
+func (u *realtimeSpeechUpstream) Clear() error {
+	return u.send(speakMessage{Type: "Clear"})
+}
+
+// Finish asks Deepgram to synthesize the remaining text and close the connection afterwards.
+func (u *realtimeSpeechUpstream) Finish() error {
+	return u.send(speakMessage{Type: "Close"})
+}
+
+func (u *realtimeSpeechUpstream) Receive() (types.RealtimeSpeechMessage, error) {
+	for {
+		messageType, message, err := u.conn.ReadMessage()
+		if err != nil {
+			// Deepgram closes the connection normally after Close
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return types.RealtimeSpeechMessage{}, io.EOF
+			}
+
+			return types.RealtimeSpeechMessage{}, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		if messageType == websocket.BinaryMessage {
+			return types.RealtimeSpeechMessage{Audio: message}, nil
+		}
+
+		var response speakResponse
+
+		err = json.Unmarshal(message, &response)
+		if err != nil {
+			return types.RealtimeSpeechMessage{}, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		switch response.Type {
+		case "Flushed":
+			return types.RealtimeSpeechMessage{Event: &types.RealtimeSpeechEvent{Type: types.RealtimeSpeechEventTypeFlushed}}, nil
+		case "Cleared":
+			return types.RealtimeSpeechMessage{Event: &types.RealtimeSpeechEvent{Type: types.RealtimeSpeechEventTypeCleared}}, nil
+		case "Error":
+			return types.RealtimeSpeechMessage{}, apierrors.
+				NewErrBadGateway().
+				WithDetailf("upstream returned error, code: %s, message: %s", response.Code, lo.CoalesceOrEmpty(response.Description, response.Message))
+		default:
+			// Metadata, Warning, etc.
+			continue
+		}
+	}
+}
+
+func (u *realtimeSpeechUpstream) Close() error {
+	return u.conn.Close()
+}
+
+// HandleRealtimeSpeech synthesizes text sent piece by piece over WebSocket, audio is streamed back as it's
+// generated, and clear discards pending audio so that playback can be interrupted when the user barges in.
+func HandleRealtimeSpeech(c echo.Context, options mo.Option[types.RealtimeSpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	if !lo.Contains(realtimeSpeechSampleRates[opts.Encoding], opts.SampleRate) {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported sample_rate %d for encoding %s, supported ones are %v", opts.SampleRate, opts.Encoding, realtimeSpeechSampleRates[opts.Encoding]).
+			WithSourceParameter("sample_rate"))
+	}
+
+	query := url.Values{}
+	query.Set("model", lo.CoalesceOrEmpty(opts.Voice, opts.Model, defaultRealtimeSpeechModel))
+	query.Set("encoding", realtimeSpeechEncodings[opts.Encoding])
+	query.Set("sample_rate", strconv.Itoa(opts.SampleRate))
+
+	for key, values := range opts.ExtraQuery {
+		if lo.Contains(realtimeSpeechReservedQuery, key) {
+			continue
+		}
+
+		query[key] = values
+	}
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Token "+opts.Authorization(c))
+
+	// Text to Speech Streaming | Deepgram's Docs
+	// https://developers.deepgram.com/reference/text-to-speech-api/speak-streaming
+	conn, resp, err := websocket.DefaultDialer.DialContext(
+		c.Request().Context(),
+		fmt.Sprintf("wss://api.deepgram.com/v1/speak?%s", query.Encode()),
+		headers,
+	)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		return mo.Err[any](apierrors.
+			NewUpstreamError(resp.StatusCode).
+			WithDetail(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+			WithCaller())
+	}
+
+	return types.ServeRealtimeSpeech(c, &realtimeSpeechUpstream{conn: conn})
+}
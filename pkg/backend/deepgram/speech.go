@@ -3,31 +3,139 @@ package deepgram
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
 	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+// MaxInputLength is the maximum number of characters of text in a request.
+//
+// Text to Speech REST | Deepgram's Docs
+// https://developers.deepgram.com/docs/text-to-speech#text-input-requirements
+const MaxInputLength = 2000
+
+type speechEncoding struct {
+	Encoding  string
+	Container string
+	MimeType  string
+	// Default sample rate, and the ones that can be chosen with sample_rate, none for fixed ones.
+	SampleRate  int
+	SampleRates []int
+}
+
+var (
+	linear16SampleRates = []int{8000, 16000, 24000, 32000, 48000} //nolint:mnd
+	g711SampleRates     = []int{8000, 16000}                      //nolint:mnd
+
+	// Media Output Settings | Deepgram's Docs
+	// https://developers.deepgram.com/docs/tts-media-output-settings
+	speechEncodings = map[string]speechEncoding{
+		"mp3":  {Encoding: "mp3", MimeType: "audio/mpeg", SampleRate: 22050},                                                          //nolint:mnd
+		"opus": {Encoding: "opus", Container: "ogg", MimeType: "audio/ogg", SampleRate: 48000},                                        //nolint:mnd
+		"aac":  {Encoding: "aac", MimeType: "audio/aac", SampleRate: 22050},                                                           //nolint:mnd
+		"flac": {Encoding: "flac", MimeType: "audio/flac", SampleRate: 48000, SampleRates: []int{8000, 16000, 22050, 32000, 48000}},   //nolint:mnd
+		"wav":  {Encoding: "linear16", Container: "wav", MimeType: "audio/wav", SampleRate: 24000, SampleRates: linear16SampleRates},  //nolint:mnd
+		"pcm":  {Encoding: "linear16", Container: "none", MimeType: "audio/pcm", SampleRate: 24000, SampleRates: linear16SampleRates}, //nolint:mnd
+		"ulaw": {Encoding: "mulaw", Container: "none", MimeType: "audio/basic", SampleRate: 8000, SampleRates: g711SampleRates},       //nolint:mnd
+		"alaw": {Encoding: "alaw", Container: "none", MimeType: "audio/x-alaw-basic", SampleRate: 8000, SampleRates: g711SampleRates}, //nolint:mnd
+	}
+)
+
+// ProsodyRanges is the prosody Deepgram supports, the speed only, and only by Aura-2 voices.
+//
+// Text to Speech Speed Control | Deepgram's Docs
+// https://developers.deepgram.com/docs/tts-speed-control
+var ProsodyRanges = types.ProsodyRanges{
+	Speed: mo.Some(types.ProsodyRange{Min: 0.7, Max: 1.5}), //nolint:mnd
+}
+
+// authorizationFrom converts the Bearer token into the Token scheme Deepgram expects.
+func authorizationFrom(c echo.Context) string {
+	auth := c.Request().Header.Get("Authorization")
+	if after, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		auth = "Token " + after
+	}
+
+	return auth
+}
+
+// speechQuery describes the model and the output of the synthesized audio, sample_rate, bit_rate and
+// container can be overridden with extra_body.
+func speechQuery(opt types.SpeechRequestOptions, encoding speechEncoding, sampleRate int) url.Values {
+	query := url.Values{}
+
+	if opt.Voice != "" {
+		query.Set("model", opt.Voice)
+	}
+
+	query.Set("encoding", encoding.Encoding)
+
+	if container := lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opt.ExtraBody, "{ .container }"), encoding.Container); container != "" {
+		query.Set("container", container)
+	}
+	if sampleRate != encoding.SampleRate {
+		query.Set("sample_rate", strconv.Itoa(sampleRate))
+	}
+	if bitRate := utils.GetByJSONPath[int](opt.ExtraBody, "{ .bit_rate }"); bitRate != 0 {
+		query.Set("bit_rate", strconv.Itoa(bitRate))
+	}
+	if speed, ok := opt.Prosody.Speed.Get(); ok {
+		query.Set("speed", strconv.FormatFloat(speed, 'f', -1, 64))
+	}
+
+	return query
+}
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
 	opt := options.MustGet()
 
-	// Deepgram uses query parameters for model/voice configuration
-	// https://developers.deepgram.com/docs/text-to-speech
-	u, _ := url.Parse("https://api.deepgram.com/v1/speak")
-	q := u.Query()
+	responseFormat := lo.CoalesceOrEmpty(opt.ResponseFormat, "mp3")
 
-	if opt.Voice != "" {
-		q.Set("model", opt.Voice)
+	encoding, ok := speechEncodings[responseFormat]
+	if !ok {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats are mp3, opus, aac, flac, wav, pcm, ulaw and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	// extra_body.sample_rate is the same as sample_rate.
+	output, err := opt.Output.WithProviderSampleRate(utils.GetByJSONPath[*int](opt.ExtraBody, "{ .sample_rate }"), "/extra_body/sample_rate")
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	opt.Output = output
+	sampleRate := opt.Output.NativeSampleRate(encoding.SampleRates, encoding.SampleRate)
+
+	// Other sample rates and stereo are converted from raw pcm, which can't be done for mp3, opus and aac.
+	native := audio.Spec{Format: responseFormat, SampleRate: sampleRate, Channels: 1}
+	transcoded := !native.Matches(opt.Output.Spec(responseFormat))
+
+	if transcoded {
+		err = types.CheckOutput(native, responseFormat, opt.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		encoding = speechEncodings[audio.FormatPCM]
+		sampleRate = opt.Output.NativeSampleRate(encoding.SampleRates, encoding.SampleRate)
 	}
 
-	u.RawQuery = q.Encode()
+	// Deepgram uses query parameters for model/voice configuration
+	// https://developers.deepgram.com/docs/text-to-speech
+	u, _ := url.Parse("https://api.deepgram.com/v1/speak")
+	u.RawQuery = speechQuery(opt, encoding, sampleRate).Encode()
 
 	// Request body only needs text
 	payload := lo.Must(json.Marshal(map[string]string{
@@ -44,12 +152,7 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
 	}
 
-	auth := c.Request().Header.Get("Authorization")
-	if after, ok := strings.CutPrefix(auth, "Bearer "); ok {
-		auth = "Token " + after
-	}
-
-	req.Header.Set("Authorization", auth)
+	req.Header.Set("Authorization", authorizationFrom(c))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "audio/*")
 
@@ -72,12 +175,24 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		)
 	}
 
-	// Stream audio response
-	// Deepgram returns the audio content directly
-	contentType := res.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "audio/mpeg" // Default to mp3 if not specified
+	if requestID := res.Header.Get("Dg-Request-Id"); requestID != "" {
+		c.Response().Header().Set("Dg-Request-Id", requestID)
+	}
+
+	if transcoded {
+		from := audio.Spec{Format: audio.FormatPCM, SampleRate: sampleRate, Channels: 1}
+		if audio.CanStream(responseFormat) {
+			return types.StreamTranscoded(c, res.Body, from, responseFormat, opt.Output)
+		}
+
+		pcm, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, pcm, from, responseFormat, opt.Output)
 	}
 
-	return mo.Ok[any](c.Stream(http.StatusOK, contentType, res.Body))
+	// Raw encodings are reported as application/octet-stream, prefer the MIME type of the requested format.
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, encoding.MimeType, res.Body))
 }
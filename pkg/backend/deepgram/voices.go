@@ -3,7 +3,6 @@ package deepgram
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
@@ -14,11 +13,14 @@ import (
 
 var (
 	formats = []types.VoiceFormat{
-		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg"},
-		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav"},
-		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac"},
-		{Name: "AAC", Extension: ".aac", MimeType: "audio/aac"},
-		{Name: "OPUS", Extension: ".opus", MimeType: "audio/opus"},
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 22050, FormatCode: "mp3"},            //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, FormatCode: "wav"},             //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, FormatCode: "pcm"},             //nolint:mnd
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 48000, FormatCode: "flac"},         //nolint:mnd
+		{Name: "AAC", Extension: ".aac", MimeType: "audio/aac", SampleRate: 22050, FormatCode: "aac"},             //nolint:mnd
+		{Name: "OPUS", Extension: ".ogg", MimeType: "audio/ogg", SampleRate: 48000, FormatCode: "opus"},           //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 8000, FormatCode: "ulaw"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 8000, FormatCode: "alaw"}, //nolint:mnd
 	}
 )
 
@@ -37,10 +39,7 @@ type DeepgramModelsResponse struct {
 
 func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
 	// Deepgram requires authentication to list models
-	auth := c.Request().Header.Get("Authorization")
-	if after, ok := strings.CutPrefix(auth, "Bearer "); ok {
-		auth = "Token " + after
-	}
+	auth := authorizationFrom(c)
 
 	req, err := http.NewRequestWithContext(
 		c.Request().Context(),
@@ -2,43 +2,239 @@ package elevenlabs
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/markup"
 	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/moeru-ai/unspeech/pkg/utils/jsonpatch"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+// MaxInputLength is the character limit of Multilingual v2, the lowest among the models, Flash and
+// Turbo v2.5 take longer text.
+//
+// Models | ElevenLabs Documentation
+// https://elevenlabs.io/docs/models#character-limits
+const MaxInputLength = 5000
+
+// Default output format of every OpenAI response_format, PCM is 24kHz just like OpenAI does. Formats
+// ElevenLabs doesn't synthesize, e.g. wav and flac, are encoded from PCM. AAC is neither synthesized nor
+// encodable, MP3 is returned in its place.
+var responseFormatCodes = map[string]string{
+	"mp3":  "mp3_44100_128",
+	"opus": "opus_48000_128",
+	"aac":  "mp3_44100_128",
+	"pcm":  "pcm_24000",
+	"wav":  "pcm_24000",
+	"flac": "pcm_24000",
+	"ulaw": "ulaw_8000",
+	"alaw": "alaw_8000",
+}
+
+// Longest pause of a break tag.
+const maxBreak = 3 * time.Second
+
+// Query parameters of the endpoint, they are taken out of extra_body and never sent in the body.
+//
+// Stream speech — ElevenLabs Documentation
+// https://elevenlabs.io/docs/api-reference/text-to-speech/stream#request
+var speechQueryParameters = []string{"output_format", "enable_logging", "optimize_streaming_latency"}
+
+// outputFormatFrom resolves the ElevenLabs output format, extra_body.output_format takes precedence so
+// that every format listed in the voices can be requested, otherwise response_format is translated.
+func outputFormatFrom(opts types.SpeechRequestOptions) mo.Result[types.VoiceFormat] {
+	code := utils.GetByJSONPath[string](opts.ExtraBody, "{ .output_format }")
+	if code == "" {
+		var ok bool
+
+		code, ok = responseFormatCodes[lo.CoalesceOrEmpty(opts.ResponseFormat, "mp3")]
+		if !ok {
+			return mo.Err[types.VoiceFormat](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format, supported formats are mp3, opus, aac, pcm, wav, flac, ulaw and alaw").
+				WithSourcePointer("/response_format"))
+		}
+
+		code = nativeOutputFormat(code, opts.Output)
+	}
+
+	format, ok := lo.Find(formats, func(item types.VoiceFormat) bool { return item.FormatCode == code })
+	if !ok {
+		return mo.Err[types.VoiceFormat](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported output_format %s", code).
+			WithSourcePointer("/extra_body/output_format"))
+	}
+
+	return mo.Ok(format)
+}
+
+// nativeOutputFormat picks the output format of the same codec closest to the sample rate asked for, with
+// the highest bitrate. μ-law and A-law only come at 8kHz, at other sample rates they are encoded from PCM.
+func nativeOutputFormat(code string, output types.AudioOutput) string {
+	if output.SampleRate == 0 {
+		return code
+	}
+
+	codec, _, _ := strings.Cut(code, "_")
+	if (codec == audio.FormatULaw || codec == audio.FormatALaw) && output.SampleRate != 8000 { //nolint:mnd
+		codec = audio.FormatPCM
+	}
+
+	candidates := lo.Filter(formats, func(item types.VoiceFormat, _ int) bool {
+		return strings.HasPrefix(item.FormatCode, codec+"_")
+	})
+	sampleRate := output.NativeSampleRate(lo.Map(candidates, func(item types.VoiceFormat, _ int) int { return item.SampleRate }), 0)
+
+	return lo.MaxBy(
+		lo.Filter(candidates, func(item types.VoiceFormat, _ int) bool { return item.SampleRate == sampleRate }),
+		func(a types.VoiceFormat, b types.VoiceFormat) bool { return a.Bitrate > b.Bitrate },
+	).FormatCode
+}
+
+// ProsodyRanges is the prosody ElevenLabs supports, only the speed can be controlled through
+// voice_settings, the rest is up to the voice.
+//
+// Voice settings — ElevenLabs Documentation
+// https://elevenlabs.io/docs/api-reference/voices/settings/get
+var ProsodyRanges = types.ProsodyRanges{
+	Speed: mo.Some(types.ProsodyRange{Min: 0.7, Max: 1.2}), //nolint:mnd
+}
+
+// voiceSettingsFrom merges the OpenAI speed into extra_body.voice_settings, values set explicitly in
+// voice_settings win.
+func voiceSettingsFrom(opts types.SpeechRequestOptions) map[VoiceSettingsKey]any {
+	voiceSettings := utils.GetByJSONPath[map[VoiceSettingsKey]any](opts.ExtraBody, "{ .voice_settings }")
+
+	if speed, ok := opts.Prosody.Speed.Get(); ok {
+		if voiceSettings == nil {
+			voiceSettings = make(map[VoiceSettingsKey]any)
+		}
+
+		if _, ok := voiceSettings[VoiceSettingKeySpeed]; !ok {
+			voiceSettings[VoiceSettingKeySpeed] = speed
+		}
+	}
+
+	return voiceSettings
+}
+
+// breakTags renders a pause as break tags, each one is at most 3 seconds long.
+//
+// Controls | ElevenLabs Documentation
+// https://elevenlabs.io/docs/best-practices/prompting/controls#pauses
+func breakTags(pause time.Duration) string {
+	tags := make([]string, 0)
+
+	for ; pause > 0; pause -= maxBreak {
+		tags = append(tags, fmt.Sprintf(`<break time="%ss" />`, strconv.FormatFloat(min(pause, maxBreak).Seconds(), 'f', -1, 64)))
+	}
+
+	return strings.Join(tags, " ")
+}
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	format := outputFormatFrom(opts)
+	if format.IsError() {
+		return mo.Err[any](format.Error())
+	}
+
+	// Output formats are named after the codec, e.g. pcm_16000, mp3_44100_128 or ulaw_8000, always in mono.
+	codec, _, _ := strings.Cut(format.MustGet().FormatCode, "_")
+	from := audio.Spec{Format: codec, SampleRate: format.MustGet().SampleRate, Channels: 1}
+
+	responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, codec)
+	if responseFormat == "aac" {
+		responseFormat = audio.FormatMP3
+	}
+
+	// extra_body.output_format may name another codec than response_format, which is then converted into.
+	if codec != responseFormat && (!audio.CanDecode(codec) || !audio.CanEncode(responseFormat)) {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("output_format %s can't be converted into response_format %s", format.MustGet().FormatCode, responseFormat).
+			WithSourcePointer("/extra_body/output_format"))
+	}
+
+	err := types.CheckOutput(from, responseFormat, opts.Output)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	text := opts.Input
+
+	if markup.Contains(text) {
+		nodes := types.ParseMarkup(text)
+		if nodes.IsError() {
+			return mo.Err[any](nodes.Error())
+		}
+
+		text = markup.TextWithBreaks(nodes.MustGet(), breakTags)
+	}
+
+	query := url.Values{}
+	query.Set("output_format", format.MustGet().FormatCode)
+
+	for _, key := range speechQueryParameters {
+		if value, ok := opts.ExtraBody[key]; ok && key != "output_format" {
+			query.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	// Stream speech — ElevenLabs Documentation
+	// https://elevenlabs.io/docs/api-reference/text-to-speech/stream
 	reqURL := lo.Must(url.Parse("https://api.elevenlabs.io/v1/text-to-speech")).
-		JoinPath(options.MustGet().Voice).
-		String()
+		JoinPath(opts.Voice, "stream")
+	reqURL.RawQuery = query.Encode()
+
+	// previous_text, next_text, previous_request_ids and next_request_ids are passed through with
+	// extra_body to keep the prosody continuous between chunks of a longer text.
+	patches := []mo.Option[jsonpatch.JSONPatchOperationObject]{
+		jsonpatch.NewRemove("/model"),
+		jsonpatch.NewRemove("/voice"),
+		jsonpatch.NewRemove("/input"),
+		jsonpatch.NewRemove("/response_format"),
+		jsonpatch.NewRemove("/speed"),
+		jsonpatch.NewRemove("/pitch"),
+		jsonpatch.NewRemove("/volume"),
+		jsonpatch.NewRemove("/sample_rate"),
+		jsonpatch.NewRemove("/channels"),
+		jsonpatch.NewRemove("/resample_quality"),
+		jsonpatch.NewRemove("/extra_body"),
+		jsonpatch.NewAdd("/text", text),
+		jsonpatch.NewAdd("/model_id", opts.Model),
+	}
+
+	for key, value := range opts.ExtraBody {
+		if lo.Contains(speechQueryParameters, key) || key == "voice_settings" {
+			continue
+		}
+
+		patches = append(patches, jsonpatch.NewAdd(strings.Join([]string{"/", key}, ""), value))
+	}
+
+	if voiceSettings := voiceSettingsFrom(opts); voiceSettings != nil {
+		patches = append(patches, jsonpatch.NewAdd("/voice_settings", voiceSettings))
+	}
 
-	// https://elevenlabs.io/docs/api-reference/text-to-speech/convert#request
 	patchedPayload := jsonpatch.ApplyPatches(
-		options.MustGet().AsBuffer().OrElse(new(bytes.Buffer)).Bytes(),
+		opts.AsBuffer().OrElse(new(bytes.Buffer)).Bytes(),
 		mo.Some(jsonpatch.ApplyOptions{AllowMissingPathOnRemove: true}),
-		append(
-			[]mo.Option[jsonpatch.JSONPatchOperationObject]{
-				jsonpatch.NewRemove("/model"),
-				jsonpatch.NewRemove("/voice"),
-				jsonpatch.NewRemove("/input"),
-				jsonpatch.NewAdd("/text", options.MustGet().Input),
-				jsonpatch.NewAdd("/model_id", options.MustGet().Model),
-			},
-			lo.Map(
-				lo.Entries(options.MustGet().ExtraBody),
-				func(item lo.Entry[string, any], index int) mo.Option[jsonpatch.JSONPatchOperationObject] {
-					return jsonpatch.NewAdd(strings.Join([]string{"/", item.Key}, ""), item.Value)
-				})...,
-		)...,
+		patches...,
 	)
 	if patchedPayload.IsError() {
 		return mo.Err[any](apierrors.NewErrInternal().WithDetail(patchedPayload.Error().Error()).WithCaller())
@@ -47,7 +243,7 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 	req, err := http.NewRequestWithContext(
 		c.Request().Context(),
 		http.MethodPost,
-		reqURL,
+		reqURL.String(),
 		bytes.NewBuffer(patchedPayload.MustGet()),
 	)
 	if err != nil {
@@ -85,8 +281,31 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 				slog.String("content_type", res.Header.Get("Content-Type")),
 				slog.String("content_length", res.Header.Get("Content-Length")),
 			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
 		}
 	}
 
-	return mo.Ok[any](c.Stream(http.StatusOK, "audio/mp3", res.Body))
+	// The request ID can be used as previous_request_ids of the next chunk.
+	if requestID := res.Header.Get("request-id"); requestID != "" { //nolint:canonicalheader
+		c.Response().Header().Set("request-id", requestID) //nolint:canonicalheader
+	}
+
+	// The length of the audio is unknown until the stream ends, therefore formats encoded from another codec
+	// are buffered.
+	if codec != responseFormat {
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, data, from, responseFormat, opts.Output)
+	}
+
+	// Raw PCM, μ-law and A-law are resampled while streaming when the sample rate asked for isn't offered.
+	if !from.Matches(opts.Output.Spec(codec)) {
+		return types.StreamTranscoded(c, res.Body, from, codec, opts.Output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, format.MustGet().MimeType, res.Body))
 }
@@ -0,0 +1,225 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultTranscriptionModel = "scribe_v1"
+)
+
+// Create transcript | ElevenLabs Documentation
+// https://elevenlabs.io/docs/api-reference/speech-to-text/convert#response
+type TranscriptionWord struct {
+	Text      string   `json:"text"`
+	Start     float64  `json:"start"`
+	End       float64  `json:"end"`
+	Type      string   `json:"type"` // word, spacing or audio_event
+	SpeakerID string   `json:"speaker_id"`
+	Logprob   *float64 `json:"logprob"`
+}
+
+type TranscriptionResponse struct {
+	LanguageCode        string              `json:"language_code"`
+	LanguageProbability float64             `json:"language_probability"`
+	Text                string              `json:"text"`
+	Words               []TranscriptionWord `json:"words"`
+}
+
+func isSentenceEnd(text string) bool {
+	return strings.HasSuffix(text, ".") ||
+		strings.HasSuffix(text, "?") ||
+		strings.HasSuffix(text, "!") ||
+		strings.HasSuffix(text, "。") ||
+		strings.HasSuffix(text, "？") ||
+		strings.HasSuffix(text, "！")
+}
+
+// toVerboseTranscription groups words into segments, a new segment starts whenever the speaker changes
+// or a sentence ends, since Scribe only returns words.
+func toVerboseTranscription(response TranscriptionResponse) types.VerboseTranscriptionResponse {
+	result := types.VerboseTranscriptionResponse{
+		Language: response.LanguageCode,
+		Text:     response.Text,
+		Segments: make([]types.TranscriptionSegment, 0),
+		Words:    make([]types.TranscriptionWord, 0, len(response.Words)),
+	}
+
+	var current *types.TranscriptionSegment
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+
+		current.Text = strings.TrimSpace(current.Text)
+		if current.Text != "" {
+			current.ID = len(result.Segments)
+			result.Segments = append(result.Segments, *current)
+		}
+
+		current = nil
+	}
+
+	for _, word := range response.Words {
+		result.Duration = max(result.Duration, word.End)
+
+		if word.Type == "spacing" {
+			if current != nil {
+				current.Text += word.Text
+			}
+
+			continue
+		}
+
+		if current != nil && current.Speaker != word.SpeakerID {
+			flush()
+		}
+		if current == nil {
+			current = &types.TranscriptionSegment{
+				Start:   word.Start,
+				Speaker: word.SpeakerID,
+			}
+		}
+
+		current.Text += word.Text
+		current.End = word.End
+
+		if word.Type == "word" {
+			result.Words = append(result.Words, types.TranscriptionWord{
+				Word:    word.Text,
+				Start:   word.Start,
+				End:     word.End,
+				Speaker: word.SpeakerID,
+			})
+		}
+
+		if isSentenceEnd(word.Text) {
+			flush()
+		}
+	}
+
+	flush()
+
+	return result
+}
+
+func HandleTranscription(c echo.Context, options mo.Option[types.TranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	file, err := opts.File.Open()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	defer func() { _ = file.Close() }()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	fields := map[string]string{
+		"model_id": lo.CoalesceOrEmpty(opts.Model, defaultTranscriptionModel),
+		// Word timestamps are always requested since they are needed to build segments
+		"timestamps_granularity": "word",
+	}
+	if opts.Language != "" {
+		fields["language_code"] = opts.Language
+	}
+
+	// Extra fields such as diarize, num_speakers, tag_audio_events are passed as they are
+	for key, value := range opts.ExtraBody {
+		// null leaves the field out, instead of sending <nil>
+		if value == nil {
+			continue
+		}
+
+		fields[key] = utils.ToString(value)
+	}
+
+	for key, value := range fields {
+		err = writer.WriteField(key, value)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", opts.File.Filename)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	// Create transcript | ElevenLabs Documentation
+	// https://elevenlabs.io/docs/api-reference/speech-to-text/convert
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, "https://api.elevenlabs.io/v1/speech-to-text", body)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	// Rewrite the Authorization header
+	//nolint:canonicalheader
+	req.Header.Set("xi-api-key", strings.TrimPrefix(
+		c.Request().Header.Get("Authorization"),
+		"Bearer ",
+	))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	var response TranscriptionResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	return types.WriteTranscription(c, opts.ResponseFormat, toVerboseTranscription(response))
+}
@@ -142,14 +142,22 @@ var (
 		{Name: "MP3 44.1kHz 192kbps", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 44100, Bitrate: 192, FormatCode: "mp3_44100_192"}, //nolint:mnd
 
 		// PCM formats (S16LE)
-		{Name: "PCM 8kHz", Extension: ".wav", MimeType: "audio/wav", SampleRate: 8000, FormatCode: "pcm_8000"},       //nolint:mnd
-		{Name: "PCM 16kHz", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, FormatCode: "pcm_16000"},    //nolint:mnd
-		{Name: "PCM 22.05kHz", Extension: ".wav", MimeType: "audio/wav", SampleRate: 22050, FormatCode: "pcm_22050"}, //nolint:mnd
-		{Name: "PCM 24kHz", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, FormatCode: "pcm_24000"},    //nolint:mnd
-		{Name: "PCM 44.1kHz", Extension: ".wav", MimeType: "audio/wav", SampleRate: 44100, FormatCode: "pcm_44100"},  //nolint:mnd
-
-		// μ-law format
-		{Name: "μ-law 8kHz", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 8000, FormatCode: "ulaw_8000"}, //nolint:mnd
+		{Name: "PCM 8kHz", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 8000, FormatCode: "pcm_8000"},       //nolint:mnd
+		{Name: "PCM 16kHz", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 16000, FormatCode: "pcm_16000"},    //nolint:mnd
+		{Name: "PCM 22.05kHz", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 22050, FormatCode: "pcm_22050"}, //nolint:mnd
+		{Name: "PCM 24kHz", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, FormatCode: "pcm_24000"},    //nolint:mnd
+		{Name: "PCM 44.1kHz", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 44100, FormatCode: "pcm_44100"},  //nolint:mnd
+
+		// Opus formats
+		{Name: "Opus 48kHz 32kbps", Extension: ".opus", MimeType: "audio/ogg", SampleRate: 48000, Bitrate: 32, FormatCode: "opus_48000_32"},    //nolint:mnd
+		{Name: "Opus 48kHz 64kbps", Extension: ".opus", MimeType: "audio/ogg", SampleRate: 48000, Bitrate: 64, FormatCode: "opus_48000_64"},    //nolint:mnd
+		{Name: "Opus 48kHz 96kbps", Extension: ".opus", MimeType: "audio/ogg", SampleRate: 48000, Bitrate: 96, FormatCode: "opus_48000_96"},    //nolint:mnd
+		{Name: "Opus 48kHz 128kbps", Extension: ".opus", MimeType: "audio/ogg", SampleRate: 48000, Bitrate: 128, FormatCode: "opus_48000_128"}, //nolint:mnd
+		{Name: "Opus 48kHz 192kbps", Extension: ".opus", MimeType: "audio/ogg", SampleRate: 48000, Bitrate: 192, FormatCode: "opus_48000_192"}, //nolint:mnd
+
+		// μ-law and A-law formats
+		{Name: "μ-law 8kHz", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 8000, FormatCode: "ulaw_8000"},        //nolint:mnd
+		{Name: "A-law 8kHz", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 8000, FormatCode: "alaw_8000"}, //nolint:mnd
 	}
 )
 
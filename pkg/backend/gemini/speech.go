@@ -0,0 +1,272 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength keeps a request well within the 32k tokens context of the TTS models, and the audio
+// short enough to be generated in one go.
+//
+// Speech generation (text-to-speech) | Gemini API | Google AI for Developers
+// https://ai.google.dev/gemini-api/docs/speech-generation#limitations
+const MaxInputLength = 5000
+
+// ProsodyRanges is empty, Gemini is directed with natural language in the input instead, e.g. "Say slowly: ...".
+var ProsodyRanges = types.ProsodyRanges{}
+
+const (
+	defaultModel      = "gemini-2.5-flash-preview-tts"
+	defaultSampleRate = 24000
+)
+
+type PrebuiltVoiceConfig struct {
+	VoiceName string `json:"voiceName"`
+}
+
+type VoiceConfig struct {
+	PrebuiltVoiceConfig PrebuiltVoiceConfig `json:"prebuiltVoiceConfig"`
+}
+
+type SpeakerVoiceConfig struct {
+	Speaker     string      `json:"speaker"`
+	VoiceConfig VoiceConfig `json:"voiceConfig"`
+}
+
+type MultiSpeakerVoiceConfig struct {
+	SpeakerVoiceConfigs []SpeakerVoiceConfig `json:"speakerVoiceConfigs"`
+}
+
+type SpeechConfig struct {
+	VoiceConfig             *VoiceConfig             `json:"voiceConfig,omitempty"`
+	MultiSpeakerVoiceConfig *MultiSpeakerVoiceConfig `json:"multiSpeakerVoiceConfig,omitempty"`
+	LanguageCode            string                   `json:"languageCode,omitempty"`
+}
+
+type GenerationConfig struct {
+	ResponseModalities []string `json:"responseModalities"`
+	SpeechConfig       any      `json:"speechConfig"`
+	Temperature        *float64 `json:"temperature,omitempty"`
+}
+
+type Part struct {
+	Text       string      `json:"text,omitempty"`
+	InlineData *InlineData `json:"inlineData,omitempty"`
+}
+
+type InlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+type GenerateContentRequest struct {
+	Contents         []Content        `json:"contents"`
+	GenerationConfig GenerationConfig `json:"generationConfig"`
+}
+
+type Candidate struct {
+	Content      Content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+}
+
+type GenerateContentResponse struct {
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Speaker maps a speaker name appearing in the prompt, e.g. `Joe: How's it going today?`, to one
+// of the prebuilt voices.
+type Speaker struct {
+	Speaker string `json:"speaker"`
+	Voice   string `json:"voice"`
+}
+
+func newSpeechConfig(opts types.SpeechRequestOptions) mo.Result[any] {
+	// Allow the full speechConfig to be passed through as it is
+	for _, key := range []string{"speech_config", "speechConfig"} {
+		if raw, ok := opts.ExtraBody[key]; ok && raw != nil {
+			return mo.Ok(raw)
+		}
+	}
+
+	speechConfig := SpeechConfig{
+		LanguageCode: utils.GetByJSONPath[string](opts.ExtraBody, "{ .language_code }"),
+	}
+
+	var speakers []Speaker
+
+	if raw, ok := opts.ExtraBody["speakers"]; ok && raw != nil {
+		speakersJSON, err := json.Marshal(raw)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+
+		err = json.Unmarshal(speakersJSON, &speakers)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrInvalidArgument().WithDetail(err.Error()).WithSourcePointer("/extra_body/speakers"))
+		}
+	}
+
+	if len(speakers) > 0 {
+		speechConfig.MultiSpeakerVoiceConfig = &MultiSpeakerVoiceConfig{
+			SpeakerVoiceConfigs: lo.Map(speakers, func(item Speaker, _ int) SpeakerVoiceConfig {
+				return SpeakerVoiceConfig{
+					Speaker: item.Speaker,
+					VoiceConfig: VoiceConfig{
+						PrebuiltVoiceConfig: PrebuiltVoiceConfig{VoiceName: item.Voice},
+					},
+				}
+			}),
+		}
+
+		return mo.Ok[any](speechConfig)
+	}
+
+	speechConfig.VoiceConfig = &VoiceConfig{
+		PrebuiltVoiceConfig: PrebuiltVoiceConfig{VoiceName: opts.Voice},
+	}
+
+	return mo.Ok[any](speechConfig)
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	// Gemini only returns raw pcm, every format is encoded from it.
+	responseFormat := lo.Ternary(opts.ResponseFormat == "", "wav", opts.ResponseFormat)
+	if !audio.CanEncode(responseFormat) {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats are wav, pcm, flac, ulaw and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	speechConfig := newSpeechConfig(opts)
+	if speechConfig.IsError() {
+		return mo.Err[any](speechConfig.Error())
+	}
+
+	payload, err := json.Marshal(GenerateContentRequest{
+		Contents: []Content{
+			{Parts: []Part{{Text: opts.Input}}},
+		},
+		GenerationConfig: GenerationConfig{
+			ResponseModalities: []string{"AUDIO"},
+			SpeechConfig:       speechConfig.MustGet(),
+			Temperature:        utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .temperature }"),
+		},
+	})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	// Generate speech | Gemini API | Google AI for Developers
+	// https://ai.google.dev/gemini-api/docs/speech-generation
+	reqURL := lo.Must(url.Parse("https://generativelanguage.googleapis.com/v1beta/models")).
+		JoinPath(lo.CoalesceOrEmpty(opts.Model, defaultModel) + ":generateContent").
+		String()
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, reqURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	// Rewrite the Authorization header
+	//nolint:canonicalheader
+	req.Header.Set("x-goog-api-key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	var response GenerateContentResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	pcm := new(bytes.Buffer)
+	sampleRate := defaultSampleRate
+
+	for _, candidate := range response.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.InlineData == nil {
+				continue
+			}
+
+			data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+			if err != nil {
+				return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+			}
+
+			_, _ = pcm.Write(data)
+			sampleRate = sampleRateFromMimeType(part.InlineData.MimeType).OrElse(sampleRate)
+		}
+	}
+
+	if pcm.Len() == 0 {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail("upstream returned no audio data").WithCaller())
+	}
+
+	return types.RespondTranscoded(c, pcm.Bytes(), audio.Spec{Format: audio.FormatPCM, SampleRate: sampleRate, Channels: 1}, responseFormat, opts.Output)
+}
+
+// sampleRateFromMimeType parses the sample rate from MIME types like `audio/L16;codec=pcm;rate=24000`.
+func sampleRateFromMimeType(mimeType string) mo.Option[int] {
+	_, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return mo.None[int]()
+	}
+
+	rate, err := strconv.Atoi(params["rate"])
+	if err != nil {
+		return mo.None[int]()
+	}
+
+	return mo.Some(rate)
+}
@@ -0,0 +1,114 @@
+package gemini
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/mo"
+)
+
+var (
+	// Generate speech | Gemini API | Google AI for Developers
+	// https://ai.google.dev/gemini-api/docs/speech-generation#languages
+	languages = []types.VoiceLanguage{
+		{Code: "ar-EG", Title: "Arabic (Egyptian)"},
+		{Code: "de-DE", Title: "German (Germany)"},
+		{Code: "en-US", Title: "English (US)"},
+		{Code: "es-US", Title: "Spanish (US)"},
+		{Code: "fr-FR", Title: "French (France)"},
+		{Code: "hi-IN", Title: "Hindi (India)"},
+		{Code: "id-ID", Title: "Indonesian (Indonesia)"},
+		{Code: "it-IT", Title: "Italian (Italy)"},
+		{Code: "ja-JP", Title: "Japanese (Japan)"},
+		{Code: "ko-KR", Title: "Korean (Korea)"},
+		{Code: "pt-BR", Title: "Portuguese (Brazil)"},
+		{Code: "ru-RU", Title: "Russian (Russia)"},
+		{Code: "nl-NL", Title: "Dutch (Netherlands)"},
+		{Code: "pl-PL", Title: "Polish (Poland)"},
+		{Code: "th-TH", Title: "Thai (Thailand)"},
+		{Code: "tr-TR", Title: "Turkish (Turkey)"},
+		{Code: "vi-VN", Title: "Vietnamese (Vietnam)"},
+		{Code: "ro-RO", Title: "Romanian (Romania)"},
+		{Code: "uk-UA", Title: "Ukrainian (Ukraine)"},
+		{Code: "bn-BD", Title: "Bengali (Bangladesh)"},
+		{Code: "en-IN", Title: "English (India)"},
+		{Code: "mr-IN", Title: "Marathi (India)"},
+		{Code: "ta-IN", Title: "Tamil (India)"},
+		{Code: "te-IN", Title: "Telugu (India)"},
+	}
+
+	// Gemini returns 24kHz mono signed 16-bit PCM, which is either forwarded as it is or
+	// wrapped into a WAV container.
+	formats = []types.VoiceFormat{
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, FormatCode: "wav"},              //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, FormatCode: "pcm"},              //nolint:mnd
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 24000, FormatCode: "flac"},          //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 24000, FormatCode: "ulaw"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 24000, FormatCode: "alaw"}, //nolint:mnd
+	}
+
+	models = []string{
+		"gemini-2.5-flash-preview-tts",
+		"gemini-2.5-pro-preview-tts",
+	}
+)
+
+// Generate speech | Gemini API | Google AI for Developers
+// https://ai.google.dev/gemini-api/docs/speech-generation#voices
+var prebuiltVoices = []struct {
+	Name  string
+	Style string
+}{
+	{Name: "Zephyr", Style: "Bright"},
+	{Name: "Puck", Style: "Upbeat"},
+	{Name: "Charon", Style: "Informative"},
+	{Name: "Kore", Style: "Firm"},
+	{Name: "Fenrir", Style: "Excitable"},
+	{Name: "Leda", Style: "Youthful"},
+	{Name: "Orus", Style: "Firm"},
+	{Name: "Aoede", Style: "Breezy"},
+	{Name: "Callirrhoe", Style: "Easy-going"},
+	{Name: "Autonoe", Style: "Bright"},
+	{Name: "Enceladus", Style: "Breathy"},
+	{Name: "Iapetus", Style: "Clear"},
+	{Name: "Umbriel", Style: "Easy-going"},
+	{Name: "Algieba", Style: "Smooth"},
+	{Name: "Despina", Style: "Smooth"},
+	{Name: "Erinome", Style: "Clear"},
+	{Name: "Algenib", Style: "Gravelly"},
+	{Name: "Rasalgethi", Style: "Informative"},
+	{Name: "Laomedeia", Style: "Upbeat"},
+	{Name: "Achernar", Style: "Soft"},
+	{Name: "Alnilam", Style: "Firm"},
+	{Name: "Schedar", Style: "Even"},
+	{Name: "Gacrux", Style: "Mature"},
+	{Name: "Pulcherrima", Style: "Forward"},
+	{Name: "Achird", Style: "Friendly"},
+	{Name: "Zubenelgenubi", Style: "Casual"},
+	{Name: "Vindemiatrix", Style: "Gentle"},
+	{Name: "Sadachbia", Style: "Lively"},
+	{Name: "Sadaltager", Style: "Knowledgeable"},
+	{Name: "Sulafat", Style: "Warm"},
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	voices := make([]types.Voice, 0, len(prebuiltVoices))
+
+	for _, voice := range prebuiltVoices {
+		voices = append(voices, types.Voice{
+			ID:          voice.Name,
+			Name:        voice.Name,
+			Description: voice.Style,
+			Labels: map[string]any{
+				"style": voice.Style,
+			},
+			Tags:             []string{voice.Style},
+			Languages:        languages,
+			Formats:          formats,
+			CompatibleModels: models,
+		})
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: voices,
+	})
+}
@@ -0,0 +1,261 @@
+package hume
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength is the maximum number of characters of the text of an utterance.
+//
+// Text-to-speech (TTS) | Hume API
+// https://dev.hume.ai/reference/text-to-speech-tts/synthesize-file
+const MaxInputLength = 5000
+
+type VoiceProvider string
+
+const (
+	VoiceProviderHumeAI      VoiceProvider = "HUME_AI"
+	VoiceProviderCustomVoice VoiceProvider = "CUSTOM_VOICE"
+)
+
+type Voice struct {
+	ID       string        `json:"id,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Provider VoiceProvider `json:"provider,omitempty"`
+}
+
+type Utterance struct {
+	Text string `json:"text"`
+	// Natural language instructions describing how the utterance should be spoken, e.g.
+	// `whispering, nervous`, which is how acting direction and emotions are expressed.
+	Description     string   `json:"description,omitempty"`
+	Voice           *Voice   `json:"voice,omitempty"`
+	Speed           *float64 `json:"speed,omitempty"`
+	TrailingSilence *float64 `json:"trailing_silence,omitempty"`
+}
+
+// UtteranceOptions is the shape accepted from extra_body.utterances, where voice is referenced
+// by either ID or name as a plain string.
+type UtteranceOptions struct {
+	Text            string   `json:"text"`
+	Description     string   `json:"description"`
+	Voice           string   `json:"voice"`
+	Speed           *float64 `json:"speed"`
+	TrailingSilence *float64 `json:"trailing_silence"`
+}
+
+type Format struct {
+	Type string `json:"type"`
+}
+
+type SpeechRequest struct {
+	Utterances      []Utterance    `json:"utterances"`
+	Format          Format         `json:"format"`
+	Context         map[string]any `json:"context,omitempty"`
+	NumGenerations  int            `json:"num_generations"`
+	SplitUtterances *bool          `json:"split_utterances,omitempty"`
+	Version         string         `json:"version,omitempty"`
+	InstantMode     *bool          `json:"instant_mode,omitempty"`
+}
+
+var (
+	// Formats Hume synthesizes natively, the others, e.g. flac and ulaw, are transcoded from wav.
+	//
+	// Text-to-speech (TTS) | Hume API
+	// https://dev.hume.ai/reference/text-to-speech-tts/synthesize-file-streaming
+	contentTypes = map[string]string{
+		"mp3": "audio/mpeg",
+		"wav": "audio/wav",
+		"pcm": "audio/pcm",
+	}
+)
+
+// ProsodyRanges is the prosody Hume supports, only the speed of utterances can be controlled, the rest is
+// up to the acting direction.
+var ProsodyRanges = types.ProsodyRanges{
+	Speed: mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+}
+
+// speedOf prefers the speed given explicitly to the one of the request.
+func speedOf(opts types.SpeechRequestOptions, speed *float64) *float64 {
+	if speed != nil || opts.Prosody.Speed.IsAbsent() {
+		return speed
+	}
+
+	return lo.ToPtr(opts.Prosody.Speed.MustGet())
+}
+
+func newVoice(id string, provider string) *Voice {
+	if id == "" {
+		return nil
+	}
+
+	// Voices can be referenced either by ID or by name
+	if _, err := uuid.Parse(id); err == nil {
+		return &Voice{ID: id}
+	}
+
+	return &Voice{
+		Name:     id,
+		Provider: VoiceProvider(lo.CoalesceOrEmpty(provider, string(VoiceProviderHumeAI))),
+	}
+}
+
+func newUtterances(opts types.SpeechRequestOptions) mo.Result[[]Utterance] {
+	voiceProvider := utils.GetByJSONPath[string](opts.ExtraBody, "{ .voice_provider }")
+
+	// Utterances with individual voices and acting directions
+	if raw, ok := opts.ExtraBody["utterances"]; ok && raw != nil {
+		utterancesJSON, err := json.Marshal(raw)
+		if err != nil {
+			return mo.Err[[]Utterance](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+
+		var utterances []UtteranceOptions
+
+		err = json.Unmarshal(utterancesJSON, &utterances)
+		if err != nil {
+			return mo.Err[[]Utterance](apierrors.NewErrInvalidArgument().WithDetail(err.Error()).WithSourcePointer("/extra_body/utterances"))
+		}
+
+		return mo.Ok(lo.Map(utterances, func(item UtteranceOptions, _ int) Utterance {
+			return Utterance{
+				Text:            item.Text,
+				Description:     item.Description,
+				Voice:           newVoice(lo.CoalesceOrEmpty(item.Voice, opts.Voice), voiceProvider),
+				Speed:           speedOf(opts, item.Speed),
+				TrailingSilence: item.TrailingSilence,
+			}
+		}))
+	}
+
+	// For compatibility with the emotion field of other providers (e.g. Volcengine), emotion is
+	// used as acting direction when no description was given.
+	description := lo.CoalesceOrEmpty(
+		utils.GetByJSONPath[string](opts.ExtraBody, "{ .description }"),
+		utils.GetByJSONPath[string](opts.ExtraBody, "{ .emotion }"),
+	)
+
+	return mo.Ok([]Utterance{
+		{
+			Text:            opts.Input,
+			Description:     description,
+			Voice:           newVoice(opts.Voice, voiceProvider),
+			Speed:           speedOf(opts, utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .speed }")),
+			TrailingSilence: utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .trailing_silence }"),
+		},
+	})
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	responseFormat := lo.Ternary(opts.ResponseFormat == "", "mp3", opts.ResponseFormat)
+	format := responseFormat
+
+	contentType, ok := contentTypes[format]
+	if !ok {
+		if !audio.CanEncode(responseFormat) {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format, supported formats are mp3, wav, pcm, flac, ulaw and alaw").
+				WithSourcePointer("/response_format"))
+		}
+
+		format = audio.FormatWAV
+	}
+
+	// There is no sample rate to choose from, so wav is requested to resample it instead.
+	if opts.Output.IsSpecified() {
+		err := types.CheckOutput(audio.Spec{Format: responseFormat}, responseFormat, opts.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		format = audio.FormatWAV
+	}
+
+	utterances := newUtterances(opts)
+	if utterances.IsError() {
+		return mo.Err[any](utterances.Error())
+	}
+
+	payload, err := json.Marshal(SpeechRequest{
+		Utterances:      utterances.MustGet(),
+		Format:          Format{Type: format},
+		Context:         utils.GetByJSONPath[map[string]any](opts.ExtraBody, "{ .context }"),
+		NumGenerations:  1,
+		SplitUtterances: utils.GetByJSONPath[*bool](opts.ExtraBody, "{ .split_utterances }"),
+		Version:         lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .version }"), opts.Model),
+		InstantMode:     utils.GetByJSONPath[*bool](opts.ExtraBody, "{ .instant_mode }"),
+	})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(
+		c.Request().Context(),
+		http.MethodPost,
+		"https://api.hume.ai/v0/tts/stream/file",
+		bytes.NewBuffer(payload),
+	)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	// Rewrite the Authorization header
+	req.Header.Set("X-Hume-Api-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	if format != responseFormat || opts.Output.IsSpecified() {
+		wav, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, responseFormat, opts.Output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, contentType, res.Body))
+}
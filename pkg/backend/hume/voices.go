@@ -0,0 +1,152 @@
+package hume
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	voicesPageSize = 100
+)
+
+var (
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", FormatCode: "mp3"},
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", FormatCode: "wav"},
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", FormatCode: "pcm"},
+		// Synthesized as WAV and transcoded.
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", FormatCode: "wav"},
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", FormatCode: "wav"},
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", FormatCode: "wav"},
+	}
+
+	// Acting directions and other expressive controls that can be set through extra_body.
+	predefinedOptions = map[string]any{
+		"description":      "",
+		"speed":            map[string]any{"min": 0.5, "max": 2, "default": 1},
+		"trailing_silence": map[string]any{"min": 0, "max": 5, "default": 0},
+	}
+)
+
+type ListVoicesResponseVoice struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Provider VoiceProvider `json:"provider"`
+}
+
+type ListVoicesResponse struct {
+	PageNumber int                       `json:"page_number"`
+	PageSize   int                       `json:"page_size"`
+	TotalPages int                       `json:"total_pages"`
+	VoicesPage []ListVoicesResponseVoice `json:"voices_page"`
+}
+
+func listVoicesPage(ctx context.Context, apiKey string, provider string, page int) mo.Result[ListVoicesResponse] {
+	query := url.Values{}
+	query.Set("provider", provider)
+	query.Set("page_number", strconv.Itoa(page))
+	query.Set("page_size", strconv.Itoa(voicesPageSize))
+
+	// List voices | Hume API
+	// https://dev.hume.ai/reference/text-to-speech-tts/voices/list
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.hume.ai/v0/tts/voices?"+query.Encode(), nil)
+	if err != nil {
+		return mo.Err[ListVoicesResponse](apierrors.NewErrInternal().WithError(err).WithCaller())
+	}
+
+	req.Header.Set("X-Hume-Api-Key", apiKey)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[ListVoicesResponse](apierrors.NewErrBadGateway().WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[ListVoicesResponse](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[ListVoicesResponse](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[ListVoicesResponse](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	var response ListVoicesResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[ListVoicesResponse](apierrors.NewErrBadGateway().WithError(err).WithCaller())
+	}
+
+	return mo.Ok(response)
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	apiKey := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	// `provider` query parameter is taken by unSpeech for selecting backend
+	providers := []string{string(VoiceProviderHumeAI), string(VoiceProviderCustomVoice)}
+	if voiceProvider := options.MustGet().ExtraQuery.Get("voice_provider"); voiceProvider != "" {
+		providers = []string{voiceProvider}
+	}
+
+	voices := make([]types.Voice, 0)
+
+	for _, provider := range providers {
+		for page := 0; ; page++ {
+			res := listVoicesPage(c.Request().Context(), apiKey, provider, page)
+			if res.IsError() {
+				return mo.Err[any](res.Error())
+			}
+
+			for _, voice := range res.MustGet().VoicesPage {
+				voices = append(voices, types.Voice{
+					ID:          voice.ID,
+					Name:        voice.Name,
+					Description: voice.Name,
+					Labels: map[string]any{
+						types.VoiceLabelKeyType: voice.Provider,
+					},
+					Tags:              make([]string, 0),
+					Languages:         []types.VoiceLanguage{{Code: "en-US", Title: "English"}},
+					Formats:           formats,
+					CompatibleModels:  []string{"1", "2"},
+					PredefinedOptions: lo.Assign(predefinedOptions, map[string]any{"voice_provider": voice.Provider}),
+				})
+			}
+
+			if page+1 >= res.MustGet().TotalPages {
+				break
+			}
+		}
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: voices,
+	})
+}
@@ -0,0 +1,69 @@
+package iflytek
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type credentials struct {
+	AppID     string
+	APIKey    string
+	APISecret string
+}
+
+// parseCredentials extracts APIKey and APISecret from the Authorization header, which is expected
+// to be in the form of `Bearer <APIKey>:<APISecret>`, the AppID is carried within extra_body.
+func parseCredentials(authorization string, appID string) mo.Option[credentials] {
+	apiKey, apiSecret, ok := strings.Cut(strings.TrimPrefix(authorization, "Bearer "), ":")
+	if !ok || apiKey == "" || apiSecret == "" || appID == "" {
+		return mo.None[credentials]()
+	}
+
+	return mo.Some(credentials{
+		AppID:     appID,
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+	})
+}
+
+// signURL builds the authenticated WebSocket URL.
+//
+// 在线语音合成 API 文档 | 讯飞开放平台文档中心
+// https://www.xfyun.cn/doc/tts/online_tts/API.html#%E6%8E%A5%E5%8F%A3%E9%89%B4%E6%9D%83
+func signURL(rawURL string, creds credentials, now time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	date := now.UTC().Format(time.RFC1123)
+	date = strings.Replace(date, "UTC", "GMT", 1)
+
+	signatureOrigin := fmt.Sprintf("host: %s\ndate: %s\nGET %s HTTP/1.1", u.Host, date, u.Path)
+
+	mac := hmac.New(sha256.New, []byte(creds.APISecret))
+	_, _ = mac.Write([]byte(signatureOrigin))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	authorizationOrigin := fmt.Sprintf(
+		`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`,
+		creds.APIKey,
+		signature,
+	)
+
+	query := url.Values{}
+	query.Set("authorization", base64.StdEncoding.EncodeToString([]byte(authorizationOrigin)))
+	query.Set("date", date)
+	query.Set("host", u.Host)
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,348 @@
+package iflytek
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength is the maximum number of characters in a request, the text is limited to 8000 bytes
+// before base64 encoding, i.e. about 2000 Chinese characters.
+//
+// 在线语音合成 API 文档 | 讯飞开放平台文档中心
+// https://www.xfyun.cn/doc/tts/online_tts/API.html
+const MaxInputLength = 2000
+
+const (
+	ttsURL = "wss://tts-api.xfyun.cn/v2/tts"
+)
+
+type FrameStatus int
+
+const (
+	FrameStatusFirst    FrameStatus = 0
+	FrameStatusContinue FrameStatus = 1
+	FrameStatusLast     FrameStatus = 2
+)
+
+type RequestCommon struct {
+	AppID string `json:"app_id"`
+}
+
+type RequestBusiness struct {
+	Aue    string  `json:"aue"`
+	Sfl    *int    `json:"sfl,omitempty"`
+	Auf    string  `json:"auf,omitempty"`
+	Vcn    string  `json:"vcn"`
+	Speed  int     `json:"speed"`
+	Volume int     `json:"volume"`
+	Pitch  int     `json:"pitch"`
+	Bgs    *int    `json:"bgs,omitempty"`
+	Tte    string  `json:"tte"`
+	Reg    *string `json:"reg,omitempty"`
+	Rdn    *string `json:"rdn,omitempty"`
+}
+
+type RequestData struct {
+	Status FrameStatus `json:"status"`
+	Text   string      `json:"text"`
+}
+
+type Request struct {
+	Common   RequestCommon   `json:"common"`
+	Business RequestBusiness `json:"business"`
+	Data     RequestData     `json:"data"`
+}
+
+type ResponseData struct {
+	Audio  string      `json:"audio"`
+	Ced    string      `json:"ced"`
+	Status FrameStatus `json:"status"`
+}
+
+type Response struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	SID     string        `json:"sid"`
+	Data    *ResponseData `json:"data"`
+}
+
+type audioEncoding struct {
+	Aue         string
+	Sfl         *int
+	ContentType string
+	// Synthesized as raw and encoded once finished, since the length of the audio is unknown until then.
+	Buffered bool
+}
+
+var (
+	// 在线语音合成 API 文档 | 讯飞开放平台文档中心
+	// https://www.xfyun.cn/doc/tts/online_tts/API.html#%E4%B8%9A%E5%8A%A1%E5%8F%82%E6%95%B0
+	audioEncodings = map[string]audioEncoding{
+		"mp3":   {Aue: "lame", Sfl: lo.ToPtr(1), ContentType: "audio/mpeg"},
+		"pcm":   {Aue: "raw", ContentType: "audio/pcm"},
+		"opus":  {Aue: "opus-wb", ContentType: "audio/opus"},
+		"speex": {Aue: "speex-wb", ContentType: "audio/speex"},
+		"wav":   {Aue: "raw", ContentType: "audio/wav", Buffered: true},
+		"flac":  {Aue: "raw", ContentType: "audio/flac", Buffered: true},
+		"ulaw":  {Aue: "raw", ContentType: "audio/basic", Buffered: true},
+		"alaw":  {Aue: "raw", ContentType: "audio/x-alaw-basic", Buffered: true},
+	}
+	sampleRates = []int{8000, 16000} //nolint:mnd
+
+	// ProsodyRanges is the prosody iFlytek supports, speed, volume and pitch range from 0 to 100 with 50
+	// as normal.
+	ProsodyRanges = types.ProsodyRanges{
+		Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Pitch:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+		Volume: mo.Some(types.ProsodyRange{Min: 0, Max: 2.0}),   //nolint:mnd
+	}
+	prosodyScale = types.ProsodyScale{Min: 0, Normal: 50, Max: 100} //nolint:mnd
+
+	// 错误码 | 讯飞开放平台文档中心
+	// https://www.xfyun.cn/document/error-code
+	errorCodeStatuses = map[int]int{
+		10005: http.StatusUnauthorized,       // licc fail, appid authorization failed
+		10105: http.StatusForbidden,          // illegal access, no authorization
+		10106: http.StatusBadRequest,         // invalid parameter
+		10107: http.StatusBadRequest,         // illegal parameter value
+		10109: http.StatusBadRequest,         // text length illegal
+		10110: http.StatusForbidden,          // no license
+		10160: http.StatusBadRequest,         // request data format illegal
+		10161: http.StatusBadRequest,         // base64 decode failed
+		10163: http.StatusBadRequest,         // parameter validation failed
+		10313: http.StatusUnauthorized,       // app_id mismatched with api_key
+		11200: http.StatusForbidden,          // voice or feature not authorized
+		11201: http.StatusTooManyRequests,    // daily quota exceeded
+		10200: http.StatusGatewayTimeout,     // read data timeout
+		10222: http.StatusBadGateway,         // network error
+		10700: http.StatusServiceUnavailable, // engine error
+	}
+)
+
+func newErrFromResponse(res Response) *apierrors.Error {
+	status, ok := errorCodeStatuses[res.Code]
+	if !ok {
+		status = http.StatusBadGateway
+	}
+
+	return apierrors.NewUpstreamError(status).
+		WithDetailf("iflytek error code: %d, message: %s, sid: %s", res.Code, res.Message, res.SID).
+		WithMeta("code", res.Code).
+		WithMeta("sid", res.SID)
+}
+
+func readFrame(conn *websocket.Conn) (Response, error) {
+	var res Response
+
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return res, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+
+	err = json.Unmarshal(message, &res)
+	if err != nil {
+		return res, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+	if res.Code != 0 {
+		return res, newErrFromResponse(res)
+	}
+
+	return res, nil
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	creds := parseCredentials(
+		c.Request().Header.Get("Authorization"),
+		utils.GetByJSONPath[string](opts.ExtraBody, "{ .app_id }"),
+	)
+	if creds.IsAbsent() {
+		return mo.Err[any](apierrors.
+			NewErrUnauthorized().
+			WithDetail("iflytek requires Authorization header in the form of `Bearer <APIKey>:<APISecret>` and extra_body.app_id").
+			WithSourceHeader("Authorization"))
+	}
+
+	responseFormat := lo.Ternary(opts.ResponseFormat == "", "mp3", opts.ResponseFormat)
+
+	encoding, ok := audioEncodings[responseFormat]
+	if !ok {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats are mp3, pcm, opus, speex, wav, flac, ulaw and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	// extra_body.sample_rate is the same as sample_rate, other rates than 8000 and 16000 are resampled.
+	output, err := opts.Output.WithProviderSampleRate(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .sample_rate }"), "/extra_body/sample_rate")
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	opts.Output = output
+	sampleRate := lo.ToPtr(opts.Output.NativeSampleRate(sampleRates, 16000)) //nolint:mnd
+
+	// Other sample rates and stereo are converted from raw audio, which can't be done for the compressed formats.
+	err = types.CheckOutput(audio.Spec{Format: responseFormat, SampleRate: *sampleRate, Channels: 1}, responseFormat, opts.Output)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	// Values given in extra_body are on the scale of iFlytek already.
+	speed := utils.GetByJSONPath[*int](opts.ExtraBody, "{ .speed }")
+	if speed == nil {
+		speed = lo.ToPtr(prosodyScale.MapInt(opts.Prosody.Speed, ProsodyRanges.Speed.MustGet()))
+	}
+
+	volume := utils.GetByJSONPath[*int](opts.ExtraBody, "{ .volume }")
+	if volume == nil {
+		volume = lo.ToPtr(prosodyScale.MapInt(opts.Prosody.Volume, ProsodyRanges.Volume.MustGet()))
+	}
+
+	pitch := utils.GetByJSONPath[*int](opts.ExtraBody, "{ .pitch }")
+	if pitch == nil {
+		pitch = lo.ToPtr(prosodyScale.MapInt(opts.Prosody.Pitch, ProsodyRanges.Pitch.MustGet()))
+	}
+
+	signedURL, err := signURL(ttsURL, creds.MustGet(), time.Now())
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(c.Request().Context(), signedURL, nil)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		// Pass upstream error, local error (wss badhandshake) is not helpful
+		ct := resp.Header.Get("Content-Type")
+
+		switch {
+		case strings.HasPrefix(ct, "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(resp.StatusCode).
+				WithDetail(utils.NewJSONResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+				WithCaller())
+		case strings.HasPrefix(ct, "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(resp.StatusCode).
+				WithDetail(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+				WithCaller())
+		default:
+			slog.Warn("unknown upstream error",
+				slog.Int("status", resp.StatusCode),
+				slog.String("content_type", ct),
+				slog.String("content_length", resp.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.
+				NewUpstreamError(resp.StatusCode).
+				WithDetail("unknown Content-Type: " + ct).
+				WithCaller())
+		}
+	}
+
+	err = conn.WriteJSON(Request{
+		Common: RequestCommon{
+			AppID: creds.MustGet().AppID,
+		},
+		Business: RequestBusiness{
+			Aue:    encoding.Aue,
+			Sfl:    encoding.Sfl,
+			Auf:    fmt.Sprintf("audio/L16;rate=%d", *sampleRate),
+			Vcn:    opts.Voice,
+			Speed:  *speed,
+			Volume: *volume,
+			Pitch:  *pitch,
+			Bgs:    utils.GetByJSONPath[*int](opts.ExtraBody, "{ .bgs }"),
+			Tte:    "UTF8",
+			Reg:    utils.GetByJSONPath[*string](opts.ExtraBody, "{ .reg }"),
+			Rdn:    utils.GetByJSONPath[*string](opts.ExtraBody, "{ .rdn }"),
+		},
+		Data: RequestData{
+			Status: FrameStatusLast,
+			Text:   base64.StdEncoding.EncodeToString([]byte(opts.Input)),
+		},
+	})
+	if err != nil {
+		_ = conn.Close()
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	// Wait for the first frame before committing the response, so that errors like
+	// authorization failures can still be reported with the correct status code.
+	first, err := readFrame(conn)
+	if err != nil {
+		_ = conn.Close()
+		return mo.Err[any](err)
+	}
+
+	reader, writer := io.Pipe()
+	defer func() { _ = reader.Close() }()
+
+	go func() {
+		defer func() { _ = conn.Close() }()
+
+		frame := first
+
+		for {
+			if frame.Data != nil && frame.Data.Audio != "" {
+				data, err := base64.StdEncoding.DecodeString(frame.Data.Audio)
+				if err != nil {
+					_ = writer.CloseWithError(err)
+					return
+				}
+
+				_, err = writer.Write(data)
+				if err != nil {
+					return
+				}
+			}
+
+			if frame.Data != nil && frame.Data.Status == FrameStatusLast {
+				_ = writer.Close()
+				return
+			}
+
+			frame, err = readFrame(conn)
+			if err != nil {
+				_ = writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	if encoding.Buffered {
+		pcm, err := io.ReadAll(reader)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, pcm, audio.Spec{Format: audio.FormatPCM, SampleRate: *sampleRate, Channels: 1}, responseFormat, opts.Output)
+	}
+
+	if responseFormat == audio.FormatPCM {
+		return types.StreamTranscoded(c, reader, audio.Spec{Format: audio.FormatPCM, SampleRate: *sampleRate, Channels: 1}, responseFormat, opts.Output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, encoding.ContentType, reader))
+}
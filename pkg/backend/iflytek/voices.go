@@ -0,0 +1,81 @@
+package iflytek
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/mo"
+)
+
+var (
+	// 在线语音合成 API 文档 | 讯飞开放平台文档中心
+	// https://www.xfyun.cn/doc/tts/online_tts/API.html#%E4%B8%9A%E5%8A%A1%E5%8F%82%E6%95%B0
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 16000, FormatCode: "lame"},        //nolint:mnd
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", SampleRate: 8000, FormatCode: "lame"},         //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 16000, FormatCode: "raw"},          //nolint:mnd
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 8000, FormatCode: "raw"},           //nolint:mnd
+		{Name: "Opus", Extension: ".opus", MimeType: "audio/opus", SampleRate: 16000, FormatCode: "opus-wb"},   //nolint:mnd
+		{Name: "Speex", Extension: ".spx", MimeType: "audio/speex", SampleRate: 16000, FormatCode: "speex-wb"}, //nolint:mnd
+		// Synthesized as raw PCM and transcoded.
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, FormatCode: "raw"},             //nolint:mnd
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 16000, FormatCode: "raw"},          //nolint:mnd
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 16000, FormatCode: "raw"},        //nolint:mnd
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 16000, FormatCode: "raw"}, //nolint:mnd
+	}
+
+	predefinedOptions = map[string]any{
+		"speed":  map[string]any{"min": 0, "max": 100, "default": 50},
+		"volume": map[string]any{"min": 0, "max": 100, "default": 50},
+		"pitch":  map[string]any{"min": 0, "max": 100, "default": 50},
+	}
+
+	zhCN = types.VoiceLanguage{Code: "zh-CN", Title: "中文普通话"}
+	enUS = types.VoiceLanguage{Code: "en-US", Title: "English"}
+)
+
+type voice struct {
+	ID          string
+	Name        string
+	Description string
+	Gender      string
+	Age         string
+	Languages   []types.VoiceLanguage
+}
+
+// Voices that are available for free (basic) tier, other voices require purchasing and
+// enabling them in the console before being used.
+//
+// 发音人列表 | 讯飞开放平台
+// https://console.xfyun.cn/services/tts
+var voices = []voice{
+	{ID: "xiaoyan", Name: "小燕", Description: "甜美女声", Gender: "female", Age: "young adult", Languages: []types.VoiceLanguage{zhCN, enUS}},
+	{ID: "aisjiuxu", Name: "许久", Description: "亲切男声", Gender: "male", Age: "young adult", Languages: []types.VoiceLanguage{zhCN, enUS}},
+	{ID: "aisxping", Name: "小萍", Description: "知性女声", Gender: "female", Age: "young adult", Languages: []types.VoiceLanguage{zhCN, enUS}},
+	{ID: "aisjinger", Name: "小婧", Description: "亲切女声", Gender: "female", Age: "young adult", Languages: []types.VoiceLanguage{zhCN, enUS}},
+	{ID: "aisbabyxu", Name: "许小宝", Description: "可爱童声", Gender: "male", Age: "child", Languages: []types.VoiceLanguage{zhCN, enUS}},
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	result := make([]types.Voice, 0, len(voices))
+
+	for _, v := range voices {
+		result = append(result, types.Voice{
+			ID:          v.ID,
+			Name:        v.Name,
+			Description: v.Description,
+			Labels: map[string]any{
+				types.VoiceLabelKeyGender: v.Gender,
+				types.VoiceLabelKeyAge:    v.Age,
+			},
+			Tags:              make([]string, 0),
+			Languages:         v.Languages,
+			Formats:           formats,
+			CompatibleModels:  []string{"v2"},
+			PredefinedOptions: predefinedOptions,
+		})
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: result,
+	})
+}
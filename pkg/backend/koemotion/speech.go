@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"log/slog"
+	"math"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
 	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/moeru-ai/unspeech/pkg/utils/jsonpatch"
@@ -17,20 +19,95 @@ import (
 	"github.com/vincent-petithory/dataurl"
 )
 
+// MaxInputLength keeps the text short enough for Koemotion, which takes a short text of a few
+// sentences at a time.
+const MaxInputLength = 100
+
+// Formats Koemotion synthesizes natively, the others, e.g. pcm and flac, are transcoded from wav.
+var outputFormats = []string{"mp3", "wav"}
+
+// ProsodyRanges is the prosody Koemotion supports, pitch_shift is in cents, within ±600 cents, i.e. half
+// an octave.
+var ProsodyRanges = types.ProsodyRanges{
+	Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}),   //nolint:mnd
+	Pitch:  mo.Some(types.ProsodyRange{Min: 0.71, Max: 1.41}), //nolint:mnd
+	Volume: mo.Some(types.ProsodyRange{Min: 0, Max: 2.0}),     //nolint:mnd
+}
+
+// prosodyPatches turns the prosody into speed, pitch_shift and volume_scale, unless given explicitly.
+func prosodyPatches(opts types.SpeechRequestOptions) []mo.Option[jsonpatch.JSONPatchOperationObject] {
+	patches := make([]mo.Option[jsonpatch.JSONPatchOperationObject], 0)
+
+	if speed, ok := opts.Prosody.Speed.Get(); ok && opts.ExtraBody["speed"] == nil {
+		patches = append(patches, jsonpatch.NewAdd("/speed", speed))
+	}
+	if pitch, ok := opts.Prosody.Pitch.Get(); ok && opts.ExtraBody["pitch_shift"] == nil {
+		patches = append(patches, jsonpatch.NewAdd("/pitch_shift", math.Round(1200*math.Log2(pitch)))) //nolint:mnd
+	}
+	if volume, ok := opts.Prosody.Volume.Get(); ok && opts.ExtraBody["volume_scale"] == nil {
+		patches = append(patches, jsonpatch.NewAdd("/volume_scale", volume))
+	}
+
+	return patches
+}
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+	responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, "mp3")
+
+	outputFormat := responseFormat
+	if !lo.Contains(outputFormats, responseFormat) {
+		if !audio.CanEncode(responseFormat) {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format, supported formats are mp3, wav, pcm, flac, ulaw and alaw").
+				WithSourcePointer("/response_format"))
+		}
+
+		outputFormat = audio.FormatWAV
+	}
+
+	// There is no sample rate to choose from, so wav is requested to resample it instead.
+	if opts.Output.IsSpecified() {
+		err := types.CheckOutput(audio.Spec{Format: responseFormat}, responseFormat, opts.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		outputFormat = audio.FormatWAV
+	}
+
+	patches := []mo.Option[jsonpatch.JSONPatchOperationObject]{
+		jsonpatch.NewRemove("/model"),
+		jsonpatch.NewRemove("/voice"),
+		jsonpatch.NewRemove("/input"),
+		jsonpatch.NewRemove("/response_format"),
+		jsonpatch.NewRemove("/speed"),
+		jsonpatch.NewRemove("/pitch"),
+		jsonpatch.NewRemove("/volume"),
+		jsonpatch.NewRemove("/sample_rate"),
+		jsonpatch.NewRemove("/channels"),
+		jsonpatch.NewRemove("/resample_quality"),
+		jsonpatch.NewRemove("/extra_body"),
+		jsonpatch.NewAdd("/text", opts.Input),
+		jsonpatch.NewAdd("/output_format", outputFormat),
+	}
+
+	// Emotions listed as voices are turned into style_weights, unless given explicitly.
+	if styleWeights := styleWeightsOf(opts.Voice); styleWeights.IsPresent() && opts.ExtraBody["style_weights"] == nil {
+		patches = append(patches, jsonpatch.NewAdd("/style_weights", styleWeights.MustGet()))
+	}
+
+	patches = append(patches, prosodyPatches(opts)...)
+
 	// https://developers.rinna.co.jp/api-details#api=koemotion&operation=infer
 	patchedPayload := jsonpatch.ApplyPatches(
-		options.MustGet().AsBuffer().OrElse(new(bytes.Buffer)).Bytes(),
+		opts.AsBuffer().OrElse(new(bytes.Buffer)).Bytes(),
 		mo.Some(jsonpatch.ApplyOptions{AllowMissingPathOnRemove: true}),
 		append(
-			[]mo.Option[jsonpatch.JSONPatchOperationObject]{
-				jsonpatch.NewRemove("/model"),
-				jsonpatch.NewRemove("/voice"),
-				jsonpatch.NewRemove("/input"),
-				jsonpatch.NewAdd("/text", options.MustGet().Input),
-			},
+			patches,
 			lo.Map(
-				lo.Entries(options.MustGet().ExtraBody),
+				lo.Entries(opts.ExtraBody),
 				func(item lo.Entry[string, any], index int) mo.Option[jsonpatch.JSONPatchOperationObject] {
 					return jsonpatch.NewAdd(strings.Join([]string{"/", item.Key}, ""), item.Value)
 				})...,
@@ -100,5 +177,22 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithError(err).WithCaller())
 	}
 
-	return mo.Ok[any](c.Blob(http.StatusOK, "audio/mp3", audioDataURL.Data))
+	if outputFormat != responseFormat || opts.Output.IsSpecified() {
+		// Decode by what the data URL says the audio actually is, e.g. data:audio/mpeg;base64,...
+		from, ok := audio.FormatFromContentType(audioDataURL.ContentType())
+		if !ok {
+			from = outputFormat
+		}
+
+		return types.RespondTranscoded(c, audioDataURL.Data, audio.Spec{Format: from}, responseFormat, opts.Output)
+	}
+
+	// The data URL tells what the audio actually is, e.g. data:audio/mpeg;base64,..., fall back to the
+	// requested format when it doesn't.
+	contentType := audioDataURL.ContentType()
+	if !strings.HasPrefix(contentType, "audio/") {
+		contentType = lo.Ternary(outputFormat == "wav", "audio/wav", "audio/mpeg")
+	}
+
+	return mo.Ok[any](c.Blob(http.StatusOK, contentType, audioDataURL.Data))
 }
@@ -2,11 +2,110 @@ package koemotion
 
 import (
 	"github.com/labstack/echo/v4"
-	"github.com/moeru-ai/unspeech/pkg/apierrors"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+// Koemotion doesn't have named speakers, voices are picked on a 2D speaker map with speaker_x and
+// speaker_y instead, and emotions are blended with style_weights. The catalog lists the default speaker
+// and one voice per emotion, which are turned into style_weights when used as voice.
+//
+// Koemotion API - rinna Developers
+// https://developers.rinna.co.jp/api-details#api=koemotion&operation=infer
+
+const defaultVoice = "default"
+
+type emotion struct {
+	ID   string
+	Name string
+}
+
+var (
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", FormatCode: "mp3"},
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", FormatCode: "wav"},
+		// Synthesized as WAV and transcoded.
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", FormatCode: "wav"},
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", FormatCode: "wav"},
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", FormatCode: "wav"},
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", FormatCode: "wav"},
+	}
+
+	// Emotions in the order of style_weights.
+	emotions = []emotion{
+		{ID: "neutral", Name: "ノーマル"},
+		{ID: "happy", Name: "喜び"},
+		{ID: "angry", Name: "怒り"},
+		{ID: "sad", Name: "悲しみ"},
+		{ID: "calm", Name: "落ち着き"},
+		{ID: "upbeat", Name: "元気"},
+		{ID: "whisper", Name: "ささやき"},
+		{ID: "shout", Name: "叫び"},
+	}
+
+	predefinedOptions = map[string]any{
+		"speaker_x":        map[string]any{"min": -3.0, "max": 3.0, "default": 0.0},
+		"speaker_y":        map[string]any{"min": -3.0, "max": 3.0, "default": 0.0},
+		"style_weights":    map[string]any{"items": lo.Map(emotions, func(item emotion, _ int) string { return item.ID })},
+		"speed":            map[string]any{"min": 0.5, "max": 2.0, "default": 1.0},
+		"pitch_shift":      map[string]any{"min": -600, "max": 600, "default": 0},
+		"intonation_scale": map[string]any{"min": 0.0, "max": 2.0, "default": 1.0},
+		"volume_scale":     map[string]any{"min": 0.0, "max": 2.0, "default": 1.0},
+	}
+
+	languages = []types.VoiceLanguage{
+		{Code: "ja-JP", Title: "日本語"},
+	}
+)
+
+// styleWeightsOf returns style_weights emphasizing the emotion, mo.None is returned for unknown voices.
+func styleWeightsOf(voice string) mo.Option[[]float64] {
+	_, index, ok := lo.FindIndexOf(emotions, func(item emotion) bool { return item.ID == voice })
+	if !ok {
+		return mo.None[[]float64]()
+	}
+
+	weights := make([]float64, len(emotions))
+	weights[index] = 1
+
+	return mo.Some(weights)
+}
+
 func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
-	return mo.Err[any](apierrors.NewErrNotFound().WithDetail("not implemented"))
+	result := make([]types.Voice, 0, len(emotions)+1)
+
+	result = append(result, types.Voice{
+		ID:          defaultVoice,
+		Name:        "Default",
+		Description: "Default speaker, pick others with speaker_x and speaker_y",
+		Labels: map[string]any{
+			types.VoiceLabelKeyType: "speaker",
+		},
+		Tags:              make([]string, 0),
+		Languages:         languages,
+		Formats:           formats,
+		CompatibleModels:  []string{"koemotion"},
+		PredefinedOptions: predefinedOptions,
+	})
+
+	for _, e := range emotions {
+		result = append(result, types.Voice{
+			ID:          e.ID,
+			Name:        e.Name,
+			Description: "Default speaker with the " + e.ID + " style",
+			Labels: map[string]any{
+				types.VoiceLabelKeyType: "emotion",
+			},
+			Tags:              []string{e.ID},
+			Languages:         languages,
+			Formats:           formats,
+			CompatibleModels:  []string{"koemotion"},
+			PredefinedOptions: predefinedOptions,
+		})
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: result,
+	})
 }
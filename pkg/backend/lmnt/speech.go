@@ -0,0 +1,336 @@
+package lmnt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// MaxInputLength is the maximum number of characters of text in a request.
+//
+// Generate speech | LMNT Docs
+// https://docs.lmnt.com/api-reference/speech/synthesize-speech-bytes
+const MaxInputLength = 5000
+
+type audioFormat struct {
+	Format      string
+	ContentType string
+	// Whether the format is available through the streaming WebSocket API
+	Streamable bool
+}
+
+var (
+	// Generate speech | LMNT
+	// https://docs.lmnt.com/api-reference/speech/synthesize-speech-bytes
+	audioFormats = map[string]audioFormat{
+		"mp3":  {Format: "mp3", ContentType: "audio/mpeg", Streamable: true},
+		"wav":  {Format: "wav", ContentType: "audio/wav"},
+		"aac":  {Format: "aac", ContentType: "audio/aac"},
+		"pcm":  {Format: "raw", ContentType: "audio/pcm", Streamable: true},
+		"ulaw": {Format: "ulaw", ContentType: "audio/basic", Streamable: true},
+		"webm": {Format: "webm", ContentType: "audio/webm", Streamable: true},
+		// Synthesized as wav and transcoded.
+		"flac": {Format: "wav", ContentType: "audio/flac"},
+		"alaw": {Format: "wav", ContentType: "audio/x-alaw-basic"},
+	}
+	// Sample rates LMNT synthesizes at, 24kHz by default, always in mono.
+	sampleRates = []int{8000, 16000, 24000} //nolint:mnd
+
+	// ProsodyRanges is the prosody LMNT supports, only the speed, from 0.25 to 2.0.
+	ProsodyRanges = types.ProsodyRanges{
+		Speed: mo.Some(types.ProsodyRange{Min: 0.25, Max: 2.0}), //nolint:mnd
+	}
+)
+
+type SpeechRequest struct {
+	Voice       string   `json:"voice"`
+	Text        string   `json:"text"`
+	Model       string   `json:"model,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	Format      string   `json:"format"`
+	SampleRate  *int     `json:"sample_rate,omitempty"`
+	Speed       *float64 `json:"speed,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// StreamInitMessage is the first message sent over the streaming WebSocket.
+//
+// Speech session | LMNT
+// https://docs.lmnt.com/api-reference/speech/streaming
+type StreamInitMessage struct {
+	APIKey      string   `json:"X-API-Key"` //nolint:tagliatelle
+	Voice       string   `json:"voice"`
+	Model       string   `json:"model,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	Format      string   `json:"format"`
+	SampleRate  *int     `json:"sample_rate,omitempty"`
+	Speed       *float64 `json:"speed,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+type StreamTextMessage struct {
+	Text string `json:"text"`
+}
+
+type StreamEOFMessage struct {
+	EOF bool `json:"eof"`
+}
+
+type StreamServerMessage struct {
+	Error string `json:"error"`
+}
+
+func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	responseFormat := lo.Ternary(opts.ResponseFormat == "", "mp3", opts.ResponseFormat)
+
+	format, ok := audioFormats[responseFormat]
+	if !ok {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats are mp3, wav, aac, pcm, ulaw, webm, flac and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	apiKey := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	speed := utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .speed }")
+	if speed == nil && opts.Prosody.Speed.IsPresent() {
+		speed = lo.ToPtr(opts.Prosody.Speed.MustGet())
+	}
+
+	// extra_body.sample_rate is the same as sample_rate.
+	output, err := opts.Output.WithProviderSampleRate(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .sample_rate }"), "/extra_body/sample_rate")
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	opts.Output = output
+
+	var sampleRate *int
+	if opts.Output.SampleRate != 0 {
+		sampleRate = lo.ToPtr(opts.Output.NativeSampleRate(sampleRates, 24000)) //nolint:mnd
+	}
+
+	// Other sample rates and stereo are converted from wav, pcm and ulaw, which can't be done for the compressed formats.
+	from := audio.Spec{Format: responseFormat, SampleRate: lo.FromPtrOr(sampleRate, 24000), Channels: 1} //nolint:mnd
+
+	err = types.CheckOutput(from, responseFormat, opts.Output)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	request := SpeechRequest{
+		Voice:       opts.Voice,
+		Text:        opts.Input,
+		Model:       opts.Model,
+		Language:    utils.GetByJSONPath[string](opts.ExtraBody, "{ .language }"),
+		Format:      format.Format,
+		SampleRate:  sampleRate,
+		Speed:       speed,
+		Seed:        utils.GetByJSONPath[*int](opts.ExtraBody, "{ .seed }"),
+		Temperature: utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .temperature }"),
+		TopP:        utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .top_p }"),
+	}
+
+	if utils.GetByJSONPath[bool](opts.ExtraBody, "{ .stream }") {
+		if !format.Streamable {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format for streaming, supported formats are mp3, pcm, ulaw and webm").
+				WithSourcePointer("/response_format"))
+		}
+
+		return handleStreamingSpeech(c, apiKey, request, format, from, opts.Output)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(
+		c.Request().Context(),
+		http.MethodPost,
+		"https://api.lmnt.com/v1/ai/speech/bytes",
+		bytes.NewBuffer(payload),
+	)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	// Rewrite the Authorization header
+	req.Header.Set("X-Api-Key", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	if format.Format == "wav" && (responseFormat != "wav" || opts.Output.IsSpecified()) {
+		wav, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, responseFormat, opts.Output)
+	}
+
+	if opts.Output.IsSpecified() && audio.CanStream(responseFormat) {
+		return types.StreamTranscoded(c, res.Body, from, responseFormat, opts.Output)
+	}
+
+	return mo.Ok[any](c.Stream(http.StatusOK, format.ContentType, res.Body))
+}
+
+// readAudio reads the next audio chunk from the streaming WebSocket, io.EOF is returned once the
+// server closed the session after all audio was sent.
+func readAudio(conn *websocket.Conn) ([]byte, error) {
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil, io.EOF
+			}
+
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		if messageType == websocket.BinaryMessage {
+			return message, nil
+		}
+
+		var serverMessage StreamServerMessage
+
+		err = json.Unmarshal(message, &serverMessage)
+		if err != nil {
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+		if serverMessage.Error != "" {
+			return nil, apierrors.NewUpstreamError(http.StatusBadGateway).WithDetail(serverMessage.Error)
+		}
+	}
+}
+
+func handleStreamingSpeech(c echo.Context, apiKey string, request SpeechRequest, format audioFormat, from audio.Spec, output types.AudioOutput) mo.Result[any] {
+	conn, resp, err := websocket.DefaultDialer.DialContext(c.Request().Context(), "wss://api.lmnt.com/v1/ai/speech/stream", nil)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		// Pass upstream error, local error (wss badhandshake) is not helpful
+		return mo.Err[any](apierrors.
+			NewUpstreamError(resp.StatusCode).
+			WithDetail(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+			WithCaller())
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	messages := []any{
+		StreamInitMessage{
+			APIKey:      apiKey,
+			Voice:       request.Voice,
+			Model:       request.Model,
+			Language:    request.Language,
+			Format:      request.Format,
+			SampleRate:  request.SampleRate,
+			Speed:       request.Speed,
+			Temperature: request.Temperature,
+			TopP:        request.TopP,
+		},
+		StreamTextMessage{Text: request.Text},
+		StreamEOFMessage{EOF: true},
+	}
+
+	for _, message := range messages {
+		err = conn.WriteJSON(message)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+	}
+
+	// Wait for the first chunk before committing the response, so that errors can still be
+	// reported with the correct status code.
+	first, err := readAudio(conn)
+	if errors.Is(err, io.EOF) {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail("upstream closed the session without any audio").WithCaller())
+	}
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	reader, writer := io.Pipe()
+	defer func() { _ = reader.Close() }()
+
+	go func() {
+		chunk := first
+
+		for {
+			_, err := writer.Write(chunk)
+			if err != nil {
+				return
+			}
+
+			chunk, err = readAudio(conn)
+			if errors.Is(err, io.EOF) {
+				_ = writer.Close()
+				return
+			}
+			if err != nil {
+				_ = writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	if output.IsSpecified() && audio.CanStream(from.Format) {
+		return types.StreamTranscoded(c, reader, from, from.Format, output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, format.ContentType, reader))
+}
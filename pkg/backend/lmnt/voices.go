@@ -0,0 +1,149 @@
+package lmnt
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+var (
+	// Generate speech | LMNT
+	// https://docs.lmnt.com/api-reference/speech/synthesize-speech-bytes
+	languages = []types.VoiceLanguage{
+		{Code: "en", Title: "English"},
+		{Code: "ar", Title: "Arabic"},
+		{Code: "de", Title: "German"},
+		{Code: "es", Title: "Spanish"},
+		{Code: "fr", Title: "French"},
+		{Code: "hi", Title: "Hindi"},
+		{Code: "id", Title: "Indonesian"},
+		{Code: "it", Title: "Italian"},
+		{Code: "ja", Title: "Japanese"},
+		{Code: "ko", Title: "Korean"},
+		{Code: "nl", Title: "Dutch"},
+		{Code: "pl", Title: "Polish"},
+		{Code: "pt", Title: "Portuguese"},
+		{Code: "ru", Title: "Russian"},
+		{Code: "sv", Title: "Swedish"},
+		{Code: "th", Title: "Thai"},
+		{Code: "tr", Title: "Turkish"},
+		{Code: "uk", Title: "Ukrainian"},
+		{Code: "vi", Title: "Vietnamese"},
+		{Code: "zh", Title: "Chinese"},
+	}
+
+	formats = []types.VoiceFormat{
+		{Name: "MP3", Extension: ".mp3", MimeType: "audio/mpeg", FormatCode: "mp3"},
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", FormatCode: "wav"},
+		{Name: "AAC", Extension: ".aac", MimeType: "audio/aac", FormatCode: "aac"},
+		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", FormatCode: "raw"},
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 8000, FormatCode: "ulaw"}, //nolint:mnd
+		{Name: "WebM", Extension: ".webm", MimeType: "audio/webm", FormatCode: "webm"},
+		// Synthesized as WAV and transcoded.
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", FormatCode: "wav"},
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", FormatCode: "wav"},
+	}
+
+	// Expressive controls that can be set through extra_body.
+	predefinedOptions = map[string]any{
+		"speed":       map[string]any{"min": 0.25, "max": 2, "default": 1},
+		"temperature": map[string]any{"min": 0, "max": 2, "default": 1},
+		"top_p":       map[string]any{"min": 0, "max": 1, "default": 0.8},
+		"stream":      false,
+	}
+)
+
+type Voice struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Owner       string `json:"owner"`
+	State       string `json:"state"`
+	Starred     bool   `json:"starred"`
+	Gender      string `json:"gender"`
+	Description string `json:"description"`
+	PreviewURL  string `json:"preview_url"`
+	Type        string `json:"type"`
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	query := url.Values{}
+	query.Set("owner", lo.CoalesceOrEmpty(options.MustGet().ExtraQuery.Get("owner"), "all"))
+
+	// List voices | LMNT
+	// https://docs.lmnt.com/api-reference/voice/list-voices
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, "https://api.lmnt.com/v1/ai/voice/list?"+query.Encode(), nil)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithError(err).WithCaller())
+	}
+
+	req.Header.Set("X-Api-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[any](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	var response []Voice
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithError(err).WithCaller())
+	}
+
+	voices := make([]types.Voice, 0, len(response))
+
+	for _, voice := range response {
+		voices = append(voices, types.Voice{
+			ID:          voice.ID,
+			Name:        voice.Name,
+			Description: voice.Description,
+			Labels: map[string]any{
+				types.VoiceLabelKeyGender: voice.Gender,
+				types.VoiceLabelKeyType:   voice.Type,
+				"owner":                   voice.Owner,
+			},
+			Tags:              lo.Ternary(voice.Starred, []string{"starred"}, make([]string, 0)),
+			Languages:         languages,
+			Formats:           formats,
+			CompatibleModels:  []string{"blizzard", "aurora"},
+			PredefinedOptions: predefinedOptions,
+			PreviewAudioURL:   voice.PreviewURL,
+		})
+	}
+
+	return mo.Ok[any](types.ListVoicesResponse{
+		Voices: voices,
+	})
+}
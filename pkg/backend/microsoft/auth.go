@@ -0,0 +1,216 @@
+package microsoft
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+type AuthType = string
+
+const (
+	// The Authorization header carries the subscription key of the resource, which is exchanged for an
+	// access token through the issueToken endpoint.
+	AuthTypeSubscriptionKey AuthType = "subscription_key"
+	// The Authorization header carries a Microsoft Entra ID access token with the
+	// https://cognitiveservices.azure.com/.default scope.
+	AuthTypeEntraID AuthType = "entra_id"
+)
+
+const (
+	// Access tokens issued by the issueToken endpoint are valid for 10 minutes, refresh them a while
+	// before they actually expire.
+	tokenLifetime     = 10 * time.Minute
+	tokenRefreshAhead = time.Minute
+)
+
+// authOptions describes how requests to the resource should be authenticated.
+//
+// Microsoft Entra ID tokens are accepted either with a custom domain, or with a regional endpoint
+// together with the resource ID of the Speech resource.
+//
+// Microsoft Entra authentication with the Speech SDK - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/how-to-configure-azure-ad-auth
+type authOptions struct {
+	Region       string
+	AuthType     AuthType
+	CustomDomain string
+	ResourceID   string
+}
+
+func newAuthOptions(get func(key string) string) mo.Result[authOptions] {
+	options := authOptions{
+		Region:       lo.CoalesceOrEmpty(get("region"), defaultRegion),
+		AuthType:     lo.CoalesceOrEmpty(get("auth_type"), AuthTypeSubscriptionKey),
+		CustomDomain: get("custom_domain"),
+		ResourceID:   get("resource_id"),
+	}
+
+	switch options.AuthType {
+	case AuthTypeSubscriptionKey:
+	case AuthTypeEntraID:
+		if options.CustomDomain == "" && options.ResourceID == "" {
+			return mo.Err[authOptions](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("either custom_domain or resource_id is required when auth_type is entra_id").
+				WithSourceParameter("auth_type"))
+		}
+	default:
+		return mo.Err[authOptions](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported auth_type %s, supported values are %s and %s", options.AuthType, AuthTypeSubscriptionKey, AuthTypeEntraID).
+			WithSourceParameter("auth_type"))
+	}
+
+	return mo.Ok(options)
+}
+
+// ttsBaseURL returns the base URL of the text to speech endpoints, custom domains serve them under /tts.
+func (o authOptions) ttsBaseURL() string {
+	if o.CustomDomain != "" {
+		return "https://" + o.CustomDomain + ".cognitiveservices.azure.com/tts"
+	}
+
+	return "https://" + o.Region + ".tts.speech.microsoft.com"
+}
+
+// Authentication - Text to speech API reference (REST) - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-text-to-speech#authentication
+func (o authOptions) issueTokenURL() string {
+	if o.CustomDomain != "" {
+		return "https://" + o.CustomDomain + ".cognitiveservices.azure.com/sts/v1.0/issueToken"
+	}
+
+	return "https://" + o.Region + ".api.cognitive.microsoft.com/sts/v1.0/issueToken"
+}
+
+type cachedToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+var (
+	tokensMutex sync.Mutex
+	tokens      = make(map[string]cachedToken)
+)
+
+// tokenCacheKey hashes the subscription key, so that it isn't kept around in the token cache.
+func tokenCacheKey(tokenURL string, subscriptionKey string) string {
+	hash := sha256.Sum256([]byte(subscriptionKey))
+
+	return tokenURL + ":" + hex.EncodeToString(hash[:])
+}
+
+// cachedAccessToken returns the token cached for the subscription key unless it's about to expire.
+func cachedAccessToken(cacheKey string) mo.Option[string] {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	token, ok := tokens[cacheKey]
+	if !ok || !time.Now().Add(tokenRefreshAhead).Before(token.expiresAt) {
+		return mo.None[string]()
+	}
+
+	return mo.Some(token.accessToken)
+}
+
+// storeAccessToken caches a token exchanged successfully, expired tokens of other subscription keys are
+// dropped along the way, so that keys no longer used don't stay around.
+func storeAccessToken(cacheKey string, token cachedToken) {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	for key, cached := range tokens {
+		if time.Now().After(cached.expiresAt) {
+			delete(tokens, key)
+		}
+	}
+
+	tokens[cacheKey] = token
+}
+
+// accessToken returns a cached access token for the subscription key, exchanging a new one through the
+// issueToken endpoint when absent or about to expire.
+func accessToken(ctx context.Context, options authOptions, subscriptionKey string) (string, error) {
+	cacheKey := tokenCacheKey(options.issueTokenURL(), subscriptionKey)
+
+	if token, ok := cachedAccessToken(cacheKey).Get(); ok {
+		return token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, options.issueTokenURL(), nil)
+	if err != nil {
+		return "", apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller()
+	}
+
+	req.Header.Set("Ocp-Apim-Subscription-Key", subscriptionKey)
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller()
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		return "", handleResponseError(res).Error()
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+	}
+
+	token := strings.TrimSpace(string(body))
+
+	storeAccessToken(cacheKey, cachedToken{
+		accessToken: token,
+		expiresAt:   time.Now().Add(tokenLifetime),
+	})
+
+	return token, nil
+}
+
+// credentialFrom takes the subscription key or the Entra ID token from the Authorization header.
+func credentialFrom(c echo.Context) string {
+	return strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+}
+
+// authorize sets the Authorization header of the upstream request according to the auth options.
+func authorize(ctx context.Context, req *http.Request, options authOptions, credential string) error {
+	if credential == "" {
+		return apierrors.
+			NewErrUnauthorized().
+			WithDetail("Authorization header is required").
+			WithSourceHeader("Authorization")
+	}
+
+	switch options.AuthType {
+	case AuthTypeEntraID:
+		// Regional endpoints need to know which resource the token is meant for.
+		if options.CustomDomain == "" {
+			req.Header.Set("Authorization", "Bearer aad#"+options.ResourceID+"#"+credential)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+credential)
+		}
+	default:
+		token, err := accessToken(ctx, options, credential)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package microsoft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedAccessToken(t *testing.T) {
+	tokenURL := authOptions{Region: "eastus"}.issueTokenURL()
+	keyA := tokenCacheKey(tokenURL, "key-a")
+	keyB := tokenCacheKey(tokenURL, "key-b")
+
+	assert.NotContains(t, keyA, "key-a")
+	assert.NotEqual(t, keyA, keyB)
+	assert.NotEqual(t, keyA, tokenCacheKey(authOptions{Region: "westus"}.issueTokenURL(), "key-a"))
+
+	assert.True(t, cachedAccessToken(keyA).IsAbsent())
+
+	storeAccessToken(keyA, cachedToken{accessToken: "token-a", expiresAt: time.Now().Add(tokenLifetime)})
+	assert.Equal(t, "token-a", cachedAccessToken(keyA).OrEmpty())
+
+	storeAccessToken(keyA, cachedToken{accessToken: "token-a", expiresAt: time.Now().Add(tokenRefreshAhead / 2)})
+	assert.True(t, cachedAccessToken(keyA).IsAbsent())
+
+	storeAccessToken(keyA, cachedToken{accessToken: "token-a", expiresAt: time.Now().Add(-time.Second)})
+	storeAccessToken(keyB, cachedToken{accessToken: "token-b", expiresAt: time.Now().Add(tokenLifetime)})
+
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+
+	assert.NotContains(t, tokens, keyA)
+	assert.Contains(t, tokens, keyB)
+}
@@ -0,0 +1,285 @@
+package microsoft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// Query parameters passed through to the service, e.g. for tweaking the segmentation.
+//
+// Speech to text REST API for short audio - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-speech-to-text-short#query-parameters
+var realtimePassthroughQuery = []string{"profanity", "cid", "initialSilenceTimeoutMs", "endSilenceTimeoutMs", "segmentationSilenceTimeoutMs"}
+
+// Messages of the WebSocket protocol used by the Speech SDK, which are HTTP-like headers followed by the body.
+//
+// Text messages: headers and body separated by an empty line.
+// Binary messages: 2 bytes big-endian length of headers, headers, then the audio.
+type realtimeMessage struct {
+	Path string
+	Body []byte
+}
+
+type realtimeHypothesis struct {
+	Text     string `json:"Text"`
+	Offset   int64  `json:"Offset"`
+	Duration int64  `json:"Duration"`
+}
+
+type realtimePhrase struct {
+	RecognitionStatus string            `json:"RecognitionStatus"`
+	Offset            int64             `json:"Offset"`
+	Duration          int64             `json:"Duration"`
+	DisplayText       string            `json:"DisplayText"`
+	NBest             []shortAudioNBest `json:"NBest"`
+}
+
+type realtimeUpstream struct {
+	conn        *websocket.Conn
+	requestID   string
+	sampleRate  int
+	channels    int
+	headersSent bool
+	mutex       sync.Mutex
+}
+
+func formatRealtimeHeaders(path string, requestID string, contentType string) string {
+	headers := fmt.Sprintf(
+		"Path: %s\r\nX-RequestId: %s\r\nX-Timestamp: %s\r\n",
+		path,
+		requestID,
+		time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+	)
+	if contentType != "" {
+		headers += "Content-Type: " + contentType + "\r\n"
+	}
+
+	return headers
+}
+
+func parseRealtimeMessage(message []byte) realtimeMessage {
+	headers, body, _ := bytes.Cut(message, []byte("\r\n\r\n"))
+
+	var parsed realtimeMessage
+
+	for line := range strings.SplitSeq(string(headers), "\r\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "Path") {
+			parsed.Path = strings.TrimSpace(value)
+		}
+	}
+
+	parsed.Body = body
+
+	return parsed
+}
+
+func (u *realtimeUpstream) writeText(path string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return u.conn.WriteMessage(
+		websocket.TextMessage,
+		append([]byte(formatRealtimeHeaders(path, u.requestID, "application/json")+"\r\n"), payload...),
+	)
+}
+
+func (u *realtimeUpstream) writeAudio(data []byte) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var headers string
+
+	// The first audio message describes the format with a WAV header, the service doesn't care
+	// about the data size therefore it's left as 0.
+	if !u.headersSent {
+		headers = formatRealtimeHeaders("audio", u.requestID, "audio/x-wav")
+		data = append(audio.WAVHeader(0, u.sampleRate, u.channels, 16), data...) //nolint:mnd
+		u.headersSent = true
+	} else {
+		headers = formatRealtimeHeaders("audio", u.requestID, "")
+	}
+
+	message := new(bytes.Buffer)
+	_ = binary.Write(message, binary.BigEndian, uint16(len(headers))) //nolint:gosec
+	message.WriteString(headers)
+	message.Write(data)
+
+	return u.conn.WriteMessage(websocket.BinaryMessage, message.Bytes())
+}
+
+func (u *realtimeUpstream) SendAudio(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return u.writeAudio(data)
+}
+
+// Finish sends an audio message without body which marks the end of the audio stream.
+func (u *realtimeUpstream) Finish() error {
+	return u.writeAudio(make([]byte, 0))
+}
+
+func (u *realtimeUpstream) Receive() ([]types.RealtimeTranscriptionEvent, error) {
+	for {
+		_, message, err := u.conn.ReadMessage()
+		if err != nil {
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		parsed := parseRealtimeMessage(message)
+
+		switch parsed.Path {
+		case "speech.startDetected":
+			return []types.RealtimeTranscriptionEvent{{Type: types.RealtimeTranscriptionEventTypeSpeechStarted}}, nil
+		case "speech.endDetected":
+			return []types.RealtimeTranscriptionEvent{{Type: types.RealtimeTranscriptionEventTypeSpeechEnded}}, nil
+		case "speech.hypothesis":
+			var hypothesis realtimeHypothesis
+
+			err = json.Unmarshal(parsed.Body, &hypothesis)
+			if err != nil {
+				return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+			}
+
+			return []types.RealtimeTranscriptionEvent{
+				{
+					Type: types.RealtimeTranscriptionEventTypeTranscript,
+					Transcript: &types.RealtimeTranscript{
+						Text:  hypothesis.Text,
+						Start: ticksToSeconds(hypothesis.Offset),
+						End:   ticksToSeconds(hypothesis.Offset + hypothesis.Duration),
+					},
+				},
+			}, nil
+		case "speech.phrase":
+			var phrase realtimePhrase
+
+			err = json.Unmarshal(parsed.Body, &phrase)
+			if err != nil {
+				return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+			}
+
+			text := phrase.DisplayText
+			if text == "" && len(phrase.NBest) > 0 {
+				text = phrase.NBest[0].Display
+			}
+
+			if phrase.RecognitionStatus != "Success" || text == "" {
+				continue
+			}
+
+			return []types.RealtimeTranscriptionEvent{
+				{
+					Type: types.RealtimeTranscriptionEventTypeTranscript,
+					Transcript: &types.RealtimeTranscript{
+						Text:    text,
+						IsFinal: true,
+						Start:   ticksToSeconds(phrase.Offset),
+						End:     ticksToSeconds(phrase.Offset + phrase.Duration),
+					},
+				},
+			}, nil
+		case "turn.end":
+			return nil, io.EOF
+		default:
+			// turn.start, etc.
+			continue
+		}
+	}
+}
+
+func (u *realtimeUpstream) Close() error {
+	return u.conn.Close()
+}
+
+func HandleRealtimeTranscription(c echo.Context, options mo.Option[types.RealtimeTranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	// Compressed audio is decoded by the Speech SDK on the client side, the service accepts PCM only.
+	if opts.Encoding != types.RealtimeAudioEncodingPCM {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("only pcm encoding is supported by microsoft").
+			WithSourceParameter("encoding"))
+	}
+
+	query := url.Values{}
+	query.Set("language", lo.CoalesceOrEmpty(opts.Language, defaultLocale))
+	query.Set("format", "detailed")
+
+	for _, key := range realtimePassthroughQuery {
+		if value := opts.ExtraQuery.Get(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	connectionID := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", ""))
+
+	headers := http.Header{}
+	headers.Set("Ocp-Apim-Subscription-Key", opts.Authorization(c))
+	headers.Set("X-ConnectionId", connectionID) //nolint:canonicalheader
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(
+		c.Request().Context(),
+		fmt.Sprintf(
+			"wss://%s.stt.speech.microsoft.com/speech/recognition/conversation/cognitiveservices/v1?%s",
+			lo.CoalesceOrEmpty(opts.ExtraQuery.Get("region"), defaultRegion),
+			query.Encode(),
+		),
+		headers,
+	)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		return mo.Err[any](apierrors.
+			NewUpstreamError(resp.StatusCode).
+			WithDetail(lo.CoalesceOrEmpty(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error(), resp.Status)).
+			WithCaller())
+	}
+
+	upstream := &realtimeUpstream{
+		conn:       conn,
+		requestID:  strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")),
+		sampleRate: opts.SampleRate,
+		channels:   opts.Channels,
+	}
+
+	err = upstream.writeText("speech.config", map[string]any{
+		"context": map[string]any{
+			"system": map[string]any{"version": "1.0.0"},
+			"os":     map[string]any{"platform": "unSpeech", "name": "unSpeech", "version": "0.0.0"},
+		},
+	})
+	if err != nil {
+		_ = conn.Close()
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	return types.ServeRealtimeTranscription(c, upstream)
+}
@@ -3,36 +3,24 @@ package microsoft
 import (
 	"bytes"
 	"encoding/json"
-	"encoding/xml"
-	"fmt"
-	"log/slog"
+	"io"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
-type voice struct {
-	XMLName xml.Name `xml:"voice"`
-	Lang    string   `xml:"lang,attr"`
-	Gender  string   `xml:"gender,attr"`
-	Name    string   `xml:"name,attr"`
-	Text    string   `xml:",chardata"`
-}
-
-// SSML structures for parsing
-type ssml struct {
-	XMLName  xml.Name `xml:"speak"`
-	Version  string   `xml:"version,attr"`
-	Lang     string   `xml:"lang,attr"`
-	Voice    voice    `xml:"voice"`
-	TextOnly string   `xml:",chardata"`
-}
+// MaxInputLength keeps a request well within the 10 minutes of audio a request can produce.
+//
+// Speech service quotas and limits - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-services-quotas-and-limits#text-to-speech-quotas-and-limits-per-resource
+const MaxInputLength = 5000
 
 type extraBody struct {
 	DisableSSML  mo.Option[bool]   `json:"disable_ssml,omitempty"`
@@ -41,6 +29,12 @@ type extraBody struct {
 	Lang         mo.Option[string] `json:"lang,omitempty"`
 	Gender       mo.Option[string] `json:"gender,omitempty"`
 	SampleRate   mo.Option[uint]   `json:"sample_rate,omitempty"`
+
+	// Speaking style, style degree and role play of mstts:express-as, and prosody, only applied to plain text.
+	Style       mo.Option[string]      `json:"style,omitempty"`
+	StyleDegree mo.Option[float64]     `json:"styledegree,omitempty"`
+	Role        mo.Option[string]      `json:"role,omitempty"`
+	Prosody     mo.Option[ssmlProsody] `json:"prosody,omitempty"`
 }
 
 var (
@@ -81,28 +75,26 @@ var (
 			},
 		},
 		"wav": {
-			8000: {
-				"raw-8khz-16bit-mono-pcm",
-				"raw-8khz-8bit-mono-alaw",
-				"raw-8khz-8bit-mono-mulaw",
-			},
-			16000: {
-				"raw-16khz-16bit-mono-pcm",
-				"raw-16khz-16bit-mono-truesilk",
-			},
-			22050: {
-				"raw-22050hz-16bit-mono-pcm",
-			},
-			24000: {
-				"raw-24khz-16bit-mono-pcm",
-				"raw-24khz-16bit-mono-truesilk",
-			},
-			44100: {
-				"raw-44100hz-16bit-mono-pcm",
-			},
-			48000: {
-				"raw-48khz-16bit-mono-pcm",
-			},
+			8000:  {"riff-8khz-16bit-mono-pcm"},
+			16000: {"riff-16khz-16bit-mono-pcm"},
+			22050: {"riff-22050hz-16bit-mono-pcm"},
+			24000: {"riff-24khz-16bit-mono-pcm"},
+			44100: {"riff-44100hz-16bit-mono-pcm"},
+			48000: {"riff-48khz-16bit-mono-pcm"},
+		},
+		"pcm": {
+			8000:  {"raw-8khz-16bit-mono-pcm"},
+			16000: {"raw-16khz-16bit-mono-pcm"},
+			22050: {"raw-22050hz-16bit-mono-pcm"},
+			24000: {"raw-24khz-16bit-mono-pcm"},
+			44100: {"raw-44100hz-16bit-mono-pcm"},
+			48000: {"raw-48khz-16bit-mono-pcm"},
+		},
+		"ulaw": {
+			8000: {"raw-8khz-8bit-mono-mulaw"},
+		},
+		"alaw": {
+			8000: {"raw-8khz-8bit-mono-alaw"},
 		},
 	}
 )
@@ -121,95 +113,28 @@ func getOutputFormat(format string, sampleRate uint) mo.Option[string] {
 	return mo.Some(formatFull[0])
 }
 
-// Helper function to process input text and SSML
-func processSSML(input string, option types.SpeechRequestOptions, extraBody mo.Option[extraBody]) string {
-	// Check if SSML formatting is disabled
-	if extraBody.OrEmpty().DisableSSML.OrEmpty() {
-		return input
-	}
-
-	// Default SSML values
-	defaultLang := "en-US"
-	defaultGender := "Male"
-	defaultVoiceName := lo.CoalesceOrEmpty(option.Voice, "en-US-ChristopherNeural")
-
-	// Override defaults with values from ExtraBody if provided
-	if extraBody.OrEmpty().Lang.IsPresent() {
-		defaultLang = extraBody.MustGet().Lang.MustGet()
-	}
-	if extraBody.OrEmpty().Gender.IsPresent() {
-		defaultGender = extraBody.MustGet().Gender.MustGet()
-	}
-	if !strings.Contains(input, "<speak") {
-		// For plain text, format with defaults
-		return formatAsSSML(input, defaultLang, defaultGender, defaultVoiceName)
-	}
-
-	// Try to parse the existing SSML
-	var s ssml
-
-	err := xml.Unmarshal([]byte(input), &s)
-	if err != nil {
-		// If parsing fails, treat as plain text and format with defaults
-		slog.Warn("failed to parse SSML, using as plain text", slog.String("error", err.Error()))
-		return formatAsSSML(input, defaultLang, defaultGender, defaultVoiceName)
-	}
-
-	// Extract values from parsed SSML, using defaults for missing values
-	lang := defaultLang
-	if s.Lang != "" {
-		lang = s.Lang
-	}
-
-	gender := defaultGender
-	if s.Voice.Gender != "" {
-		gender = s.Voice.Gender
-	}
-
-	voiceName := defaultVoiceName
-	if s.Voice.Name != "" {
-		voiceName = s.Voice.Name
-	}
-
-	// If there's no voice tag or it's empty, use the text from the speak tag
-	text := s.Voice.Text
-	if strings.TrimSpace(text) == "" {
-		text = strings.TrimSpace(s.TextOnly)
-	}
+// nativeSampleRate picks the sample rate of the format closest to the one asked for, 48kHz by default.
+func nativeSampleRate(format string, output types.AudioOutput) uint {
+	sampleRates := lo.Map(lo.Keys(supportedOutputFormats[format]), func(item uint, _ int) int { return int(item) }) //nolint:gosec
 
-	// If we have valid text, format it with the extracted/default values
-	if strings.TrimSpace(text) != "" {
-		return formatAsSSML(text, lang, gender, voiceName)
-	}
-
-	// If we couldn't extract text, return the original input
-	return input
-}
-
-// Helper to format text as SSML
-//
-// See also: Speech Synthesis Markup Language (SSML) overview - Speech service - Azure AI services | Microsoft Learn
-// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup
-func formatAsSSML(text string, lang string, gender string, voiceName string) string {
-	return fmt.Sprintf(`<speak version='1.0' xml:lang='%s'>
-  <voice xml:lang='%s' xml:gender='%s' name='%s'>
-    %s
-  </voice>
-</speak>`, lang, lang, gender, voiceName, text)
+	return uint(output.NativeSampleRate(sampleRates, 48000)) //nolint:gosec,mnd
 }
 
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
 	opts := options.MustGet()
 
 	// Text to speech API reference (REST) - Speech service - Azure AI services | Microsoft Learn
-	reqURL := lo.Must(url.Parse(fmt.Sprintf(
-		"https://%s.tts.speech.microsoft.com/cognitiveservices/v1",
-		// Text to speech API reference (REST) - Speech service - Azure AI services | Microsoft Learn
-		// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-text-to-speech?tabs=streaming#prebuilt-neural-voices
-		//
-		// NOTICE: Voices in preview are available in only these three regions: East US, West Europe, and Southeast Asia.
-		lo.Must(lo.Coalesce(opts.ExtraBody["region"], "eastasia")))),
-	)
+	// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-text-to-speech?tabs=streaming#prebuilt-neural-voices
+	//
+	// NOTICE: Voices in preview are available in only these three regions: East US, West Europe, and Southeast Asia.
+	auth := newAuthOptions(func(key string) string {
+		return utils.GetByJSONPath[string](opts.ExtraBody, "{ ."+key+" }")
+	})
+	if auth.IsError() {
+		return mo.Err[any](auth.Error())
+	}
+
+	reqURL := lo.Must(url.Parse(auth.MustGet().ttsBaseURL() + "/cognitiveservices/v1"))
 
 	var extra mo.Option[extraBody]
 
@@ -241,27 +166,50 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		reqSearchParams.Add("deploymentId", extra.MustGet().DeploymentID.MustGet())
 	}
 
-	// Process the input text with SSML handling
-	inputText := opts.Input
-	formattedText := processSSML(inputText, opts, extra)
+	// Process the input text with SSML handling, prosody only applies to plain text
+	formattedText := processSSML(opts.Input, opts, extra)
+	if formattedText.IsError() {
+		return mo.Err[any](formattedText.Error())
+	}
 
 	// Create request with the SSML content directly
-	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, reqURL.String()+lo.Ternary(len(reqSearchParams) > 0, "?"+reqSearchParams.Encode(), ""), bytes.NewBufferString(formattedText))
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, reqURL.String()+lo.Ternary(len(reqSearchParams) > 0, "?"+reqSearchParams.Encode(), ""), bytes.NewBufferString(formattedText.MustGet()))
 	if err != nil {
 		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
 	}
 
 	var format string
-	if opts.ResponseFormat == "" {
+
+	// Formats Microsoft doesn't synthesize at the sample rate, e.g. flac, and stereo are transcoded from wav.
+	transcoded := false
+
+	if opts.ResponseFormat == "" && !opts.Output.IsSpecified() {
 		format = "audio-48khz-192kbitrate-mono-mp3"
 	} else {
-		format = getOutputFormat(opts.ResponseFormat, extra.MustGet().SampleRate.OrElse(48000)).OrEmpty() //nolint:mnd
+		responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, "mp3")
+		sampleRate := extra.OrEmpty().SampleRate.OrElse(nativeSampleRate(responseFormat, opts.Output))
+		native := audio.Spec{Format: responseFormat, SampleRate: int(sampleRate), Channels: 1} //nolint:gosec
+
+		format = getOutputFormat(responseFormat, sampleRate).OrEmpty()
+		if (format == "" || !native.Matches(opts.Output.Spec(responseFormat))) && audio.CanEncode(responseFormat) {
+			format = getOutputFormat(audio.FormatWAV, extra.OrEmpty().SampleRate.OrElse(nativeSampleRate(audio.FormatWAV, opts.Output))).OrEmpty()
+			transcoded = true
+		}
 		if format == "" {
 			return mo.Err[any](apierrors.NewErrBadRequest().WithDetail("unsupported output format, check https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-text-to-speech?tabs=streaming#audio-outputs for full list of supported formats"))
 		}
+
+		err = types.CheckOutput(native, responseFormat, opts.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+	}
+
+	err = authorize(c.Request().Context(), req, auth.MustGet(), credentialFrom(c))
+	if err != nil {
+		return mo.Err[any](err)
 	}
 
-	req.Header.Set("Ocp-Apim-Subscription-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
 	req.Header.Set("Content-Type", "application/ssml+xml")
 	req.Header.Set("X-Microsoft-OutputFormat", format) //nolint:canonicalheader
 
@@ -301,5 +249,14 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		}
 	}
 
+	if transcoded {
+		wav, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, opts.ResponseFormat, opts.Output)
+	}
+
 	return mo.Ok[any](c.Stream(http.StatusOK, res.Header.Get("Content-Type"), res.Body))
 }
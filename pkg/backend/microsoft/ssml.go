@@ -0,0 +1,316 @@
+package microsoft
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/markup"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// Speech Synthesis Markup Language (SSML) document structure and events - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup-structure
+const (
+	ssmlNamespace      = "http://www.w3.org/2001/10/synthesis"
+	ssmlMSTTSNamespace = "https://www.w3.org/2001/mstts"
+)
+
+const (
+	defaultSSMLLang   = "en-US"
+	defaultSSMLGender = "Male"
+	defaultSSMLVoice  = "en-US-ChristopherNeural"
+)
+
+// Customize voice and sound with SSML - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup-voice#adjust-prosody
+type ssmlProsody struct {
+	Rate    string `json:"rate,omitempty"`
+	Pitch   string `json:"pitch,omitempty"`
+	Volume  string `json:"volume,omitempty"`
+	Contour string `json:"contour,omitempty"`
+	Range   string `json:"range,omitempty"`
+}
+
+// ProsodyRanges is the prosody Azure Speech supports, rate is a multiplier of the default rate, pitch and
+// volume are relative changes in percent.
+var ProsodyRanges = types.ProsodyRanges{
+	Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+	Pitch:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 1.5}), //nolint:mnd
+	Volume: mo.Some(types.ProsodyRange{Min: 0, Max: 2.0}),   //nolint:mnd
+}
+
+func relativePercent(multiplier float64) string {
+	return fmt.Sprintf("%+.0f%%", (multiplier-1)*100) //nolint:mnd
+}
+
+// withDefaults fills the attributes not given explicitly from the prosody of the request.
+func (p ssmlProsody) withDefaults(prosody types.Prosody) ssmlProsody {
+	if speed, ok := prosody.Speed.Get(); ok && p.Rate == "" {
+		p.Rate = strconv.FormatFloat(speed, 'f', -1, 64)
+	}
+	if pitch, ok := prosody.Pitch.Get(); ok && p.Pitch == "" {
+		p.Pitch = relativePercent(pitch)
+	}
+	if volume, ok := prosody.Volume.Get(); ok && p.Volume == "" {
+		p.Volume = relativePercent(volume)
+	}
+
+	return p
+}
+
+func (p ssmlProsody) attributes() [][2]string {
+	return lo.Filter([][2]string{
+		{"rate", p.Rate},
+		{"pitch", p.Pitch},
+		{"volume", p.Volume},
+		{"contour", p.Contour},
+		{"range", p.Range},
+	}, func(item [2]string, _ int) bool { return item[1] != "" })
+}
+
+// ssmlRun is a run of content right within <speak> that isn't in any <voice>, offsets are in bytes.
+type ssmlRun struct {
+	start int64
+	end   int64
+}
+
+// ssmlDocument describes the root element of an SSML document.
+type ssmlDocument struct {
+	// Runs of text and elements right within <speak> to be wrapped in <voice>, i.e. other than <voice>
+	// itself and the elements only allowed there, such as <mstts:backgroundaudio>.
+	runs []ssmlRun
+}
+
+// isSpeakLevelElement tells whether the element belongs right within <speak> instead of <voice>.
+//
+// Speech Synthesis Markup Language (SSML) document structure and events - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup-structure#speak-root-element
+func isSpeakLevelElement(name xml.Name) bool {
+	switch name.Space {
+	case "", ssmlNamespace:
+		return name.Local == "voice"
+	case "mstts", ssmlMSTTSNamespace:
+		return name.Local == "backgroundaudio" || name.Local == "voiceconversion"
+	default:
+		return false
+	}
+}
+
+// parseSSML checks whether the input is a well-formed SSML document with <speak> as root element, and
+// finds the runs of content not in any <voice>.
+func parseSSML(input string) (ssmlDocument, error) {
+	var document ssmlDocument
+
+	decoder := xml.NewDecoder(strings.NewReader(input))
+	depth := 0
+	hasRoot := false
+
+	// Start of the current run, -1 when there is none, and whether it has anything to read.
+	runStart := int64(-1)
+	runHasContent := false
+
+	endRun := func(end int64) {
+		if runStart >= 0 && runHasContent {
+			document.runs = append(document.runs, ssmlRun{start: runStart, end: end})
+		}
+
+		runStart = -1
+		runHasContent = false
+	}
+
+	for {
+		offset := decoder.InputOffset()
+
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return document, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 0:
+				if hasRoot {
+					return document, errors.New("multiple root elements")
+				}
+				if element.Name.Local != "speak" {
+					return document, fmt.Errorf("root element must be <speak>, got <%s>", element.Name.Local)
+				}
+
+				hasRoot = true
+
+				// Self-closing <speak/> is reported with an end element right after, without any input read.
+				if strings.HasSuffix(input[:decoder.InputOffset()], "/>") {
+					return document, errors.New("<speak> has no content")
+				}
+			case depth == 1 && isSpeakLevelElement(element.Name):
+				endRun(offset)
+			case depth == 1:
+				if runStart < 0 {
+					runStart = offset
+				}
+
+				runHasContent = true
+			}
+
+			depth++
+		case xml.EndElement:
+			depth--
+
+			if depth == 0 {
+				endRun(offset)
+			}
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(element)) > 0 {
+				return document, errors.New("text outside of <speak>")
+			}
+
+			if depth == 1 {
+				if runStart < 0 {
+					runStart = offset
+				}
+
+				runHasContent = runHasContent || len(bytes.TrimSpace(element)) > 0
+			}
+		}
+	}
+
+	if !hasRoot {
+		return document, errors.New("missing <speak> element")
+	}
+
+	return document, nil
+}
+
+func escapeSSML(text string) string {
+	buffer := new(bytes.Buffer)
+	_ = xml.EscapeText(buffer, []byte(text))
+
+	return buffer.String()
+}
+
+func writeSSMLStartElement(buffer *strings.Builder, name string, attributes [][2]string) {
+	buffer.WriteString("<" + name)
+
+	for _, attribute := range attributes {
+		buffer.WriteString(" " + attribute[0] + "='" + escapeSSML(attribute[1]) + "'")
+	}
+
+	buffer.WriteString(">")
+}
+
+// processSSML turns the input into the SSML document sent to the service.
+//
+// SSML input is forwarded as it is once validated, the requested voice is only injected around the content
+// right within <speak> that isn't in any <voice>. Plain text is escaped and rendered with voice, style and prosody from extra_body,
+// along with <break>, <emphasis>, <say-as> and <sub> in it.
+func processSSML(input string, option types.SpeechRequestOptions, extraBody mo.Option[extraBody]) mo.Result[string] {
+	extra := extraBody.OrEmpty()
+
+	if extra.DisableSSML.OrEmpty() {
+		return mo.Ok(input)
+	}
+
+	voiceName := lo.CoalesceOrEmpty(option.Voice, defaultSSMLVoice)
+
+	if strings.HasPrefix(strings.TrimSpace(input), "<") && strings.Contains(input, "<speak") {
+		document, err := parseSSML(input)
+		if err != nil {
+			return mo.Err[string](apierrors.
+				NewErrInvalidArgument().
+				WithDetailf("input is not a well-formed SSML document: %s", err.Error()).
+				WithSourcePointer("/input"))
+		}
+
+		buffer := new(strings.Builder)
+		offset := int64(0)
+
+		for _, run := range document.runs {
+			buffer.WriteString(input[offset:run.start])
+			buffer.WriteString("<voice name='" + escapeSSML(voiceName) + "'>")
+			buffer.WriteString(input[run.start:run.end])
+			buffer.WriteString("</voice>")
+
+			offset = run.end
+		}
+
+		buffer.WriteString(input[offset:])
+
+		return mo.Ok(buffer.String())
+	}
+
+	content := escapeSSML(input)
+
+	if markup.Contains(input) {
+		nodes := types.ParseMarkup(input)
+		if nodes.IsError() {
+			return mo.Err[string](nodes.Error())
+		}
+
+		content = markup.SSML(nodes.MustGet())
+	}
+
+	return mo.Ok(formatAsSSML(
+		content,
+		extra.Lang.OrElse(defaultSSMLLang),
+		extra.Gender.OrElse(defaultSSMLGender),
+		voiceName,
+		extra,
+		option.Prosody,
+	))
+}
+
+// Helper to format escaped text as SSML
+//
+// See also: Speech Synthesis Markup Language (SSML) overview - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup
+func formatAsSSML(content string, lang string, gender string, voiceName string, extra extraBody, prosody types.Prosody) string {
+	buffer := new(strings.Builder)
+
+	writeSSMLStartElement(buffer, "speak", [][2]string{
+		{"version", "1.0"},
+		{"xmlns", ssmlNamespace},
+		{"xmlns:mstts", ssmlMSTTSNamespace},
+		{"xml:lang", lang},
+	})
+	writeSSMLStartElement(buffer, "voice", [][2]string{
+		{"xml:lang", lang},
+		{"xml:gender", gender},
+		{"name", voiceName},
+	})
+
+	closing := []string{"</voice>", "</speak>"}
+
+	// Customize voice and sound with SSML - Speech service - Azure AI services | Microsoft Learn
+	// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup-voice#use-speaking-styles-and-roles
+	expressAs := lo.Filter([][2]string{
+		{"style", extra.Style.OrEmpty()},
+		{"styledegree", lo.Ternary(extra.StyleDegree.IsPresent(), strconv.FormatFloat(extra.StyleDegree.OrEmpty(), 'f', -1, 64), "")},
+		{"role", extra.Role.OrEmpty()},
+	}, func(item [2]string, _ int) bool { return item[1] != "" })
+	if len(expressAs) > 0 {
+		writeSSMLStartElement(buffer, "mstts:express-as", expressAs)
+		closing = append([]string{"</mstts:express-as>"}, closing...)
+	}
+
+	if attributes := extra.Prosody.OrEmpty().withDefaults(prosody).attributes(); len(attributes) > 0 {
+		writeSSMLStartElement(buffer, "prosody", attributes)
+		closing = append([]string{"</prosody>"}, closing...)
+	}
+
+	buffer.WriteString(content)
+	buffer.WriteString(strings.Join(closing, ""))
+
+	return buffer.String()
+}
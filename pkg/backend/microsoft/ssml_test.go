@@ -0,0 +1,127 @@
+package microsoft
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+)
+
+func TestParseSSML(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input string
+		runs  []string
+	}{
+		"WithoutVoice": {
+			input: `<speak version="1.0">Hello</speak>`,
+			runs:  []string{"Hello"},
+		},
+		"WithVoice": {
+			input: `<speak version="1.0"><voice name="en-US-AvaNeural">Hello</voice></speak>`,
+		},
+		"WhitespaceAroundVoice": {
+			input: "<speak>\n  <voice name=\"a\">Hello</voice>\n</speak>",
+		},
+		"TextAfterVoice": {
+			input: `<speak><voice name="a">Hello</voice> world</speak>`,
+			runs:  []string{" world"},
+		},
+		"BackgroundAudio": {
+			input: `<speak xmlns:mstts="https://www.w3.org/2001/mstts"><mstts:backgroundaudio src="a.wav"/>Hello <break/></speak>`,
+			runs:  []string{"Hello <break/>"},
+		},
+		"XMLDeclaration": {
+			input: `<?xml version="1.0"?><speak>Hello</speak>`,
+			runs:  []string{"Hello"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			document, err := parseSSML(tc.input)
+			require.NoError(t, err)
+
+			runs := lo.Map(document.runs, func(item ssmlRun, _ int) string { return tc.input[item.start:item.end] })
+			assert.ElementsMatch(t, tc.runs, runs)
+		})
+	}
+
+	for name, input := range map[string]string{
+		"SelfClosingSpeak":  `<speak version="1.0"/>`,
+		"InvalidRoot":       `<voice name="a">Hello</voice>`,
+		"MultipleRoots":     `<speak>Hello</speak><speak>World</speak>`,
+		"TextOutside":       `<speak>Hello</speak> world`,
+		"MalformedDocument": `<speak>Hello`,
+		"MissingRoot":       `<?xml version="1.0"?>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseSSML(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProcessSSML(t *testing.T) {
+	t.Parallel()
+
+	options := types.SpeechRequestOptions{}
+	options.Voice = "en-US-AvaNeural"
+
+	for name, tc := range map[string]struct {
+		input    string
+		expected string
+	}{
+		"PlainText": {
+			input:    "Tom & Jerry",
+			expected: `<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xmlns:mstts='https://www.w3.org/2001/mstts' xml:lang='en-US'><voice xml:lang='en-US' xml:gender='Male' name='en-US-AvaNeural'>Tom &amp; Jerry</voice></speak>`,
+		},
+		"Markup": {
+			input:    `Hello <break time="500ms"/> world`,
+			expected: `<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xmlns:mstts='https://www.w3.org/2001/mstts' xml:lang='en-US'><voice xml:lang='en-US' xml:gender='Male' name='en-US-AvaNeural'>Hello <break time='500ms'/> world</voice></speak>`,
+		},
+		"SpeakWithoutVoice": {
+			input:    `<speak version="1.0" xml:lang="en-US">Hello</speak>`,
+			expected: `<speak version="1.0" xml:lang="en-US"><voice name='en-US-AvaNeural'>Hello</voice></speak>`,
+		},
+		"SpeakWithVoice": {
+			input:    `<speak version="1.0"><voice name="en-US-AndrewNeural">Hello</voice></speak>`,
+			expected: `<speak version="1.0"><voice name="en-US-AndrewNeural">Hello</voice></speak>`,
+		},
+		"SpeakWithBackgroundAudio": {
+			input:    `<speak xmlns:mstts="https://www.w3.org/2001/mstts"><mstts:backgroundaudio src="a.wav"/>Hello</speak>`,
+			expected: `<speak xmlns:mstts="https://www.w3.org/2001/mstts"><mstts:backgroundaudio src="a.wav"/><voice name='en-US-AvaNeural'>Hello</voice></speak>`,
+		},
+		"SpeakWithVoiceAndText": {
+			input:    `<speak><voice name="en-US-AndrewNeural">Hello</voice> world</speak>`,
+			expected: `<speak><voice name="en-US-AndrewNeural">Hello</voice><voice name='en-US-AvaNeural'> world</voice></speak>`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			result := processSSML(tc.input, options, mo.None[extraBody]())
+			require.NoError(t, result.Error())
+			assert.Equal(t, tc.expected, result.MustGet())
+		})
+	}
+
+	for name, input := range map[string]string{
+		"SelfClosingSpeak": `<speak version="1.0"/>`,
+		"InvalidRoot":      `<speak>Hello</speak><voice name="a">World</voice>`,
+		"Malformed":        `<speak><voice>Hello</speak>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, processSSML(input, options, mo.None[extraBody]()).IsError())
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package microsoft
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultRegion = "eastasia"
+	defaultLocale = "en-US"
+
+	// Offsets and durations are in ticks of 100 nanoseconds.
+	ticksPerSecond = 10_000_000
+
+	shortAudioSampleRate  = 16000
+	shortAudioContentType = "audio/wav; codecs=audio/pcm; samplerate=16000"
+)
+
+// Speech to text REST API for short audio - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-speech-to-text-short#response-parameters
+type shortAudioNBest struct {
+	Confidence float64 `json:"Confidence"`
+	Lexical    string  `json:"Lexical"`
+	ITN        string  `json:"ITN"`
+	MaskedITN  string  `json:"MaskedITN"`
+	Display    string  `json:"Display"`
+}
+
+type shortAudioResponse struct {
+	RecognitionStatus string            `json:"RecognitionStatus"`
+	Offset            int64             `json:"Offset"`
+	Duration          int64             `json:"Duration"`
+	DisplayText       string            `json:"DisplayText"`
+	NBest             []shortAudioNBest `json:"NBest"`
+}
+
+func ticksToSeconds(ticks int64) float64 {
+	return float64(ticks) / ticksPerSecond
+}
+
+func regionFrom(extraBody map[string]any) string {
+	return lo.CoalesceOrEmpty(utils.GetByJSONPath[string](extraBody, "{ .region }"), defaultRegion)
+}
+
+// shortAudioContent returns the audio in a format accepted by the short audio API along with its
+// Content-Type, only OGG (Opus) and WAV of 16 kHz mono 16-bit PCM are, audio in WAV of other sample rates,
+// channels and encodings, and in MP3, is transcoded into the latter.
+//
+// Speech to text REST API for short audio - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-speech-to-text-short#audio-formats
+func shortAudioContent(data []byte, contentType string) ([]byte, string, error) {
+	if strings.HasPrefix(contentType, "audio/ogg") || bytes.HasPrefix(data, []byte("OggS")) {
+		return data, "audio/ogg; codecs=opus", nil
+	}
+
+	format, _ := audio.FormatFromContentType(contentType)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")):
+		format = audio.FormatWAV
+	case bytes.HasPrefix(data, []byte("ID3")):
+		format = audio.FormatMP3
+	}
+
+	if format != audio.FormatWAV && format != audio.FormatMP3 {
+		return nil, "", apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("unsupported audio format %s, supported formats are wav, mp3 and ogg (opus)", lo.CoalesceOrEmpty(contentType, "unknown")).
+			WithSourceParameter("file")
+	}
+
+	if format == audio.FormatWAV {
+		wav, _, err := audio.DecodeWAV(data)
+		if err == nil && wav.AudioFormat == 1 && wav.BitsPerSample == 16 && wav.Channels == 1 && wav.SampleRate == shortAudioSampleRate { //nolint:mnd
+			return data, shortAudioContentType, nil
+		}
+	}
+
+	wav, err := audio.Transcode(data, audio.Spec{Format: format}, audio.Spec{Format: audio.FormatWAV, SampleRate: shortAudioSampleRate, Channels: 1}, audio.DefaultResampleQuality)
+	if err != nil {
+		return nil, "", apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("failed to convert the audio into 16 kHz PCM: %s", err.Error()).
+			WithSourceParameter("file")
+	}
+
+	return wav, shortAudioContentType, nil
+}
+
+func HandleTranscription(c echo.Context, options mo.Option[types.TranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	data, err := opts.ReadFile()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	// Speech to text REST API for short audio - Speech service - Azure AI services | Microsoft Learn
+	// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-speech-to-text-short#query-parameters
+	query := url.Values{}
+	query.Set("language", lo.CoalesceOrEmpty(opts.Language, defaultLocale))
+	query.Set("format", "detailed")
+
+	if profanity := utils.GetByJSONPath[string](opts.ExtraBody, "{ .profanity }"); profanity != "" {
+		query.Set("profanity", profanity)
+	}
+
+	reqURL := fmt.Sprintf(
+		"https://%s.stt.speech.microsoft.com/speech/recognition/conversation/cognitiveservices/v1?%s",
+		regionFrom(opts.ExtraBody),
+		query.Encode(),
+	)
+
+	body, contentType, err := shortAudioContent(data, opts.FileContentType())
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	req.Header.Set("Ocp-Apim-Subscription-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+
+	// Same as speech synthesis, error messages are returned within the status text, see HandleSpeech for details.
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		resError := handleResponseError(res)
+		if resError.IsError() {
+			return resError
+		}
+	}
+
+	var response shortAudioResponse
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	switch response.RecognitionStatus {
+	case "Success":
+	case "NoMatch", "InitialSilenceTimeout", "BabbleTimeout":
+		// Speech wasn't recognized, which is not an error for transcriptions
+	default:
+		return mo.Err[any](apierrors.
+			NewErrBadGateway().
+			WithDetail("speech recognition failed with status " + response.RecognitionStatus))
+	}
+
+	text := response.DisplayText
+	if text == "" && len(response.NBest) > 0 {
+		text = response.NBest[0].Display
+	}
+
+	result := types.VerboseTranscriptionResponse{
+		Language: lo.CoalesceOrEmpty(opts.Language, defaultLocale),
+		Duration: ticksToSeconds(response.Offset + response.Duration),
+		Text:     text,
+	}
+
+	if text != "" {
+		result.Segments = []types.TranscriptionSegment{
+			{
+				Start: ticksToSeconds(response.Offset),
+				End:   ticksToSeconds(response.Offset + response.Duration),
+				Text:  text,
+			},
+		}
+	}
+
+	return types.WriteTranscription(c, opts.ResponseFormat, result)
+}
+
+// Batch transcription - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/batch-transcription-create
+type batchTranscriptionProperties struct {
+	WordLevelTimestampsEnabled bool   `json:"wordLevelTimestampsEnabled"`
+	DiarizationEnabled         bool   `json:"diarizationEnabled"`
+	PunctuationMode            string `json:"punctuationMode,omitempty"`
+	ProfanityFilterMode        string `json:"profanityFilterMode,omitempty"`
+	TimeToLive                 string `json:"timeToLive,omitempty"`
+
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type batchTranscription struct {
+	Self            string                       `json:"self,omitempty"`
+	ContentURLs     []string                     `json:"contentUrls,omitempty"`
+	Locale          string                       `json:"locale"`
+	DisplayName     string                       `json:"displayName"`
+	Status          string                       `json:"status,omitempty"`
+	CreatedDateTime string                       `json:"createdDateTime,omitempty"`
+	Properties      batchTranscriptionProperties `json:"properties"`
+}
+
+type batchTranscriptionFiles struct {
+	Values []struct {
+		Kind  string `json:"kind"`
+		Links struct {
+			ContentURL string `json:"contentUrl"`
+		} `json:"links"`
+	} `json:"values"`
+}
+
+// Batch transcription results - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/batch-transcription-get#transcription-result-file
+type batchTranscriptionWord struct {
+	Word            string `json:"word"`
+	OffsetInTicks   int64  `json:"offsetInTicks"`
+	DurationInTicks int64  `json:"durationInTicks"`
+}
+
+type batchCombinedPhrase struct {
+	Channel int    `json:"channel"`
+	Display string `json:"display"`
+}
+
+type batchTranscriptionResult struct {
+	Source                    string                `json:"source"`
+	DurationInTicks           int64                 `json:"durationInTicks"`
+	CombinedRecognizedPhrases []batchCombinedPhrase `json:"combinedRecognizedPhrases"`
+	RecognizedPhrases         []struct {
+		Channel         int    `json:"channel"`
+		Speaker         int    `json:"speaker"`
+		Locale          string `json:"locale"`
+		OffsetInTicks   int64  `json:"offsetInTicks"`
+		DurationInTicks int64  `json:"durationInTicks"`
+		NBest           []struct {
+			Display string                   `json:"display"`
+			Words   []batchTranscriptionWord `json:"words"`
+		} `json:"nBest"`
+	} `json:"recognizedPhrases"`
+}
+
+func batchBaseURL(region string) *url.URL {
+	return lo.Must(url.Parse(fmt.Sprintf("https://%s.api.cognitive.microsoft.com/speechtotext/v3.2/transcriptions", region)))
+}
+
+func doJSONRequest(c echo.Context, method string, reqURL string, body any, authorization bool) mo.Result[*http.Response] {
+	var payload *bytes.Buffer
+
+	if body != nil {
+		payloadBytes, err := json.Marshal(body)
+		if err != nil {
+			return mo.Err[*http.Response](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+
+		payload = bytes.NewBuffer(payloadBytes)
+	} else {
+		payload = new(bytes.Buffer)
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), method, reqURL, payload)
+	if err != nil {
+		return mo.Err[*http.Response](apierrors.NewErrInternal().WithCaller())
+	}
+
+	// Result files are served from a storage account with SAS token in the URL, the subscription key
+	// must not be sent there.
+	if authorization {
+		req.Header.Set("Ocp-Apim-Subscription-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return mo.Err[*http.Response](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		defer func() { _ = res.Body.Close() }()
+
+		resError := handleResponseError(res)
+		if resError.IsError() {
+			return mo.Err[*http.Response](resError.Error())
+		}
+	}
+
+	return mo.Ok(res)
+}
+
+func decodeJSONResponse[T any](res mo.Result[*http.Response]) mo.Result[T] {
+	if res.IsError() {
+		return mo.Err[T](res.Error())
+	}
+
+	body := res.MustGet().Body
+
+	defer func() { _ = body.Close() }()
+
+	var result T
+
+	err := json.NewDecoder(body).Decode(&result)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	return mo.Ok(result)
+}
+
+func toTranscriptionJob(transcription batchTranscription) types.TranscriptionJob {
+	job := types.TranscriptionJob{
+		ID:        path.Base(transcription.Self),
+		Provider:  "microsoft",
+		CreatedAt: transcription.CreatedDateTime,
+	}
+
+	switch transcription.Status {
+	case "NotStarted":
+		job.Status = types.TranscriptionJobStatusQueued
+	case "Running":
+		job.Status = types.TranscriptionJobStatusRunning
+	case "Succeeded":
+		job.Status = types.TranscriptionJobStatusSucceeded
+	default:
+		job.Status = types.TranscriptionJobStatusFailed
+	}
+
+	if transcription.Properties.Error != nil {
+		job.Error = transcription.Properties.Error.Message
+	}
+
+	return job
+}
+
+func toVerboseTranscription(result batchTranscriptionResult, defaultLanguage string) types.VerboseTranscriptionResponse {
+	response := types.VerboseTranscriptionResponse{
+		Task:     "transcribe",
+		Language: defaultLanguage,
+		Duration: ticksToSeconds(result.DurationInTicks),
+		Text: strings.Join(lo.Map(result.CombinedRecognizedPhrases, func(item batchCombinedPhrase, _ int) string {
+			return item.Display
+		}), "\n"),
+		Segments: make([]types.TranscriptionSegment, 0, len(result.RecognizedPhrases)),
+	}
+
+	for i, phrase := range result.RecognizedPhrases {
+		if len(phrase.NBest) == 0 {
+			continue
+		}
+
+		if phrase.Locale != "" {
+			response.Language = phrase.Locale
+		}
+
+		speaker := lo.Ternary(phrase.Speaker > 0, fmt.Sprintf("speaker_%d", phrase.Speaker), "")
+
+		response.Segments = append(response.Segments, types.TranscriptionSegment{
+			ID:      i,
+			Start:   ticksToSeconds(phrase.OffsetInTicks),
+			End:     ticksToSeconds(phrase.OffsetInTicks + phrase.DurationInTicks),
+			Text:    phrase.NBest[0].Display,
+			Speaker: speaker,
+		})
+
+		for _, word := range phrase.NBest[0].Words {
+			response.Words = append(response.Words, types.TranscriptionWord{
+				Word:    word.Word,
+				Start:   ticksToSeconds(word.OffsetInTicks),
+				End:     ticksToSeconds(word.OffsetInTicks + word.DurationInTicks),
+				Speaker: speaker,
+			})
+		}
+	}
+
+	return response
+}
+
+func HandleCreateTranscriptionJob(c echo.Context, options mo.Option[types.TranscriptionJobRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	transcription := batchTranscription{
+		ContentURLs: opts.ContentURLs,
+		Locale:      lo.CoalesceOrEmpty(opts.Language, defaultLocale),
+		DisplayName: lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .display_name }"), "unspeech"),
+		Properties: batchTranscriptionProperties{
+			WordLevelTimestampsEnabled: utils.GetByJSONPath[bool](opts.ExtraBody, "{ .word_level_timestamps }"),
+			DiarizationEnabled:         utils.GetByJSONPath[bool](opts.ExtraBody, "{ .diarization }"),
+			PunctuationMode:            utils.GetByJSONPath[string](opts.ExtraBody, "{ .punctuation_mode }"),
+			ProfanityFilterMode:        utils.GetByJSONPath[string](opts.ExtraBody, "{ .profanity_filter_mode }"),
+			TimeToLive:                 utils.GetByJSONPath[string](opts.ExtraBody, "{ .time_to_live }"),
+		},
+	}
+
+	// Batch transcription - Speech service - Azure AI services | Microsoft Learn
+	// https://learn.microsoft.com/en-us/rest/api/speechtotext/transcriptions/submit?view=rest-speechtotext-v3.2
+	created := decodeJSONResponse[batchTranscription](
+		doJSONRequest(c, http.MethodPost, batchBaseURL(regionFrom(opts.ExtraBody)).String(), transcription, true),
+	)
+	if created.IsError() {
+		return mo.Err[any](created.Error())
+	}
+
+	return mo.Ok[any](c.JSON(http.StatusAccepted, toTranscriptionJob(created.MustGet())))
+}
+
+func HandleGetTranscriptionJob(c echo.Context, options mo.Option[types.TranscriptionJobQueryOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	// Transcriptions are identified by UUIDs, anything else, e.g. .., would reach other endpoints with the
+	// credential of the caller.
+	id, err := uuid.Parse(opts.ID)
+	if err != nil {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("invalid transcription job id %q, expected a UUID", opts.ID).
+			WithSourceParameter("id"))
+	}
+
+	transcriptionURL := batchBaseURL(lo.CoalesceOrEmpty(opts.ExtraQuery.Get("region"), defaultRegion)).JoinPath(id.String())
+
+	// Transcriptions - Get - REST API (Azure AI Services) | Microsoft Learn
+	// https://learn.microsoft.com/en-us/rest/api/speechtotext/transcriptions/get?view=rest-speechtotext-v3.2
+	transcription := decodeJSONResponse[batchTranscription](
+		doJSONRequest(c, http.MethodGet, transcriptionURL.String(), nil, true),
+	)
+	if transcription.IsError() {
+		return mo.Err[any](transcription.Error())
+	}
+
+	job := toTranscriptionJob(transcription.MustGet())
+	if job.Status != types.TranscriptionJobStatusSucceeded {
+		return mo.Ok[any](c.JSON(http.StatusOK, job))
+	}
+
+	// Transcriptions - List Files - REST API (Azure AI Services) | Microsoft Learn
+	// https://learn.microsoft.com/en-us/rest/api/speechtotext/transcriptions/list-files?view=rest-speechtotext-v3.2
+	files := decodeJSONResponse[batchTranscriptionFiles](
+		doJSONRequest(c, http.MethodGet, transcriptionURL.JoinPath("files").String(), nil, true),
+	)
+	if files.IsError() {
+		return mo.Err[any](files.Error())
+	}
+
+	job.Results = make([]types.VerboseTranscriptionResponse, 0)
+
+	for _, file := range files.MustGet().Values {
+		if file.Kind != "Transcription" {
+			continue
+		}
+
+		result := decodeJSONResponse[batchTranscriptionResult](
+			doJSONRequest(c, http.MethodGet, file.Links.ContentURL, nil, false),
+		)
+		if result.IsError() {
+			return mo.Err[any](result.Error())
+		}
+
+		job.Results = append(job.Results, toVerboseTranscription(result.MustGet(), transcription.MustGet().Locale))
+	}
+
+	return mo.Ok[any](c.JSON(http.StatusOK, job))
+}
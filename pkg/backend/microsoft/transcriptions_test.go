@@ -0,0 +1,26 @@
+package microsoft
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+)
+
+func TestHandleGetTranscriptionJobInvalidID(t *testing.T) {
+	t.Parallel()
+
+	for _, id := range []string{"..", "../../voices/list", "", "not-a-uuid"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/audio/transcriptions/jobs/"+url.PathEscape(id), nil)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		result := HandleGetTranscriptionJob(c, mo.Some(types.TranscriptionJobQueryOptions{ID: id, Backend: "microsoft", ExtraQuery: url.Values{}}))
+		assert.Error(t, result.Error(), id)
+	}
+}
@@ -1,9 +1,14 @@
 package microsoft
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"log/slog"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
@@ -58,6 +63,18 @@ var (
 		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 44100, FormatCode: "raw-44100hz-16bit-mono-pcm"},               //nolint:mnd
 		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 48000, FormatCode: "raw-48khz-16bit-mono-pcm"},                 //nolint:mnd
 
+		// RIFF (WAV) formats
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 8000, FormatCode: "riff-8khz-16bit-mono-pcm"},     //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 16000, FormatCode: "riff-16khz-16bit-mono-pcm"},   //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 22050, FormatCode: "riff-22050hz-16bit-mono-pcm"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, FormatCode: "riff-24khz-16bit-mono-pcm"},   //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 44100, FormatCode: "riff-44100hz-16bit-mono-pcm"}, //nolint:mnd
+		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 48000, FormatCode: "riff-48khz-16bit-mono-pcm"},   //nolint:mnd
+
+		// Synthesized as WAV and transcoded
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 24000, FormatCode: "riff-24khz-16bit-mono-pcm"}, //nolint:mnd
+		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 48000, FormatCode: "riff-48khz-16bit-mono-pcm"}, //nolint:mnd
+
 		// TrueSilk formats
 		{Name: "TrueSilk", Extension: ".silk", MimeType: "audio/silk", SampleRate: 16000, FormatCode: "raw-16khz-16bit-mono-truesilk"}, //nolint:mnd
 		{Name: "TrueSilk", Extension: ".silk", MimeType: "audio/silk", SampleRate: 24000, FormatCode: "raw-24khz-16bit-mono-truesilk"}, //nolint:mnd
@@ -84,38 +101,168 @@ type Voice struct {
 	WordsPerMinute  string                `json:"WordsPerMinute"`
 }
 
-func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
-	region := options.MustGet().ExtraQuery.Get("region")
-	if region == "" {
-		region = "eastasia"
+const (
+	// The catalog rarely changes, cached voices are refreshed in background once they are older than
+	// voicesRefreshAfter, and fetched again synchronously once they are older than voicesExpireAfter.
+	voicesRefreshAfter = time.Hour
+	voicesExpireAfter  = 24 * time.Hour
+	voicesFetchTimeout = 30 * time.Second
+)
+
+type cachedVoices struct {
+	mu sync.Mutex
+
+	voices     []Voice
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+var (
+	voicesMutex sync.Mutex
+	// Keyed by the base URL and a hash of the credential, so that a catalog is only ever served to callers
+	// with the credential it was fetched with.
+	voicesCache = make(map[string]*cachedVoices)
+)
+
+func voicesCacheKey(auth authOptions, credential string) string {
+	hash := sha256.Sum256([]byte(auth.AuthType + ":" + auth.ResourceID + ":" + credential))
+
+	return auth.ttsBaseURL() + ":" + hex.EncodeToString(hash[:])
+}
+
+// voicesFor returns the cache entry of the credential, expired entries of other credentials are dropped
+// along the way as tokens like the ones of Microsoft Entra ID rotate.
+func voicesFor(auth authOptions, credential string) *cachedVoices {
+	voicesMutex.Lock()
+	defer voicesMutex.Unlock()
+
+	for key, cached := range voicesCache {
+		if !cached.mu.TryLock() {
+			continue
+		}
+
+		if !cached.refreshing && time.Since(cached.fetchedAt) >= voicesExpireAfter {
+			delete(voicesCache, key)
+		}
+
+		cached.mu.Unlock()
 	}
 
-	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, "https://"+region+".tts.speech.microsoft.com/cognitiveservices/voices/list", nil)
+	cacheKey := voicesCacheKey(auth, credential)
+
+	cached, ok := voicesCache[cacheKey]
+	if !ok {
+		cached = &cachedVoices{}
+		voicesCache[cacheKey] = cached
+	}
+
+	return cached
+}
+
+// Get a list of voices - Text to speech API reference (REST) - Speech service - Azure AI services | Microsoft Learn
+// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/rest-text-to-speech#get-a-list-of-voices
+func fetchVoices(ctx context.Context, auth authOptions, credential string) ([]Voice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, auth.ttsBaseURL()+"/cognitiveservices/voices/list", nil)
 	if err != nil {
-		return mo.Err[any](apierrors.NewErrInternal().WithError(err).WithCaller())
+		return nil, apierrors.NewErrInternal().WithError(err).WithCaller()
 	}
 
-	req.Header.Set("Ocp-Apim-Subscription-Key", strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "))
+	err = authorize(ctx, req, auth, credential)
+	if err != nil {
+		return nil, err
+	}
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		return mo.Err[any](apierrors.NewErrBadGateway().WithError(err).WithCaller())
+		return nil, apierrors.NewErrBadGateway().WithError(err).WithCaller()
 	}
 
 	defer func() { _ = res.Body.Close() }()
 
 	if res.StatusCode >= 400 && res.StatusCode < 600 {
-		resError := handleResponseError(res)
-		if resError.IsError() {
-			return resError
-		}
+		return nil, handleResponseError(res).Error()
 	}
 
 	var response []Voice
 
 	err = json.NewDecoder(res.Body).Decode(&response)
 	if err != nil {
-		return mo.Err[any](apierrors.NewErrInternal().WithError(err).WithCaller())
+		return nil, apierrors.NewErrInternal().WithError(err).WithCaller()
+	}
+
+	return response, nil
+}
+
+// cachedVoicesFor returns the catalog cached for the credential, stale catalogs are served while being
+// refreshed in background with the same credential.
+//
+// NOTICE: the catalog of prebuilt voices is the same for every resource in the region, but it's still
+// cached per credential, so that a catalog fetched by a valid credential is never served to an invalid one.
+func cachedVoicesFor(ctx context.Context, auth authOptions, credential string) ([]Voice, error) {
+	if credential == "" {
+		return nil, apierrors.
+			NewErrUnauthorized().
+			WithDetail("Authorization header is required").
+			WithSourceHeader("Authorization")
+	}
+
+	cached := voicesFor(auth, credential)
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	age := time.Since(cached.fetchedAt)
+
+	if cached.voices != nil && age < voicesExpireAfter {
+		if age >= voicesRefreshAfter && !cached.refreshing {
+			cached.refreshing = true
+
+			go refreshVoices(cached, auth, credential)
+		}
+
+		return cached.voices, nil
+	}
+
+	voices, err := fetchVoices(ctx, auth, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	cached.voices = voices
+	cached.fetchedAt = time.Now()
+
+	return voices, nil
+}
+
+func refreshVoices(cached *cachedVoices, auth authOptions, credential string) {
+	ctx, cancel := context.WithTimeout(context.Background(), voicesFetchTimeout)
+	defer cancel()
+
+	voices, err := fetchVoices(ctx, auth, credential)
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	cached.refreshing = false
+
+	if err != nil {
+		slog.Warn("failed to refresh voices of microsoft", slog.String("base_url", auth.ttsBaseURL()), slog.String("error", err.Error()))
+		return
+	}
+
+	cached.voices = voices
+	cached.fetchedAt = time.Now()
+}
+
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	auth := newAuthOptions(options.MustGet().ExtraQuery.Get)
+	if auth.IsError() {
+		return mo.Err[any](auth.Error())
+	}
+
+	response, err := cachedVoicesFor(c.Request().Context(), auth.MustGet(), credentialFrom(c))
+	if err != nil {
+		return mo.Err[any](err)
 	}
 
 	voices := make([]types.Voice, 0, len(response))
@@ -0,0 +1,29 @@
+package microsoft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoicesFor(t *testing.T) {
+	auth := authOptions{Region: "eastus", AuthType: AuthTypeSubscriptionKey}
+
+	cached := voicesFor(auth, "key-a")
+	cached.voices = []Voice{{ShortName: "en-US-AvaNeural"}}
+	cached.fetchedAt = time.Now()
+
+	assert.Same(t, cached, voicesFor(auth, "key-a"))
+	assert.NotSame(t, cached, voicesFor(auth, "key-b"))
+	assert.NotSame(t, cached, voicesFor(authOptions{Region: "westus", AuthType: AuthTypeSubscriptionKey}, "key-a"))
+
+	cached.fetchedAt = time.Now().Add(-voicesExpireAfter)
+
+	voicesFor(auth, "key-b")
+
+	voicesMutex.Lock()
+	defer voicesMutex.Unlock()
+
+	assert.NotContains(t, voicesCache, voicesCacheKey(auth, "key-a"))
+}
@@ -2,44 +2,125 @@ package openai
 
 import (
 	"bytes"
-	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
 	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/moeru-ai/unspeech/pkg/utils/jsonpatch"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+// MaxInputLength is the maximum length of input, longer text is synthesized in chunks.
+//
+// Create speech - OpenAI API
+// https://platform.openai.com/docs/api-reference/audio/createSpeech#audio-createspeech-input
+const MaxInputLength = 4096
+
+// ProsodyRanges is the prosody OpenAI supports, only the speed, gpt-4o-mini-tts takes instructions for
+// the rest.
+var ProsodyRanges = types.ProsodyRanges{
+	Speed: mo.Some(types.ProsodyRange{Min: 0.25, Max: 4.0}), //nolint:mnd
+}
+
+// Formats OpenAI synthesizes natively, the others, e.g. ulaw and alaw, are transcoded from wav.
+var responseFormats = []string{"mp3", "opus", "aac", "flac", "wav", "pcm"}
+
+// Audio is always synthesized at 24kHz in mono.
+//
+// Text to speech - OpenAI API
+// https://platform.openai.com/docs/guides/text-to-speech#supported-output-formats
+const nativeSampleRate = 24000
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
-	// Extract options safely once
-	opt := options.MustGet()
-
-	values := types.OpenAISpeechRequestOptions{
-		Model:          opt.Model,
-		Input:          opt.Input,
-		Voice:          opt.Voice,
-		ResponseFormat: opt.ResponseFormat,
-		Speed:          opt.Speed,
+	header := http.Header{}
+	header.Set("Authorization", c.Request().Header.Get("Authorization"))
+
+	return SpeechRequest(c, options.MustGet(), "https://api.openai.com/v1/audio/speech", header)
+}
+
+// SpeechRequest sends an OpenAI shaped speech request to endpoint with the given headers and
+// streams the audio back, shared by the backends that are compatible with OpenAI API.
+//
+// The request body is forwarded as it is, e.g. instructions and stream_format, except that the model
+// is stripped of the backend prefix, and extra_body is flattened into the body without the keys that
+// are consumed by the backend itself.
+//
+// Create speech - OpenAI API
+// https://platform.openai.com/docs/api-reference/audio/createSpeech
+func SpeechRequest(c echo.Context, opt types.SpeechRequestOptions, endpoint string, header http.Header, consumedExtraBody ...string) mo.Result[any] {
+	patches := []mo.Option[jsonpatch.JSONPatchOperationObject]{
+		jsonpatch.NewReplace("/model", opt.Model),
+		jsonpatch.NewRemove("/extra_body"),
+		jsonpatch.NewRemove("/pitch"),
+		jsonpatch.NewRemove("/volume"),
+		jsonpatch.NewRemove("/sample_rate"),
+		jsonpatch.NewRemove("/channels"),
+		jsonpatch.NewRemove("/resample_quality"),
+	}
+
+	responseFormat := lo.CoalesceOrEmpty(opt.ResponseFormat, "mp3")
+
+	// The sample rate is fixed, so it can only be changed by resampling.
+	native := audio.Spec{Format: responseFormat, SampleRate: nativeSampleRate, Channels: 1}
+
+	err := types.CheckOutput(native, responseFormat, opt.Output)
+	if err != nil {
+		return mo.Err[any](err)
 	}
 
-	payload := lo.Must(json.Marshal(values))
+	transcoded := audio.CanEncode(responseFormat) &&
+		(!lo.Contains(responseFormats, responseFormat) || !native.Matches(opt.Output.Spec(responseFormat)))
+	if transcoded {
+		// Transcoded formats need the whole audio, which can't be sent as events.
+		if utils.GetByJSONPath[string](opt.AsMap(), "{ .stream_format }") == "sse" {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetailf("stream_format sse is not supported with response_format %s, sample_rate or channels that need transcoding", responseFormat).
+				WithSourcePointer("/stream_format"))
+		}
+
+		patches = append(patches, jsonpatch.NewReplace("/response_format", audio.FormatWAV))
+	}
+
+	patchedPayload := jsonpatch.ApplyPatches(
+		opt.AsBuffer().OrElse(new(bytes.Buffer)).Bytes(),
+		mo.Some(jsonpatch.ApplyOptions{AllowMissingPathOnRemove: true}),
+		append(
+			patches,
+			lo.FilterMap(
+				lo.Entries(opt.ExtraBody),
+				func(item lo.Entry[string, any], index int) (mo.Option[jsonpatch.JSONPatchOperationObject], bool) {
+					return jsonpatch.NewAdd("/"+item.Key, item.Value), !lo.Contains(consumedExtraBody, item.Key)
+				})...,
+		)...,
+	)
+	if patchedPayload.IsError() {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(patchedPayload.Error().Error()).WithCaller())
+	}
+
+	payload := patchedPayload.MustGet()
 
 	req, err := http.NewRequestWithContext(
 		c.Request().Context(),
 		http.MethodPost,
-		"https://api.openai.com/v1/audio/speech",
+		endpoint,
 		bytes.NewBuffer(payload),
 	)
 	if err != nil {
 		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
 	}
 
-	req.Header.Set("Authorization", c.Request().Header.Get("Authorization"))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
 	req.Header.Set("Content-Type", "application/json")
 
 	res, err := http.DefaultClient.Do(req)
@@ -82,6 +163,24 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		}
 	}
 
-	// Stream audio response with correct upstream content type
-	return mo.Ok[any](c.Stream(http.StatusOK, res.Header.Get("Content-Type"), res.Body))
+	if transcoded {
+		wav, err := io.ReadAll(res.Body)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, wav, audio.Spec{Format: audio.FormatWAV}, responseFormat, opt.Output)
+	}
+
+	// With stream_format set to sse, speech.audio.delta events carrying base64 encoded audio are sent
+	// until speech.audio.done, they are relayed to the client as they arrive.
+	//
+	// Streaming - OpenAI API
+	// https://platform.openai.com/docs/api-reference/audio/speech-audio-delta-event
+	return mo.Ok[any](utils.StreamWithFlush(
+		c,
+		http.StatusOK,
+		lo.CoalesceOrEmpty(res.Header.Get("Content-Type"), "audio/mpeg"),
+		res.Body,
+	))
 }
@@ -0,0 +1,54 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+)
+
+func TestSpeechRequestTranscodedSSE(t *testing.T) {
+	t.Parallel()
+
+	for name, body := range map[string]map[string]any{
+		"ulaw":        {"response_format": "ulaw"},
+		"sample rate": {"response_format": "pcm", "sample_rate": 16000},
+		"channels":    {"response_format": "wav", "channels": 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			body["model"] = "tts-1"
+			body["voice"] = "alloy"
+			body["input"] = "Hello"
+			body["stream_format"] = "sse"
+
+			payload, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			options := types.NewSpeechRequestOptions(io.NopCloser(bytes.NewReader(payload)))
+			require.NoError(t, options.Error())
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			// Rejected before anything is sent upstream.
+			result := SpeechRequest(c, options.MustGet(), "http://127.0.0.1:0", http.Header{})
+
+			var apiErr *apierrors.Error
+
+			require.ErrorAs(t, result.Error(), &apiErr)
+			assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+			assert.Equal(t, "/stream_format", apiErr.Source.MustGet().Pointer)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/mo"
+)
+
+func HandleTranslation(c echo.Context, options mo.Option[types.TranscriptionRequestOptions]) mo.Result[any] {
+	header := http.Header{}
+	header.Set("Authorization", c.Request().Header.Get("Authorization"))
+
+	// Translations always output English, language of the input audio is not accepted
+	opt := options.MustGet()
+	opt.Language = ""
+
+	return AudioFileRequest(c, opt, "https://api.openai.com/v1/audio/translations", header)
+}
+
+// AudioFileRequest re-uploads the audio file of an OpenAI shaped transcription or translation request to
+// endpoint with the given headers, and responds what upstream returned as it is since the response formats
+// are the same.
+func AudioFileRequest(c echo.Context, opt types.TranscriptionRequestOptions, endpoint string, header http.Header) mo.Result[any] {
+	file, err := opt.File.Open()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	defer func() { _ = file.Close() }()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	fields := map[string]string{
+		"model":           opt.Model,
+		"prompt":          opt.Prompt,
+		"language":        opt.Language,
+		"response_format": opt.ResponseFormat,
+	}
+	if opt.Temperature != 0 {
+		fields["temperature"] = strconv.FormatFloat(opt.Temperature, 'f', -1, 64)
+	}
+
+	for key, value := range opt.ExtraBody {
+		// null leaves the field out, instead of sending <nil>
+		if value == nil {
+			continue
+		}
+
+		fields[key] = utils.ToString(value)
+	}
+
+	for key, value := range fields {
+		if value == "" {
+			continue
+		}
+
+		err = writer.WriteField(key, value)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+	}
+
+	for _, granularity := range opt.TimestampGranularities {
+		err = writer.WriteField("timestamp_granularities[]", granularity)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", opt.File.Filename)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, endpoint, body)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithCaller())
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[any](
+			apierrors.NewErrBadGateway().
+				WithDetail(err.Error()).
+				WithError(err).
+				WithCaller(),
+		)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		ct := res.Header.Get("Content-Type")
+
+		switch {
+		case strings.HasPrefix(ct, "application/json"):
+			return mo.Err[any](
+				apierrors.NewUpstreamError(res.StatusCode).
+					WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()),
+			)
+		case strings.HasPrefix(ct, "text/"):
+			return mo.Err[any](
+				apierrors.NewUpstreamError(res.StatusCode).
+					WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()),
+			)
+		default:
+			slog.Warn("unknown upstream error",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", ct),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[any](
+				apierrors.NewUpstreamError(res.StatusCode).
+					WithDetail("unknown Content-Type: " + ct),
+			)
+		}
+	}
+
+	return mo.Ok[any](c.Stream(http.StatusOK, res.Header.Get("Content-Type"), res.Body))
+}
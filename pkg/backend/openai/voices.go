@@ -3,6 +3,7 @@ package openai
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
@@ -78,120 +79,58 @@ var (
 		{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac"},
 		{Name: "WAV", Extension: ".wav", MimeType: "audio/wav"},
 		{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm"},
+		// Synthesized as WAV and transcoded.
+		{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic"},
+		{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic"},
 	}
 )
 
+var (
+	// gpt-4o-mini-tts can be directed with instructions, e.g. accent, emotional range, tone, etc.
+	steerableModels = []string{"gpt-4o-mini-tts"}
+	allModels       = []string{"tts-1", "tts-1-hd", "gpt-4o-mini-tts"}
+)
+
+type voice struct {
+	ID               string
+	Name             string
+	CompatibleModels []string
+	PreviewAudioURL  string
+}
+
+// Text to speech - OpenAI API
+// https://platform.openai.com/docs/guides/text-to-speech#voice-options
+var voices = []voice{
+	{ID: "alloy", Name: "Alloy", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/alloy.wav"},
+	{ID: "ash", Name: "Ash", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/ash.wav"},
+	{ID: "ballad", Name: "Ballad", CompatibleModels: steerableModels},
+	{ID: "coral", Name: "Coral", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/coral.wav"},
+	{ID: "echo", Name: "Echo", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/echo.wav"},
+	{ID: "fable", Name: "Fable", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/fable.wav"},
+	{ID: "onyx", Name: "Onyx", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/onyx.wav"},
+	{ID: "nova", Name: "Nova", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/nova.wav"},
+	{ID: "sage", Name: "Sage", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/sage.wav"},
+	{ID: "shimmer", Name: "Shimmer", CompatibleModels: allModels, PreviewAudioURL: "https://cdn.openai.com/API/docs/audio/shimmer.wav"},
+	{ID: "verse", Name: "Verse", CompatibleModels: steerableModels},
+	{ID: "marin", Name: "Marin", CompatibleModels: steerableModels},
+	{ID: "cedar", Name: "Cedar", CompatibleModels: steerableModels},
+}
+
 func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
 	return mo.Ok[any](types.ListVoicesResponse{
-		Voices: []types.Voice{
-			{
-				ID:                "alloy",
-				Name:              "Alloy",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/alloy.wav",
-			},
-			{
-				ID:                "ash",
-				Name:              "Ash",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/ash.wav",
-			},
-			{
-				ID:                "coral",
-				Name:              "Coral",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/coral.wav",
-			},
-			{
-				ID:                "echo",
-				Name:              "Echo",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/echo.wav",
-			},
-			{
-				ID:                "fable",
-				Name:              "Fable",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/fable.wav",
-			},
-			{
-				ID:                "onyx",
-				Name:              "Onyx",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/onyx.wav",
-			},
-			{
-				ID:                "nova",
-				Name:              "Nova",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/nova.wav",
-			},
-			{
-				ID:                "sage",
-				Name:              "Sage",
-				Description:       "",
-				Labels:            map[string]any{},
-				Tags:              make([]string, 0),
-				Languages:         languages,
-				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
-				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/sage.wav",
-			},
-			{
-				ID:                "shimmer",
-				Name:              "Shimmer",
+		Voices: lo.Map(voices, func(item voice, _ int) types.Voice {
+			return types.Voice{
+				ID:                item.ID,
+				Name:              item.Name,
 				Description:       "",
 				Labels:            map[string]any{},
 				Tags:              make([]string, 0),
 				Languages:         languages,
 				Formats:           formats,
-				CompatibleModels:  []string{"tts-1", "tts-1-hd"},
+				CompatibleModels:  item.CompatibleModels,
 				PredefinedOptions: nil,
-				PreviewAudioURL:   "https://cdn.openai.com/API/docs/audio/shimmer.wav",
-			},
-		},
+				PreviewAudioURL:   item.PreviewAudioURL,
+			}
+		}),
 	})
 }
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/markup"
+	"github.com/samber/mo"
+)
+
+// ParseMarkup parses <break>, <emphasis>, <say-as> and <sub> in the input of a speech request.
+func ParseMarkup(input string) mo.Result[[]markup.Node] {
+	nodes, err := markup.Parse(input)
+	if err != nil {
+		return mo.Err[[]markup.Node](apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("input is not well-formed markup, < and & in text should be escaped as &lt; and &amp;: %s", err.Error()).
+			WithSourcePointer("/input"))
+	}
+
+	return mo.Ok(nodes)
+}
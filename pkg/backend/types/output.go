@@ -0,0 +1,113 @@
+package types
+
+import (
+	"slices"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+)
+
+// AudioOutput is the sample rate and channel layout asked for, unSpeech extensions sent along with
+// response_format in the request body. Zero means whatever the provider produces.
+//
+// Backends pick a native sample rate when the provider offers it, otherwise the audio is resampled.
+type AudioOutput struct {
+	// Sample rate in Hz, from 8000 to 192000.
+	SampleRate int `json:"sample_rate,omitempty"`
+	// 1 for mono, 2 for stereo, speech is always synthesized in mono.
+	Channels int `json:"channels,omitempty"`
+	// Quality of the resampler, one of low, medium and high, medium is the default.
+	ResampleQuality audio.ResampleQuality `json:"resample_quality,omitempty"`
+}
+
+const (
+	minOutputSampleRate = 8000
+	maxOutputSampleRate = 192000
+)
+
+// Validate checks whether the output can be produced at all.
+func (o AudioOutput) Validate() error {
+	if o.SampleRate != 0 && (o.SampleRate < minOutputSampleRate || o.SampleRate > maxOutputSampleRate) {
+		return apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("sample_rate must be between %d and %d, got %d", minOutputSampleRate, maxOutputSampleRate, o.SampleRate).
+			WithSourcePointer("/sample_rate")
+	}
+	if o.Channels != 0 && o.Channels != 1 && o.Channels != 2 {
+		return apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("channels must be either 1 or 2, got %d", o.Channels).
+			WithSourcePointer("/channels")
+	}
+	if !audio.IsResampleQuality(o.ResampleQuality) {
+		return apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("resample_quality must be one of low, medium and high, got %s", o.ResampleQuality).
+			WithSourcePointer("/resample_quality")
+	}
+
+	return nil
+}
+
+// WithProviderSampleRate takes the sample rate set in extra_body in the shape of the provider, e.g.
+// extra_body.audio.rate, as the sample rate asked for, so that it's validated and picked the same way. It
+// must not disagree with sample_rate, pointer locates it in the request body.
+func (o AudioOutput) WithProviderSampleRate(sampleRate *int, pointer string) (AudioOutput, error) {
+	if sampleRate == nil {
+		return o, nil
+	}
+	if *sampleRate < minOutputSampleRate || *sampleRate > maxOutputSampleRate {
+		return o, apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("sample rate must be between %d and %d, got %d", minOutputSampleRate, maxOutputSampleRate, *sampleRate).
+			WithSourcePointer(pointer)
+	}
+	if o.SampleRate != 0 && o.SampleRate != *sampleRate {
+		return o, apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("sample rate %d disagrees with sample_rate %d, set only one of them", *sampleRate, o.SampleRate).
+			WithSourcePointer(pointer)
+	}
+
+	o.SampleRate = *sampleRate
+
+	return o, nil
+}
+
+// Quality returns the resample quality asked for, or the default one.
+func (o AudioOutput) Quality() audio.ResampleQuality {
+	if o.ResampleQuality == "" {
+		return audio.DefaultResampleQuality
+	}
+
+	return o.ResampleQuality
+}
+
+// Spec returns the target of the conversion from what the provider synthesized.
+func (o AudioOutput) Spec(format audio.Format) audio.Spec {
+	return audio.Spec{Format: format, SampleRate: o.SampleRate, Channels: o.Channels}
+}
+
+// IsSpecified tells whether a sample rate or channel layout is asked for.
+func (o AudioOutput) IsSpecified() bool {
+	return o.SampleRate != 0 || o.Channels != 0
+}
+
+// NativeSampleRate picks the sample rate to request from a provider offering the rates: the one asked
+// for when supported, otherwise the lowest one above it, or the highest one, to resample from the closest
+// without losing quality. fallback is used when no sample rate is asked for.
+func (o AudioOutput) NativeSampleRate(supported []int, fallback int) int {
+	if o.SampleRate == 0 || len(supported) == 0 {
+		return fallback
+	}
+
+	sorted := slices.Sorted(slices.Values(supported))
+
+	for _, rate := range sorted {
+		if rate >= o.SampleRate {
+			return rate
+		}
+	}
+
+	return sorted[len(sorted)-1]
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioOutputWithProviderSampleRate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		output     AudioOutput
+		sampleRate *int
+		expected   AudioOutput
+		fails      bool
+	}{
+		"absent":         {output: AudioOutput{SampleRate: 16000}, expected: AudioOutput{SampleRate: 16000}},
+		"taken":          {output: AudioOutput{Channels: 2}, sampleRate: lo.ToPtr(8000), expected: AudioOutput{SampleRate: 8000, Channels: 2}},
+		"same":           {output: AudioOutput{SampleRate: 8000}, sampleRate: lo.ToPtr(8000), expected: AudioOutput{SampleRate: 8000}},
+		"disagrees":      {output: AudioOutput{SampleRate: 16000}, sampleRate: lo.ToPtr(8000), fails: true},
+		"out of range":   {sampleRate: lo.ToPtr(4000), fails: true},
+		"zero is no use": {sampleRate: lo.ToPtr(0), fails: true},
+	} {
+		output, err := tc.output.WithProviderSampleRate(tc.sampleRate, "/extra_body/sample_rate")
+		if tc.fails {
+			assert.Error(t, err, name)
+
+			continue
+		}
+
+		require.NoError(t, err, name)
+		assert.Equal(t, tc.expected, output, name)
+	}
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"math"
+
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// Prosody is the provider independent way to control how fast, how high and how loud the speech is.
+// Every value is a multiplier where 1.0 is the normal of the voice, mo.None means not specified, which is
+// different from 0, e.g. volume 0 mutes the speech where supported.
+//
+// speed comes from the OpenAI compatible request, pitch and volume are unSpeech extensions sent along
+// with it in the request body, e.g. through extra_body of the OpenAI SDKs.
+type Prosody struct {
+	// Speaking rate, from 0.25 to 4.0.
+	Speed mo.Option[float64] `json:"speed"`
+	// Pitch, from 0.5 to 2.0.
+	Pitch mo.Option[float64] `json:"pitch"`
+	// Volume, from 0 to 2.0.
+	Volume mo.Option[float64] `json:"volume"`
+}
+
+// ProsodyRange is the range of multipliers accepted, bounds included.
+type ProsodyRange struct {
+	Min float64
+	Max float64
+}
+
+// ProsodyRanges describes the prosody a backend supports, mo.None means not supported at all.
+type ProsodyRanges struct {
+	Speed  mo.Option[ProsodyRange]
+	Pitch  mo.Option[ProsodyRange]
+	Volume mo.Option[ProsodyRange]
+}
+
+var (
+	// Ranges accepted by unSpeech regardless of the backend.
+	defaultProsodyRanges = ProsodyRanges{
+		Speed:  mo.Some(ProsodyRange{Min: 0.25, Max: 4.0}), //nolint:mnd
+		Pitch:  mo.Some(ProsodyRange{Min: 0.5, Max: 2.0}),  //nolint:mnd
+		Volume: mo.Some(ProsodyRange{Min: 0, Max: 2.0}),    //nolint:mnd
+	}
+)
+
+func validateProsodyValue(name string, value mo.Option[float64], supported mo.Option[ProsodyRange]) error {
+	v, ok := value.Get()
+
+	// Not specified, or the normal which every backend produces anyway.
+	if !ok || v == 1 {
+		return nil
+	}
+
+	r, ok := supported.Get()
+	if !ok {
+		return apierrors.
+			NewErrUnsupportedCapability().
+			WithDetailf("%s is not supported by this backend", name).
+			WithSourcePointer("/" + name)
+	}
+	if v < r.Min || v > r.Max {
+		return apierrors.
+			NewErrInvalidArgument().
+			WithDetailf("%s must be between %g and %g, got %g", name, r.Min, r.Max, v).
+			WithSourcePointer("/" + name)
+	}
+
+	return nil
+}
+
+// Validate checks whether the prosody falls into the ranges supported by the backend.
+func (p Prosody) Validate(supported ProsodyRanges) error {
+	for _, item := range []lo.Tuple3[string, mo.Option[float64], mo.Option[ProsodyRange]]{
+		lo.T3("speed", p.Speed, supported.Speed),
+		lo.T3("pitch", p.Pitch, supported.Pitch),
+		lo.T3("volume", p.Volume, supported.Volume),
+	} {
+		err := validateProsodyValue(item.A, item.B, item.C)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProsodyScale describes how a provider expresses a prosody value, e.g. from 0 to 15 with 5 as normal.
+type ProsodyScale struct {
+	Min    float64
+	Normal float64
+	Max    float64
+}
+
+// Map maps the multiplier to the scale piecewise linearly, 1.0 maps to Normal and the bounds of r map to
+// the bounds of the scale. Unspecified values map to Normal.
+func (s ProsodyScale) Map(value mo.Option[float64], r ProsodyRange) float64 {
+	v, ok := value.Get()
+
+	switch {
+	case !ok:
+		return s.Normal
+	case v < 1:
+		return s.Normal - (1-lo.Clamp(v, r.Min, 1))/(1-r.Min)*(s.Normal-s.Min)
+	case v > 1:
+		return s.Normal + (lo.Clamp(v, 1, r.Max)-1)/(r.Max-1)*(s.Max-s.Normal)
+	default:
+		return s.Normal
+	}
+}
+
+// MapInt is Map rounded to the nearest integer, for scales made of integers only.
+func (s ProsodyScale) MapInt(value mo.Option[float64], r ProsodyRange) int {
+	return int(math.Round(s.Map(value, r)))
+}
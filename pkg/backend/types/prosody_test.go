@@ -0,0 +1,118 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProsodyUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	for body, expected := range map[string]Prosody{
+		`{}`:                       {},
+		`{"speed":null}`:           {},
+		`{"volume":0}`:             {Volume: mo.Some(0.0)},
+		`{"speed":1.25,"pitch":1}`: {Speed: mo.Some(1.25), Pitch: mo.Some(1.0)},
+	} {
+		var prosody Prosody
+
+		require.NoError(t, json.Unmarshal([]byte(body), &prosody))
+		assert.Equal(t, expected, prosody, body)
+	}
+}
+
+func TestProsodyValidate(t *testing.T) {
+	t.Parallel()
+
+	ranges := ProsodyRanges{
+		Speed:  mo.Some(ProsodyRange{Min: 0.5, Max: 2.0}),
+		Volume: mo.Some(ProsodyRange{Min: 0, Max: 2.0}),
+	}
+
+	for name, tc := range map[string]struct {
+		prosody Prosody
+		ranges  ProsodyRanges
+		valid   bool
+	}{
+		"unspecified":                      {prosody: Prosody{}, ranges: ProsodyRanges{}, valid: true},
+		"normal of unsupported":            {prosody: Prosody{Pitch: mo.Some(1.0)}, ranges: ranges, valid: true},
+		"within range":                     {prosody: Prosody{Speed: mo.Some(1.5), Volume: mo.Some(0.5)}, ranges: ranges, valid: true},
+		"bounds included":                  {prosody: Prosody{Speed: mo.Some(0.5), Volume: mo.Some(2.0)}, ranges: ranges, valid: true},
+		"volume 0 mutes":                   {prosody: Prosody{Volume: mo.Some(0.0)}, ranges: ranges, valid: true},
+		"speed 0":                          {prosody: Prosody{Speed: mo.Some(0.0)}, ranges: ranges, valid: false},
+		"below range":                      {prosody: Prosody{Speed: mo.Some(0.25)}, ranges: ranges, valid: false},
+		"above range":                      {prosody: Prosody{Volume: mo.Some(3.0)}, ranges: ranges, valid: false},
+		"unsupported":                      {prosody: Prosody{Pitch: mo.Some(1.5)}, ranges: ranges, valid: false},
+		"speed 0 out of default range":     {prosody: Prosody{Speed: mo.Some(0.0)}, ranges: defaultProsodyRanges, valid: false},
+		"volume 0 within default range":    {prosody: Prosody{Volume: mo.Some(0.0)}, ranges: defaultProsodyRanges, valid: true},
+		"volume 0 where minimum is higher": {prosody: Prosody{Volume: mo.Some(0.0)}, ranges: ProsodyRanges{Volume: mo.Some(ProsodyRange{Min: 0.5, Max: 2.0})}, valid: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.prosody.Validate(tc.ranges)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestProsodyScaleMap(t *testing.T) {
+	t.Parallel()
+
+	// e.g. the volume of CosyVoice, from 0 to 100 with 50 as normal.
+	scale := ProsodyScale{Min: 0, Normal: 50, Max: 100}
+	r := ProsodyRange{Min: 0, Max: 2.0}
+
+	for name, tc := range map[string]struct {
+		value    mo.Option[float64]
+		expected float64
+	}{
+		"unspecified":  {value: mo.None[float64](), expected: 50},
+		"normal":       {value: mo.Some(1.0), expected: 50},
+		"minimum":      {value: mo.Some(0.0), expected: 0},
+		"half":         {value: mo.Some(0.5), expected: 25},
+		"maximum":      {value: mo.Some(2.0), expected: 100},
+		"one and half": {value: mo.Some(1.5), expected: 75},
+		"clamped":      {value: mo.Some(4.0), expected: 100},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.InDelta(t, tc.expected, scale.Map(tc.value, r), 1e-9)
+		})
+	}
+}
+
+func TestProsodyScaleMapInt(t *testing.T) {
+	t.Parallel()
+
+	// e.g. the speed of Baidu, from 0 to 15 with 5 as normal.
+	scale := ProsodyScale{Min: 0, Normal: 5, Max: 15}
+	r := ProsodyRange{Min: 0.5, Max: 2.0}
+
+	for name, tc := range map[string]struct {
+		value    mo.Option[float64]
+		expected int
+	}{
+		"unspecified": {value: mo.None[float64](), expected: 5},
+		"normal":      {value: mo.Some(1.0), expected: 5},
+		"minimum":     {value: mo.Some(0.5), expected: 0},
+		"maximum":     {value: mo.Some(2.0), expected: 15},
+		"rounded":     {value: mo.Some(1.25), expected: 8},
+		"below":       {value: mo.Some(0.8), expected: 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, scale.MapInt(tc.value, r))
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/jsonapi"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+type RealtimeAudioEncoding = string
+
+const (
+	// Signed 16-bit little-endian linear PCM.
+	RealtimeAudioEncodingPCM RealtimeAudioEncoding = "pcm"
+	// Opus encapsulated in Ogg.
+	RealtimeAudioEncodingOpus RealtimeAudioEncoding = "opus"
+)
+
+const (
+	defaultRealtimeSampleRate = 16000
+)
+
+const (
+	// Subprotocol selected for every WebSocket connection, clients passing the API key as a subprotocol
+	// must offer it as well, as browsers fail the handshake when none of the offered ones is selected.
+	RealtimeSubprotocol = "unspeech"
+	// Prefix of the subprotocol carrying the API key, e.g. unspeech.api-key.sk-xxx.
+	RealtimeAPIKeySubprotocolPrefix = "unspeech.api-key."
+)
+
+// realtimeAuthorization returns the API key of the upstream provider from the Authorization header, or
+// from the Sec-WebSocket-Protocol header for browsers, which are not able to set headers for WebSocket
+// connections. Query parameters are never read, as they end up in the access logs.
+func realtimeAuthorization(c echo.Context) string {
+	if auth := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "); auth != "" {
+		return auth
+	}
+
+	for _, subprotocol := range websocket.Subprotocols(c.Request()) {
+		if key, ok := strings.CutPrefix(subprotocol, RealtimeAPIKeySubprotocolPrefix); ok {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// errRealtimeAPIKeyInQuery rejects the api_key query parameter, so that clients don't get used to leaking the
+// key into access logs.
+func errRealtimeAPIKeyInQuery() *apierrors.Error {
+	return apierrors.
+		NewErrInvalidArgument().
+		WithDetailf("api_key must not be passed as a query parameter, use the Authorization header or the %s<key> WebSocket subprotocol instead", RealtimeAPIKeySubprotocolPrefix).
+		WithSourceParameter("api_key")
+}
+
+type RealtimeTranscriptionRequestOptions struct {
+	Backend string `json:"provider"`
+	Model   string `json:"model"`
+
+	// The language of the input audio.
+	Language string `json:"language"`
+	// Encoding of the binary audio frames, either pcm or opus.
+	Encoding RealtimeAudioEncoding `json:"encoding"`
+	// Sample rate of the audio, only meaningful for pcm.
+	SampleRate int `json:"sample_rate"`
+	// Number of channels of the audio, only meaningful for pcm.
+	Channels int `json:"channels"`
+
+	// Extension: VAD / endpointing parameters and other provider specific options are passed through
+	// with query parameters.
+	ExtraQuery url.Values `json:"extra_query"`
+}
+
+// Authorization returns the API key of the upstream provider, see realtimeAuthorization.
+func (o RealtimeTranscriptionRequestOptions) Authorization(c echo.Context) string {
+	return realtimeAuthorization(c)
+}
+
+func NewRealtimeTranscriptionRequestOptions(request *http.Request) mo.Result[RealtimeTranscriptionRequestOptions] {
+	query := request.URL.Query()
+
+	provider := query.Get("provider")
+	if provider == "" {
+		return mo.Err[RealtimeTranscriptionRequestOptions](
+			apierrors.
+				NewErrInvalidArgument().
+				WithDetail("provider is required").
+				WithSourceParameter("provider"),
+		)
+	}
+
+	if query.Has("api_key") {
+		return mo.Err[RealtimeTranscriptionRequestOptions](errRealtimeAPIKeyInQuery())
+	}
+
+	options := RealtimeTranscriptionRequestOptions{
+		Backend:    provider,
+		Model:      query.Get("model"),
+		Language:   query.Get("language"),
+		Encoding:   lo.CoalesceOrEmpty(query.Get("encoding"), RealtimeAudioEncodingPCM),
+		SampleRate: defaultRealtimeSampleRate,
+		Channels:   1,
+		ExtraQuery: query,
+	}
+
+	if options.Encoding != RealtimeAudioEncodingPCM && options.Encoding != RealtimeAudioEncodingOpus {
+		return mo.Err[RealtimeTranscriptionRequestOptions](
+			apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported encoding, supported encodings are pcm and opus").
+				WithSourceParameter("encoding"),
+		)
+	}
+
+	for key, target := range map[string]*int{"sample_rate": &options.SampleRate, "channels": &options.Channels} {
+		value := query.Get(key)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return mo.Err[RealtimeTranscriptionRequestOptions](
+				apierrors.
+					NewErrInvalidArgument().
+					WithDetailf("%s must be a positive integer", key).
+					WithSourceParameter(key),
+			)
+		}
+
+		*target = parsed
+	}
+
+	return mo.Ok(options)
+}
+
+type RealtimeTranscriptionEventType = string
+
+const (
+	// Interim or final transcript of the audio received so far.
+	RealtimeTranscriptionEventTypeTranscript RealtimeTranscriptionEventType = "transcript"
+	// Upstream VAD detected the start of speech.
+	RealtimeTranscriptionEventTypeSpeechStarted RealtimeTranscriptionEventType = "speech_started"
+	// Upstream VAD / endpointing detected the end of an utterance.
+	RealtimeTranscriptionEventTypeSpeechEnded RealtimeTranscriptionEventType = "speech_ended"
+	// Something went wrong, the connection will be closed afterwards.
+	RealtimeTranscriptionEventTypeError RealtimeTranscriptionEventType = "error"
+	// All audio has been transcribed, the connection will be closed afterwards.
+	RealtimeTranscriptionEventTypeFinished RealtimeTranscriptionEventType = "finished"
+)
+
+type RealtimeTranscript struct {
+	Text    string  `json:"text"`
+	IsFinal bool    `json:"is_final"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+
+	Speaker string              `json:"speaker,omitempty"`
+	Words   []TranscriptionWord `json:"words,omitempty"`
+}
+
+type RealtimeTranscriptionEvent struct {
+	Type       RealtimeTranscriptionEventType `json:"type"`
+	Transcript *RealtimeTranscript            `json:"transcript,omitempty"`
+	Error      *jsonapi.ErrorObject           `json:"error,omitempty"`
+}
+
+type RealtimeTranscriptionClientEventType = string
+
+const (
+	// Sent by the client once all audio frames have been sent.
+	RealtimeTranscriptionClientEventTypeFinish RealtimeTranscriptionClientEventType = "finish"
+)
+
+type RealtimeTranscriptionClientEvent struct {
+	Type RealtimeTranscriptionClientEventType `json:"type"`
+}
+
+var realtimeUpgrader = websocket.Upgrader{
+	// Cross-origin connections are allowed on purpose, same as every other endpoint with the CORS middleware,
+	// which doesn't apply to WebSocket though. It's fine as no ambient credentials like cookies are used, the
+	// API key has to be passed explicitly by the page.
+	CheckOrigin:  func(r *http.Request) bool { return true },
+	Subprotocols: []string{RealtimeSubprotocol},
+}
+
+// RealtimeTranscriptionClient is the downstream WebSocket connection, audio comes in as binary frames
+// and RealtimeTranscriptionEvent are sent back as text frames.
+type RealtimeTranscriptionClient struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+// UpgradeRealtimeTranscriptionClient upgrades the request to WebSocket, it should be called after the
+// upstream connection is established so that upstream errors can still be responded as HTTP errors.
+func UpgradeRealtimeTranscriptionClient(c echo.Context) mo.Result[*RealtimeTranscriptionClient] {
+	conn, err := realtimeUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return mo.Err[*RealtimeTranscriptionClient](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	return mo.Ok(&RealtimeTranscriptionClient{conn: conn})
+}
+
+// ReadAudio reads the next audio frame, io.EOF is returned once the client finished or went away.
+func (c *RealtimeTranscriptionClient) ReadAudio() ([]byte, error) {
+	for {
+		messageType, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil, io.EOF
+			}
+
+			return nil, err
+		}
+
+		if messageType == websocket.BinaryMessage {
+			return message, nil
+		}
+
+		var event RealtimeTranscriptionClientEvent
+
+		err = json.Unmarshal(message, &event)
+		if err != nil {
+			slog.Warn("unknown text frame received from realtime transcription client", slog.String("error", err.Error()))
+			continue
+		}
+
+		if event.Type == RealtimeTranscriptionClientEventTypeFinish {
+			return nil, io.EOF
+		}
+	}
+}
+
+func (c *RealtimeTranscriptionClient) Send(event RealtimeTranscriptionEvent) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.conn.WriteJSON(event)
+}
+
+// SendError sends the error event, errors other than *apierrors.Error are sent as internal errors.
+func (c *RealtimeTranscriptionClient) SendError(err error) {
+	var apiErr *apierrors.Error
+
+	if !errors.As(err, &apiErr) {
+		apiErr = apierrors.NewErrInternal().WithError(err)
+	}
+
+	slog.Error("error occurred during realtime transcription", slog.Any("error", apiErr.Error()))
+
+	_ = c.Send(RealtimeTranscriptionEvent{
+		Type:  RealtimeTranscriptionEventTypeError,
+		Error: apiErr.ErrorObject,
+	})
+}
+
+func (c *RealtimeTranscriptionClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	return c.conn.Close()
+}
+
+// RealtimeTranscriptionUpstream bridges the streaming recognition protocol of a provider.
+type RealtimeTranscriptionUpstream interface {
+	// SendAudio forwards an audio frame received from the client.
+	SendAudio(audio []byte) error
+	// Finish tells the upstream that no more audio will come.
+	Finish() error
+	// Receive blocks until the next events are available, io.EOF is returned once the upstream finished.
+	Receive() ([]RealtimeTranscriptionEvent, error)
+	Close() error
+}
+
+// ServeRealtimeTranscription upgrades the request and pipes audio and events between the client and the
+// connected upstream until either side finishes.
+func ServeRealtimeTranscription(c echo.Context, upstream RealtimeTranscriptionUpstream) mo.Result[any] {
+	defer func() { _ = upstream.Close() }()
+
+	clientResult := UpgradeRealtimeTranscriptionClient(c)
+	if clientResult.IsError() {
+		return mo.Err[any](clientResult.Error())
+	}
+
+	client := clientResult.MustGet()
+
+	defer func() { _ = client.Close() }()
+
+	go func() {
+		for {
+			audio, err := client.ReadAudio()
+			if errors.Is(err, io.EOF) {
+				err = upstream.Finish()
+				if err != nil {
+					_ = upstream.Close()
+				}
+
+				return
+			}
+			if err != nil {
+				// Client went away, closing the upstream will unblock Receive as well
+				_ = upstream.Close()
+				return
+			}
+
+			err = upstream.SendAudio(audio)
+			if err != nil {
+				_ = upstream.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		events, err := upstream.Receive()
+		if errors.Is(err, io.EOF) {
+			_ = client.Send(RealtimeTranscriptionEvent{Type: RealtimeTranscriptionEventTypeFinished})
+			break
+		}
+		if err != nil {
+			client.SendError(err)
+			break
+		}
+
+		for _, event := range events {
+			err = client.Send(event)
+			if err != nil {
+				return mo.Ok[any](nil)
+			}
+		}
+	}
+
+	return mo.Ok[any](nil)
+}
@@ -0,0 +1,347 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/jsonapi"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+type RealtimeSpeechEncoding = string
+
+const (
+	// Signed 16-bit little-endian linear PCM.
+	RealtimeSpeechEncodingPCM RealtimeSpeechEncoding = "pcm"
+	// G.711 μ-law.
+	RealtimeSpeechEncodingULaw RealtimeSpeechEncoding = "ulaw"
+	// G.711 A-law.
+	RealtimeSpeechEncodingALaw RealtimeSpeechEncoding = "alaw"
+)
+
+const (
+	defaultRealtimeSpeechSampleRate = 24000
+	// G.711 is meant for telephony, which is at 8 kHz.
+	defaultRealtimeSpeechG711SampleRate = 8000
+)
+
+type RealtimeSpeechRequestOptions struct {
+	Backend string `json:"provider"`
+	Model   string `json:"model"`
+	Voice   string `json:"voice"`
+
+	// Encoding of the binary audio frames sent back, either pcm, ulaw or alaw.
+	Encoding RealtimeSpeechEncoding `json:"encoding"`
+	// Sample rate of the audio sent back.
+	SampleRate int `json:"sample_rate"`
+
+	// Extension: provider specific options are passed through with query parameters.
+	ExtraQuery url.Values `json:"extra_query"`
+}
+
+// Authorization returns the API key of the upstream provider, see realtimeAuthorization.
+func (o RealtimeSpeechRequestOptions) Authorization(c echo.Context) string {
+	return realtimeAuthorization(c)
+}
+
+func NewRealtimeSpeechRequestOptions(request *http.Request) mo.Result[RealtimeSpeechRequestOptions] {
+	query := request.URL.Query()
+
+	provider := query.Get("provider")
+	if provider == "" {
+		return mo.Err[RealtimeSpeechRequestOptions](
+			apierrors.
+				NewErrInvalidArgument().
+				WithDetail("provider is required").
+				WithSourceParameter("provider"),
+		)
+	}
+
+	if query.Has("api_key") {
+		return mo.Err[RealtimeSpeechRequestOptions](errRealtimeAPIKeyInQuery())
+	}
+
+	options := RealtimeSpeechRequestOptions{
+		Backend:    provider,
+		Model:      query.Get("model"),
+		Voice:      query.Get("voice"),
+		Encoding:   lo.CoalesceOrEmpty(query.Get("encoding"), RealtimeSpeechEncodingPCM),
+		ExtraQuery: query,
+	}
+
+	options.SampleRate = lo.Ternary(
+		options.Encoding == RealtimeSpeechEncodingPCM,
+		defaultRealtimeSpeechSampleRate,
+		defaultRealtimeSpeechG711SampleRate,
+	)
+
+	if !lo.Contains([]string{RealtimeSpeechEncodingPCM, RealtimeSpeechEncodingULaw, RealtimeSpeechEncodingALaw}, options.Encoding) {
+		return mo.Err[RealtimeSpeechRequestOptions](
+			apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported encoding, supported encodings are pcm, ulaw and alaw").
+				WithSourceParameter("encoding"),
+		)
+	}
+
+	if value := query.Get("sample_rate"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return mo.Err[RealtimeSpeechRequestOptions](
+				apierrors.
+					NewErrInvalidArgument().
+					WithDetail("sample_rate must be a positive integer").
+					WithSourceParameter("sample_rate"),
+			)
+		}
+
+		options.SampleRate = parsed
+	}
+
+	return mo.Ok(options)
+}
+
+type RealtimeSpeechEventType = string
+
+const (
+	// All text sent before the flush has been synthesized.
+	RealtimeSpeechEventTypeFlushed RealtimeSpeechEventType = "flushed"
+	// Pending text and audio have been discarded, no audio of the text sent before the clear follows.
+	RealtimeSpeechEventTypeCleared RealtimeSpeechEventType = "cleared"
+	// Something went wrong, the connection will be closed afterwards.
+	RealtimeSpeechEventTypeError RealtimeSpeechEventType = "error"
+	// All text has been synthesized, the connection will be closed afterwards.
+	RealtimeSpeechEventTypeFinished RealtimeSpeechEventType = "finished"
+)
+
+type RealtimeSpeechEvent struct {
+	Type  RealtimeSpeechEventType `json:"type"`
+	Error *jsonapi.ErrorObject    `json:"error,omitempty"`
+}
+
+// RealtimeSpeechMessage is either a chunk of synthesized audio or an event.
+type RealtimeSpeechMessage struct {
+	Audio []byte
+	Event *RealtimeSpeechEvent
+}
+
+type RealtimeSpeechClientEventType = string
+
+const (
+	// Text to synthesize, it may be sent in pieces as it's generated.
+	RealtimeSpeechClientEventTypeSpeak RealtimeSpeechClientEventType = "speak"
+	// Synthesize the text buffered so far without waiting for more.
+	RealtimeSpeechClientEventTypeFlush RealtimeSpeechClientEventType = "flush"
+	// Discard the text buffered and the audio not sent yet, e.g. when the user barges in.
+	RealtimeSpeechClientEventTypeClear RealtimeSpeechClientEventType = "clear"
+	// Sent by the client once all text has been sent.
+	RealtimeSpeechClientEventTypeFinish RealtimeSpeechClientEventType = "finish"
+)
+
+type RealtimeSpeechClientEvent struct {
+	Type RealtimeSpeechClientEventType `json:"type"`
+	Text string                        `json:"text,omitempty"`
+}
+
+// RealtimeSpeechClient is the downstream WebSocket connection, RealtimeSpeechClientEvent come in as text
+// frames, audio is sent back as binary frames and RealtimeSpeechEvent as text frames.
+type RealtimeSpeechClient struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+// UpgradeRealtimeSpeechClient upgrades the request to WebSocket, it should be called after the upstream
+// connection is established so that upstream errors can still be responded as HTTP errors.
+func UpgradeRealtimeSpeechClient(c echo.Context) mo.Result[*RealtimeSpeechClient] {
+	conn, err := realtimeUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return mo.Err[*RealtimeSpeechClient](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	return mo.Ok(&RealtimeSpeechClient{conn: conn})
+}
+
+// ReadEvent reads the next event, io.EOF is returned once the client went away.
+func (c *RealtimeSpeechClient) ReadEvent() (RealtimeSpeechClientEvent, error) {
+	for {
+		messageType, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return RealtimeSpeechClientEvent{}, io.EOF
+			}
+
+			return RealtimeSpeechClientEvent{}, err
+		}
+
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		var event RealtimeSpeechClientEvent
+
+		err = json.Unmarshal(message, &event)
+		if err != nil {
+			slog.Warn("unknown text frame received from realtime speech client", slog.String("error", err.Error()))
+			continue
+		}
+
+		return event, nil
+	}
+}
+
+func (c *RealtimeSpeechClient) SendAudio(audio []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.conn.WriteMessage(websocket.BinaryMessage, audio)
+}
+
+func (c *RealtimeSpeechClient) Send(event RealtimeSpeechEvent) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.conn.WriteJSON(event)
+}
+
+// SendError sends the error event, errors other than *apierrors.Error are sent as internal errors.
+func (c *RealtimeSpeechClient) SendError(err error) {
+	var apiErr *apierrors.Error
+
+	if !errors.As(err, &apiErr) {
+		apiErr = apierrors.NewErrInternal().WithError(err)
+	}
+
+	slog.Error("error occurred during realtime speech", slog.Any("error", apiErr.Error()))
+
+	_ = c.Send(RealtimeSpeechEvent{
+		Type:  RealtimeSpeechEventTypeError,
+		Error: apiErr.ErrorObject,
+	})
+}
+
+func (c *RealtimeSpeechClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	return c.conn.Close()
+}
+
+// RealtimeSpeechUpstream bridges the streaming speech synthesis protocol of a provider.
+type RealtimeSpeechUpstream interface {
+	Speak(text string) error
+	Flush() error
+	Clear() error
+	// Finish tells the upstream that no more text will come.
+	Finish() error
+	// Receive blocks until the next message is available, io.EOF is returned once the upstream finished.
+	Receive() (RealtimeSpeechMessage, error)
+	Close() error
+}
+
+func forwardRealtimeSpeechClientEvent(upstream RealtimeSpeechUpstream, event RealtimeSpeechClientEvent) error {
+	switch event.Type {
+	case RealtimeSpeechClientEventTypeSpeak:
+		return upstream.Speak(event.Text)
+	case RealtimeSpeechClientEventTypeFlush:
+		return upstream.Flush()
+	case RealtimeSpeechClientEventTypeClear:
+		return upstream.Clear()
+	case RealtimeSpeechClientEventTypeFinish:
+		return upstream.Finish()
+	default:
+		slog.Warn("unknown event received from realtime speech client", slog.String("type", event.Type))
+		return nil
+	}
+}
+
+// ServeRealtimeSpeech upgrades the request and pipes text, audio and events between the client and the
+// connected upstream until either side finishes.
+//
+// Audio received between a clear and the cleared event of the upstream is dropped, as it was already on its
+// way before the upstream discarded what's pending.
+func ServeRealtimeSpeech(c echo.Context, upstream RealtimeSpeechUpstream) mo.Result[any] {
+	defer func() { _ = upstream.Close() }()
+
+	clientResult := UpgradeRealtimeSpeechClient(c)
+	if clientResult.IsError() {
+		return mo.Err[any](clientResult.Error())
+	}
+
+	client := clientResult.MustGet()
+
+	defer func() { _ = client.Close() }()
+
+	// Number of clears forwarded to the upstream and not yet acknowledged with the cleared event.
+	var pendingClears atomic.Int32
+
+	go func() {
+		for {
+			event, err := client.ReadEvent()
+			if err != nil {
+				// Client went away, closing the upstream will unblock Receive as well
+				_ = upstream.Close()
+				return
+			}
+
+			if event.Type == RealtimeSpeechClientEventTypeClear {
+				pendingClears.Add(1)
+			}
+
+			err = forwardRealtimeSpeechClientEvent(upstream, event)
+			if err != nil {
+				_ = upstream.Close()
+				return
+			}
+
+			if event.Type == RealtimeSpeechClientEventTypeFinish {
+				return
+			}
+		}
+	}()
+
+	for {
+		message, err := upstream.Receive()
+		if errors.Is(err, io.EOF) {
+			_ = client.Send(RealtimeSpeechEvent{Type: RealtimeSpeechEventTypeFinished})
+			break
+		}
+		if err != nil {
+			client.SendError(err)
+			break
+		}
+
+		switch {
+		case message.Event == nil && pendingClears.Load() > 0:
+			continue
+		case message.Event == nil:
+			err = client.SendAudio(message.Audio)
+		case message.Event.Type == RealtimeSpeechEventTypeCleared:
+			if pendingClears.Load() > 0 {
+				pendingClears.Add(-1)
+			}
+
+			err = client.Send(*message.Event)
+		default:
+			err = client.Send(*message.Event)
+		}
+
+		if err != nil {
+			return mo.Ok[any](nil)
+		}
+	}
+
+	return mo.Ok[any](nil)
+}
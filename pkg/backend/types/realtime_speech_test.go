@@ -0,0 +1,120 @@
+package types
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRealtimeSpeechUpstream sends audio of the text already synthesized right after a clear, as what's
+// in flight when the clear arrives.
+type fakeRealtimeSpeechUpstream struct {
+	messages chan RealtimeSpeechMessage
+}
+
+func (u *fakeRealtimeSpeechUpstream) Speak(string) error { return nil }
+func (u *fakeRealtimeSpeechUpstream) Flush() error       { return nil }
+func (u *fakeRealtimeSpeechUpstream) Finish() error      { return nil }
+func (u *fakeRealtimeSpeechUpstream) Close() error       { return nil }
+
+func (u *fakeRealtimeSpeechUpstream) Clear() error {
+	u.messages <- RealtimeSpeechMessage{Audio: []byte("stale")}
+	u.messages <- RealtimeSpeechMessage{Event: &RealtimeSpeechEvent{Type: RealtimeSpeechEventTypeCleared}}
+	u.messages <- RealtimeSpeechMessage{Audio: []byte("fresh")}
+	close(u.messages)
+
+	return nil
+}
+
+func (u *fakeRealtimeSpeechUpstream) Receive() (RealtimeSpeechMessage, error) {
+	message, ok := <-u.messages
+	if !ok {
+		return RealtimeSpeechMessage{}, io.EOF
+	}
+
+	return message, nil
+}
+
+func TestServeRealtimeSpeechClear(t *testing.T) {
+	t.Parallel()
+
+	upstream := &fakeRealtimeSpeechUpstream{messages: make(chan RealtimeSpeechMessage, 4)}
+	upstream.messages <- RealtimeSpeechMessage{Audio: []byte("before")}
+
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		return ServeRealtimeSpeech(c, upstream).Error()
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	messageType, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, "before", string(message))
+
+	require.NoError(t, conn.WriteJSON(RealtimeSpeechClientEvent{Type: RealtimeSpeechClientEventTypeClear}))
+
+	var event RealtimeSpeechEvent
+
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, RealtimeSpeechEventTypeCleared, event.Type)
+
+	messageType, message, err = conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, "fresh", string(message))
+
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, RealtimeSpeechEventTypeFinished, event.Type)
+}
+
+func TestNewRealtimeSpeechRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	for query, sampleRate := range map[string]int{
+		"provider=deepgram":                                 24000,
+		"provider=deepgram&encoding=ulaw":                   8000,
+		"provider=deepgram&encoding=alaw":                   8000,
+		"provider=deepgram&encoding=ulaw&sample_rate=16000": 16000,
+	} {
+		request := httptest.NewRequest("GET", "/?"+query, nil)
+
+		options := NewRealtimeSpeechRequestOptions(request)
+		require.NoError(t, options.Error(), query)
+		assert.Equal(t, sampleRate, options.MustGet().SampleRate, query)
+	}
+
+	request := httptest.NewRequest("GET", "/?"+url.Values{"provider": {"deepgram"}, "api_key": {"secret"}}.Encode(), nil)
+	assert.True(t, NewRealtimeSpeechRequestOptions(request).IsError())
+}
+
+func TestRealtimeAuthorization(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", "Bearer header-key")
+	assert.Equal(t, "header-key", realtimeAuthorization(e.NewContext(request, httptest.NewRecorder())))
+
+	request = httptest.NewRequest("GET", "/?api_key=query-key", nil)
+	request.Header.Set("Sec-WebSocket-Protocol", RealtimeSubprotocol+", "+RealtimeAPIKeySubprotocolPrefix+"subprotocol-key")
+	assert.Equal(t, "subprotocol-key", realtimeAuthorization(e.NewContext(request, httptest.NewRecorder())))
+
+	request = httptest.NewRequest("GET", "/?api_key=query-key", nil)
+	assert.Empty(t, realtimeAuthorization(e.NewContext(request, httptest.NewRecorder())))
+}
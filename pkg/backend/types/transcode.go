@@ -0,0 +1,62 @@
+package types
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/mo"
+)
+
+// CheckOutput fails when the sample rate or channels asked for would need converting audio the provider
+// synthesized in a format unSpeech can't encode, e.g. mp3 or opus.
+func CheckOutput(from audio.Spec, to audio.Format, output AudioOutput) error {
+	if from.Matches(output.Spec(to)) || audio.CanEncode(to) {
+		return nil
+	}
+
+	return apierrors.
+		NewErrInvalidArgument().
+		WithDetailf("sample_rate and channels are not supported with response_format %s by this backend, use one of %v instead", to, audio.EncodableFormats()).
+		WithSourcePointer("/response_format")
+}
+
+// RespondTranscoded responds with the audio synthesized by the provider converted into the requested format,
+// sample rate and channels.
+func RespondTranscoded(c echo.Context, data []byte, from audio.Spec, to audio.Format, output AudioOutput) mo.Result[any] {
+	err := CheckOutput(from, to, output)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	out, err := audio.Transcode(data, from, output.Spec(to), output.Quality())
+	if err != nil {
+		return mo.Err[any](apierrors.
+			NewErrBadGateway().
+			WithDetailf("failed to transcode upstream audio from %s to %s: %s", from.Format, to, err.Error()).
+			WithCaller())
+	}
+
+	return mo.Ok[any](c.Blob(http.StatusOK, audio.ContentType(to), out))
+}
+
+// StreamTranscoded relays raw audio streamed by the provider, resampled and remixed on the fly when the
+// sample rate or channels asked for differ from the synthesized ones.
+func StreamTranscoded(c echo.Context, body io.Reader, from audio.Spec, to audio.Format, output AudioOutput) mo.Result[any] {
+	if from.Matches(output.Spec(to)) {
+		return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, audio.ContentType(to), body))
+	}
+
+	reader, err := audio.NewConvertReader(body, from, output.Spec(to), output.Quality())
+	if err != nil {
+		return mo.Err[any](apierrors.
+			NewErrInternal().
+			WithDetailf("failed to convert upstream audio from %s to %s: %s", from.Format, to, err.Error()).
+			WithCaller())
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, audio.ContentType(to), reader))
+}
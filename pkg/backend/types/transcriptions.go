@@ -0,0 +1,363 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	// Maximum bytes of the uploaded audio kept in memory, the rest is stored in temporary files.
+	transcriptionMaxMemory = 32 << 20
+)
+
+type TranscriptionResponseFormat = string
+
+const (
+	TranscriptionResponseFormatJSON        TranscriptionResponseFormat = "json"
+	TranscriptionResponseFormatText        TranscriptionResponseFormat = "text"
+	TranscriptionResponseFormatSRT         TranscriptionResponseFormat = "srt"
+	TranscriptionResponseFormatVerboseJSON TranscriptionResponseFormat = "verbose_json"
+	TranscriptionResponseFormatVTT         TranscriptionResponseFormat = "vtt"
+)
+
+var transcriptionResponseFormats = []TranscriptionResponseFormat{
+	TranscriptionResponseFormatJSON,
+	TranscriptionResponseFormatText,
+	TranscriptionResponseFormatSRT,
+	TranscriptionResponseFormatVerboseJSON,
+	TranscriptionResponseFormatVTT,
+}
+
+func errUnsupportedTranscriptionResponseFormat(responseFormat TranscriptionResponseFormat) *apierrors.Error {
+	return apierrors.
+		NewErrInvalidArgument().
+		WithDetailf("unsupported response_format %s, supported formats are %s", responseFormat, strings.Join(transcriptionResponseFormats, ", ")).
+		WithSourceParameter("response_format")
+}
+
+// OpenAITranscriptionRequestOptions represent API parameters refer to https://platform.openai.com/docs/api-reference/audio/createTranscription
+type OpenAITranscriptionRequestOptions struct {
+	// (required) ID of the model to use.
+	Model string `json:"model"`
+
+	// The language of the input audio in ISO-639-1 format.
+	Language string `json:"language,omitempty"`
+	// An optional text to guide the model's style or continue a previous audio segment.
+	Prompt string `json:"prompt,omitempty"`
+	// The format of the output, in one of these options: json, text, srt, verbose_json, or vtt.
+	// json is the default.
+	ResponseFormat TranscriptionResponseFormat `json:"response_format,omitempty"`
+	// The sampling temperature, between 0 and 1.
+	Temperature float64 `json:"temperature,omitempty"`
+	// The timestamp granularities to populate for this transcription, either or both of word
+	// and segment, response_format must be set to verbose_json to use timestamp granularities.
+	TimestampGranularities []string `json:"timestamp_granularities,omitempty"`
+
+	// Extension: allows you to add custom content to body, since multipart form can't carry nested
+	// objects, it's expected to be a JSON encoded string.
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+type TranscriptionRequestOptions struct {
+	OpenAITranscriptionRequestOptions
+
+	Backend string `json:"backend"`
+	Model   string `json:"model"`
+
+	File *multipart.FileHeader `json:"-"`
+}
+
+// ReadFile reads the whole uploaded audio file.
+func (o TranscriptionRequestOptions) ReadFile() ([]byte, error) {
+	file, err := o.File.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return io.ReadAll(file)
+}
+
+// FileContentType returns the Content-Type of the uploaded audio file as declared by the client.
+func (o TranscriptionRequestOptions) FileContentType() string {
+	return o.File.Header.Get("Content-Type")
+}
+
+func NewTranscriptionRequestOptions(request *http.Request) mo.Result[TranscriptionRequestOptions] {
+	err := request.ParseMultipartForm(transcriptionMaxMemory)
+	if err != nil {
+		return mo.Err[TranscriptionRequestOptions](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	form := request.MultipartForm
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return mo.Err[TranscriptionRequestOptions](apierrors.
+			NewErrRequiredFieldMissing().
+			WithDetail("file is required").
+			WithSourceParameter("file"))
+	}
+
+	value := func(key string) string {
+		return lo.FirstOr(form.Value[key], "")
+	}
+
+	options := OpenAITranscriptionRequestOptions{
+		Model:                  value("model"),
+		Language:               value("language"),
+		Prompt:                 value("prompt"),
+		ResponseFormat:         lo.CoalesceOrEmpty(value("response_format"), TranscriptionResponseFormatJSON),
+		TimestampGranularities: append(form.Value["timestamp_granularities[]"], form.Value["timestamp_granularities"]...),
+	}
+
+	if options.Model == "" {
+		return mo.Err[TranscriptionRequestOptions](apierrors.
+			NewErrRequiredFieldMissing().
+			WithDetail("model is required").
+			WithSourceParameter("model"))
+	}
+
+	if !lo.Contains(transcriptionResponseFormats, options.ResponseFormat) {
+		return mo.Err[TranscriptionRequestOptions](errUnsupportedTranscriptionResponseFormat(options.ResponseFormat))
+	}
+
+	if temperature := value("temperature"); temperature != "" {
+		options.Temperature, err = strconv.ParseFloat(temperature, 64)
+		if err != nil {
+			return mo.Err[TranscriptionRequestOptions](apierrors.
+				NewErrInvalidArgument().
+				WithDetail(err.Error()).
+				WithSourceParameter("temperature"))
+		}
+	}
+
+	if extraBody := value("extra_body"); extraBody != "" {
+		err = json.Unmarshal([]byte(extraBody), &options.ExtraBody)
+		if err != nil {
+			return mo.Err[TranscriptionRequestOptions](apierrors.
+				NewErrInvalidArgument().
+				WithDetail(err.Error()).
+				WithSourceParameter("extra_body"))
+		}
+	}
+
+	backendAndModel := lo.Ternary(
+		strings.Contains(options.Model, "/"),
+		strings.SplitN(options.Model, "/", 2), //nolint:mnd
+		[]string{options.Model, ""},
+	)
+
+	return mo.Ok(TranscriptionRequestOptions{
+		OpenAITranscriptionRequestOptions: options,
+		Backend:                           backendAndModel[0],
+		Model:                             backendAndModel[1],
+		File:                              files[0],
+	})
+}
+
+type TranscriptionWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+
+	// Extension: speaker of the word when diarization is available.
+	Speaker string `json:"speaker,omitempty"`
+}
+
+type TranscriptionSegment struct {
+	ID               int     `json:"id"`
+	Seek             int     `json:"seek"`
+	Start            float64 `json:"start"`
+	End              float64 `json:"end"`
+	Text             string  `json:"text"`
+	Tokens           []int   `json:"tokens"`
+	Temperature      float64 `json:"temperature"`
+	AvgLogprob       float64 `json:"avg_logprob"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	NoSpeechProb     float64 `json:"no_speech_prob"`
+
+	// Extension: speaker of the segment when diarization is available.
+	Speaker string `json:"speaker,omitempty"`
+}
+
+type TranscriptionResponse struct {
+	Text string `json:"text"`
+}
+
+type VerboseTranscriptionResponse struct {
+	Task     string                 `json:"task"`
+	Language string                 `json:"language"`
+	Duration float64                `json:"duration"`
+	Text     string                 `json:"text"`
+	Segments []TranscriptionSegment `json:"segments"`
+	Words    []TranscriptionWord    `json:"words,omitempty"`
+}
+
+func formatTimestamp(seconds float64, separator string) string {
+	milliseconds := int64(seconds * 1000) //nolint:mnd
+
+	return fmt.Sprintf(
+		"%02d:%02d:%02d%s%03d",
+		milliseconds/3600000,  //nolint:mnd
+		milliseconds/60000%60, //nolint:mnd
+		milliseconds/1000%60,  //nolint:mnd
+		separator,
+		milliseconds%1000, //nolint:mnd
+	)
+}
+
+// SRT renders the segments as SubRip subtitles.
+func (r VerboseTranscriptionResponse) SRT() string {
+	var sb strings.Builder
+
+	for i, segment := range r.Segments {
+		_, _ = fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n",
+			i+1,
+			formatTimestamp(segment.Start, ","),
+			formatTimestamp(segment.End, ","),
+			strings.TrimSpace(segment.Text),
+		)
+	}
+
+	return sb.String()
+}
+
+// VTT renders the segments as WebVTT subtitles.
+func (r VerboseTranscriptionResponse) VTT() string {
+	var sb strings.Builder
+
+	sb.WriteString("WEBVTT\n\n")
+
+	for _, segment := range r.Segments {
+		_, _ = fmt.Fprintf(&sb, "%s --> %s\n%s\n\n",
+			formatTimestamp(segment.Start, "."),
+			formatTimestamp(segment.End, "."),
+			strings.TrimSpace(segment.Text),
+		)
+	}
+
+	return sb.String()
+}
+
+// WriteTranscription responds the transcription result in the requested response_format, unknown ones are
+// rejected.
+func WriteTranscription(c echo.Context, responseFormat TranscriptionResponseFormat, result VerboseTranscriptionResponse) mo.Result[any] {
+	if result.Task == "" {
+		result.Task = "transcribe"
+	}
+	if result.Segments == nil {
+		result.Segments = make([]TranscriptionSegment, 0)
+	}
+
+	switch responseFormat {
+	case TranscriptionResponseFormatJSON, "":
+		return mo.Ok[any](c.JSON(http.StatusOK, TranscriptionResponse{Text: result.Text}))
+	case TranscriptionResponseFormatText:
+		return mo.Ok[any](c.String(http.StatusOK, result.Text))
+	case TranscriptionResponseFormatSRT:
+		return mo.Ok[any](c.Blob(http.StatusOK, "application/x-subrip", []byte(result.SRT())))
+	case TranscriptionResponseFormatVTT:
+		return mo.Ok[any](c.Blob(http.StatusOK, "text/vtt", []byte(result.VTT())))
+	case TranscriptionResponseFormatVerboseJSON:
+		return mo.Ok[any](c.JSON(http.StatusOK, result))
+	default:
+		return mo.Err[any](errUnsupportedTranscriptionResponseFormat(responseFormat))
+	}
+}
+
+type TranscriptionJobStatus = string
+
+const (
+	TranscriptionJobStatusQueued    TranscriptionJobStatus = "queued"
+	TranscriptionJobStatusRunning   TranscriptionJobStatus = "running"
+	TranscriptionJobStatusSucceeded TranscriptionJobStatus = "succeeded"
+	TranscriptionJobStatusFailed    TranscriptionJobStatus = "failed"
+)
+
+type TranscriptionJob struct {
+	// ID of the job, it's the ID assigned by the upstream and is needed for polling the job.
+	ID        string                 `json:"id"`
+	Provider  string                 `json:"provider"`
+	Status    TranscriptionJobStatus `json:"status"`
+	CreatedAt string                 `json:"created_at,omitempty"`
+	// Error message when the job failed.
+	Error string `json:"error,omitempty"`
+	// Results of each of the audio files, only present when the job succeeded.
+	Results []VerboseTranscriptionResponse `json:"results,omitempty"`
+}
+
+type TranscriptionJobRequestOptions struct {
+	// (required) ID of the model to use, same as /v1/audio/transcriptions, e.g. microsoft/batch.
+	Model string `json:"model"`
+	// (required) URLs of the audio files to transcribe, batch transcription APIs fetch the audio by themselves.
+	ContentURLs []string `json:"content_urls"`
+	// The language of the input audio.
+	Language string `json:"language,omitempty"`
+
+	// Extension: allows you to add custom content to body.
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+
+	Backend string `json:"-"`
+}
+
+func NewTranscriptionJobRequestOptions(body io.ReadCloser) mo.Result[TranscriptionJobRequestOptions] {
+	var options TranscriptionJobRequestOptions
+
+	err := json.NewDecoder(body).Decode(&options)
+	if err != nil {
+		return mo.Err[TranscriptionJobRequestOptions](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	if options.Model == "" || len(options.ContentURLs) == 0 {
+		return mo.Err[TranscriptionJobRequestOptions](apierrors.NewErrInvalidArgument().WithDetail("either one of model, and content_urls parameter is required"))
+	}
+
+	backendAndModel := lo.Ternary(
+		strings.Contains(options.Model, "/"),
+		strings.SplitN(options.Model, "/", 2), //nolint:mnd
+		[]string{options.Model, ""},
+	)
+
+	options.Backend = backendAndModel[0]
+	options.Model = backendAndModel[1]
+
+	return mo.Ok(options)
+}
+
+type TranscriptionJobQueryOptions struct {
+	ID      string `json:"id"`
+	Backend string `json:"provider"`
+
+	ExtraQuery url.Values `json:"extra_query"`
+}
+
+func NewTranscriptionJobQueryOptions(c echo.Context) mo.Result[TranscriptionJobQueryOptions] {
+	provider := c.QueryParam("provider")
+	if provider == "" {
+		return mo.Err[TranscriptionJobQueryOptions](
+			apierrors.
+				NewErrInvalidArgument().
+				WithDetail("provider is required").
+				WithSourceParameter("provider"),
+		)
+	}
+
+	return mo.Ok(TranscriptionJobQueryOptions{
+		ID:         c.Param("id"),
+		Backend:    provider,
+		ExtraQuery: c.QueryParams(),
+	})
+}
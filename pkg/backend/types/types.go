@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/utils/jsonpatch"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
@@ -27,7 +28,7 @@ type OpenAISpeechRequestOptions struct {
 	// The speed of the generated audio.
 	// Select a value from 0.25 to 4.0.
 	// 1.0 is the default.
-	Speed int `json:"speed,omitempty"`
+	Speed float64 `json:"speed,omitempty"`
 
 	// Extension: allows you to add custom content to body.
 	ExtraBody map[string]any `json:"extra_body,omitempty"`
@@ -38,6 +39,10 @@ type SpeechRequestOptions struct {
 
 	Backend string `json:"backend"`
 	Model   string `json:"model"`
+	// Speed along with the pitch and volume extensions, mapped by every backend onto its own scale.
+	Prosody Prosody `json:"prosody"`
+	// Sample rate and channels of the audio, converted when the provider can't produce them.
+	Output AudioOutput `json:"output"`
 
 	body          mo.Option[*bytes.Buffer]
 	bodyParsedMap map[string]any
@@ -77,6 +82,30 @@ func NewSpeechRequestOptions(body io.ReadCloser) mo.Result[SpeechRequestOptions]
 		return mo.Err[SpeechRequestOptions](apierrors.NewErrInvalidArgument().WithDetail("either one of model, input, and voice parameter is required"))
 	}
 
+	var prosody Prosody
+
+	err = json.Unmarshal(buffer.Bytes(), &prosody)
+	if err != nil {
+		return mo.Err[SpeechRequestOptions](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	err = prosody.Validate(defaultProsodyRanges)
+	if err != nil {
+		return mo.Err[SpeechRequestOptions](err)
+	}
+
+	var output AudioOutput
+
+	err = json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		return mo.Err[SpeechRequestOptions](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	err = output.Validate()
+	if err != nil {
+		return mo.Err[SpeechRequestOptions](err)
+	}
+
 	backendAndModel := lo.Ternary(
 		strings.Contains(options.Model, "/"),
 		strings.SplitN(options.Model, "/", 2), //nolint:mnd
@@ -87,7 +116,37 @@ func NewSpeechRequestOptions(body io.ReadCloser) mo.Result[SpeechRequestOptions]
 		OpenAISpeechRequestOptions: options,
 		Backend:                    backendAndModel[0],
 		Model:                      backendAndModel[1],
+		Prosody:                    prosody,
+		Output:                     output,
 		body:                       mo.Some(buffer),
 		bodyParsedMap:              optionsMap,
 	})
 }
+
+// WithInput returns a copy of the options with input replaced in both the parsed fields and the body,
+// e.g. for a chunk of long text synthesized on its own.
+func (o SpeechRequestOptions) WithInput(input string) mo.Result[SpeechRequestOptions] {
+	o.Input = input
+
+	return o.withBodyValue("input", input)
+}
+
+// WithResponseFormat returns a copy of the options with response_format replaced in both the parsed
+// fields and the body.
+func (o SpeechRequestOptions) WithResponseFormat(format string) mo.Result[SpeechRequestOptions] {
+	o.ResponseFormat = format
+
+	return o.withBodyValue("response_format", format)
+}
+
+func (o SpeechRequestOptions) withBodyValue(key string, value any) mo.Result[SpeechRequestOptions] {
+	patched := jsonpatch.ApplyPatches(o.AsBuffer().OrElse(new(bytes.Buffer)).Bytes(), mo.None[jsonpatch.ApplyOptions](), jsonpatch.NewAdd("/"+key, value))
+	if patched.IsError() {
+		return mo.Err[SpeechRequestOptions](apierrors.NewErrInternal().WithDetail(patched.Error().Error()).WithCaller())
+	}
+
+	o.body = mo.Some(bytes.NewBuffer(patched.MustGet()))
+	o.bodyParsedMap = lo.Assign(o.bodyParsedMap, map[string]any{key: value})
+
+	return mo.Ok(o)
+}
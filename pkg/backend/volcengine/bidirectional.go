@@ -0,0 +1,326 @@
+package volcengine
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	// 豆包语音合成模型 1.0, billed by characters.
+	defaultBidirectionalSpeechResourceID = "volc.service_type.10029"
+	// Speaker of the mixed voice, the actual voices are described by mix_speaker.
+	mixSpeaker = "custom_mix_bigtts"
+
+	bidirectionalSpeechNamespace   = "BidirectionalTTS"
+	defaultBidirectionalSampleRate = 24000
+)
+
+// speech_rate and loudness_rate range from -50 to 100, i.e. 0.5x to 2.0x.
+var bidirectionalProsodyRanges = types.ProsodyRanges{
+	Speed:  mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+	Volume: mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+}
+
+type bidirectionalFormat struct {
+	Format      string
+	ContentType string
+	// Synthesized as pcm and encoded once finished, since the length of the audio is unknown until then.
+	Buffered bool
+}
+
+// WebSocket 双向流式-V3--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1329505#audio-params
+var bidirectionalFormats = map[string]bidirectionalFormat{
+	"mp3":  {Format: "mp3", ContentType: "audio/mpeg"},
+	"opus": {Format: "ogg_opus", ContentType: "audio/ogg"},
+	"pcm":  {Format: "pcm", ContentType: "audio/pcm"},
+	"wav":  {Format: "pcm", ContentType: "audio/wav", Buffered: true},
+	"flac": {Format: "pcm", ContentType: "audio/flac", Buffered: true},
+	"ulaw": {Format: "pcm", ContentType: "audio/basic", Buffered: true},
+	"alaw": {Format: "pcm", ContentType: "audio/x-alaw-basic", Buffered: true},
+}
+
+// Sample rates the v3 API synthesizes at, always in mono.
+var bidirectionalSampleRates = []int{8000, 16000, 22050, 24000, 32000, 44100, 48000} //nolint:mnd
+
+type BidirectionalSpeechAudioParams struct {
+	Format       string  `json:"format"`
+	SampleRate   int     `json:"sample_rate"`
+	SpeechRate   int     `json:"speech_rate,omitempty"`
+	LoudnessRate int     `json:"loudness_rate,omitempty"`
+	Emotion      string  `json:"emotion,omitempty"`
+	EmotionScale float64 `json:"emotion_scale,omitempty"`
+}
+
+type BidirectionalSpeechParams struct {
+	Text        string                         `json:"text,omitempty"`
+	Speaker     string                         `json:"speaker"`
+	AudioParams BidirectionalSpeechAudioParams `json:"audio_params"`
+	// JSON encoded string of additional options, e.g. disable_markdown_filter, context_texts, etc.
+	Additions  string         `json:"additions,omitempty"`
+	MixSpeaker map[string]any `json:"mix_speaker,omitempty"`
+}
+
+type BidirectionalSpeechRequest struct {
+	User      SpeechRequestOptionsUser  `json:"user"`
+	Event     Event                     `json:"event"`
+	Namespace string                    `json:"namespace"`
+	ReqParams BidirectionalSpeechParams `json:"req_params"`
+}
+
+type bidirectionalFailure struct {
+	StatusCode int    `json:"status_code"`
+	Message    string `json:"message"`
+}
+
+// ratioToRate converts ratios like speed_ratio of the v1 API, where 1.0 is normal, to rates of the v3 API,
+// which range from -50 (0.5x) to 100 (2.0x) with 0 as normal.
+func ratioToRate(ratio float64) int {
+	if ratio == 0 {
+		return 0
+	}
+
+	return int(math.Round(lo.Clamp(ratio, 0.5, 2.0)*100)) - 100 //nolint:mnd
+}
+
+type bidirectionalSession struct {
+	conn      *websocket.Conn
+	sessionID string
+}
+
+func (s *bidirectionalSession) write(event Event, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return writeFrame(s.conn, Frame{
+		Type:          MessageTypeFullClientRequest,
+		Flags:         MessageFlagsWithEvent,
+		Serialization: SerializationJSON,
+		Compression:   CompressionNone,
+		Event:         event,
+		SessionID:     s.sessionID,
+		Payload:       data,
+	})
+}
+
+// readEvent reads the next frame, failures of the connection or the session are returned as errors.
+func (s *bidirectionalSession) readEvent() mo.Result[Frame] {
+	frame := readFrame(s.conn)
+	if frame.IsError() {
+		return frame
+	}
+
+	if frame.MustGet().Event == EventConnectionFailed || frame.MustGet().Event == EventSessionFailed {
+		var failure bidirectionalFailure
+
+		_ = json.Unmarshal(frame.MustGet().Payload, &failure)
+
+		return mo.Err[Frame](apierrors.
+			NewErrBadGateway().
+			WithDetailf("upstream returned error, event: %d, status_code: %d, message: %s", frame.MustGet().Event, failure.StatusCode, lo.CoalesceOrEmpty(failure.Message, string(frame.MustGet().Payload))))
+	}
+
+	return frame
+}
+
+// waitFor skips frames until the expected event arrives.
+func (s *bidirectionalSession) waitFor(event Event) mo.Result[Frame] {
+	for {
+		frame := s.readEvent()
+		if frame.IsError() || frame.MustGet().Event == event {
+			return frame
+		}
+	}
+}
+
+// pipeAudio writes the synthesized audio into w until the session finishes.
+func (s *bidirectionalSession) pipeAudio(w *io.PipeWriter) {
+	for {
+		frame := s.readEvent()
+		if frame.IsError() {
+			_ = w.CloseWithError(frame.Error())
+			return
+		}
+
+		switch frame.MustGet().Event {
+		case EventTTSResponse:
+			_, err := w.Write(frame.MustGet().Payload)
+			if err != nil {
+				return
+			}
+		case EventSessionFinished:
+			_ = s.write(EventFinishConnection, map[string]any{})
+			_ = w.Close()
+
+			return
+		default:
+			// TTSSentenceStart, TTSSentenceEnd, etc.
+			continue
+		}
+	}
+}
+
+func newBidirectionalSpeechParams(opts types.SpeechRequestOptions, format bidirectionalFormat) BidirectionalSpeechParams {
+	speedRatio := lo.CoalesceOrEmpty(utils.GetByJSONPath[float64](opts.ExtraBody, "{ .audio.speed_ratio }"), opts.Prosody.Speed.OrEmpty())
+	loudnessRatio := lo.CoalesceOrEmpty(utils.GetByJSONPath[float64](opts.ExtraBody, "{ .audio.loudness_ratio }"), opts.Prosody.Volume.OrEmpty())
+
+	params := BidirectionalSpeechParams{
+		Speaker: opts.Voice,
+		AudioParams: BidirectionalSpeechAudioParams{
+			Format:       format.Format,
+			SampleRate:   opts.Output.NativeSampleRate(bidirectionalSampleRates, defaultBidirectionalSampleRate),
+			SpeechRate:   ratioToRate(speedRatio),
+			LoudnessRate: ratioToRate(loudnessRatio),
+			Emotion:      utils.GetByJSONPath[string](opts.ExtraBody, "{ .audio.emotion }"),
+			EmotionScale: utils.GetByJSONPath[float64](opts.ExtraBody, "{ .audio.emotion_scale }"),
+		},
+		MixSpeaker: utils.GetByJSONPath[map[string]any](opts.ExtraBody, "{ .mix_speaker }"),
+	}
+
+	// 混音 mix_speaker: voices are mixed by their mix_factor, the speaker must be custom_mix_bigtts then.
+	if params.MixSpeaker != nil {
+		params.Speaker = mixSpeaker
+	}
+
+	// additions is accepted either as the JSON encoded string or as an object.
+	switch additions := opts.ExtraBody["additions"].(type) {
+	case nil:
+	case string:
+		params.Additions = additions
+	default:
+		encoded, err := json.Marshal(additions)
+		if err == nil {
+			params.Additions = string(encoded)
+		}
+	}
+
+	return params
+}
+
+// handleBidirectionalSpeech synthesizes speech over the v3 bidirectional WebSocket API, the text is sent
+// sentence by sentence and audio is streamed to the client as it's generated.
+//
+// WebSocket 双向流式-V3--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1329505
+func handleBidirectionalSpeech(c echo.Context, opts types.SpeechRequestOptions) mo.Result[any] {
+	responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, "mp3")
+
+	format, ok := bidirectionalFormats[responseFormat]
+	if !ok {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("unsupported response_format, supported formats are mp3, opus, pcm, wav, flac, ulaw and alaw").
+			WithSourcePointer("/response_format"))
+	}
+
+	userID := lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .user.uid }"), uuid.New().String())
+	params := newBidirectionalSpeechParams(opts, format)
+
+	// Other sample rates and stereo are converted from pcm, which can't be done for mp3 and opus.
+	synthesized := audio.Spec{Format: audio.FormatPCM, SampleRate: params.AudioParams.SampleRate, Channels: 1}
+
+	err := types.CheckOutput(audio.Spec{Format: responseFormat, SampleRate: synthesized.SampleRate, Channels: 1}, responseFormat, opts.Output)
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	connResult := dial(
+		c,
+		"wss://openspeech.bytedance.com/api/v3/tts/bidirection",
+		newAPIHeaders(
+			strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "),
+			utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.appid }"),
+			lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .resource_id }"), defaultBidirectionalSpeechResourceID),
+		),
+	)
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	session := &bidirectionalSession{conn: connResult.MustGet(), sessionID: uuid.New().String()}
+
+	defer func() { _ = session.conn.Close() }()
+
+	// Connection and session are started before responding, so that failures are still responded as
+	// HTTP errors.
+	err = session.write(EventStartConnection, map[string]any{})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	started := session.waitFor(EventConnectionStarted)
+	if started.IsError() {
+		return mo.Err[any](started.Error())
+	}
+
+	err = session.write(EventStartSession, BidirectionalSpeechRequest{
+		User:      SpeechRequestOptionsUser{UserID: userID},
+		Event:     EventStartSession,
+		Namespace: bidirectionalSpeechNamespace,
+		ReqParams: params,
+	})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	started = session.waitFor(EventSessionStarted)
+	if started.IsError() {
+		return mo.Err[any](started.Error())
+	}
+
+	for _, sentence := range utils.SplitSentences(opts.Input) {
+		task := params
+		task.Text = sentence
+
+		err = session.write(EventTaskRequest, BidirectionalSpeechRequest{
+			User:      SpeechRequestOptionsUser{UserID: userID},
+			Event:     EventTaskRequest,
+			Namespace: bidirectionalSpeechNamespace,
+			ReqParams: task,
+		})
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+	}
+
+	err = session.write(EventFinishSession, map[string]any{})
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	reader, writer := io.Pipe()
+
+	go session.pipeAudio(writer)
+
+	defer func() { _ = reader.Close() }()
+
+	if format.Buffered {
+		pcm, err := io.ReadAll(reader)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		return types.RespondTranscoded(c, pcm, synthesized, responseFormat, opts.Output)
+	}
+
+	if format.Format == audio.FormatPCM {
+		return types.StreamTranscoded(c, reader, synthesized, audio.FormatPCM, opts.Output)
+	}
+
+	return mo.Ok[any](utils.StreamWithFlush(c, http.StatusOK, format.ContentType, reader))
+}
@@ -0,0 +1,306 @@
+package volcengine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Binary protocol shared by the big-model streaming speech services.
+//
+// 大模型流式语音识别API--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1354869
+//
+// Each frame starts with a 4 bytes header:
+//
+//	| version (4 bits) | header size (4 bits) | message type (4 bits) | flags (4 bits) |
+//	| serialization (4 bits) | compression (4 bits) | reserved (8 bits) |
+//
+// followed by an optional 4 bytes sequence number, 4 bytes payload size, and the payload.
+//
+// Frames of the bidirectional speech synthesis service carry an event number and the ID of the
+// session or connection between the sequence number and the payload size.
+//
+// WebSocket 双向流式-V3--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1329505
+
+type MessageType byte
+
+const (
+	MessageTypeFullClientRequest  MessageType = 0b0001
+	MessageTypeAudioOnlyRequest   MessageType = 0b0010
+	MessageTypeFullServerResponse MessageType = 0b1001
+	MessageTypeServerAck          MessageType = 0b1011
+	// Same as MessageTypeServerAck, named after what the speech synthesis service uses it for.
+	MessageTypeAudioOnlyResponse MessageType = 0b1011
+	MessageTypeError             MessageType = 0b1111
+)
+
+type MessageFlags byte
+
+const (
+	MessageFlagsNone             MessageFlags = 0b0000
+	MessageFlagsPositiveSequence MessageFlags = 0b0001
+	MessageFlagsLast             MessageFlags = 0b0010
+	MessageFlagsNegativeSequence MessageFlags = 0b0011
+	MessageFlagsWithEvent        MessageFlags = 0b0100
+)
+
+type Event int32
+
+const (
+	EventStartConnection  Event = 1
+	EventFinishConnection Event = 2
+
+	EventConnectionStarted  Event = 50
+	EventConnectionFailed   Event = 51
+	EventConnectionFinished Event = 52
+
+	EventStartSession  Event = 100
+	EventCancelSession Event = 101
+	EventFinishSession Event = 102
+
+	EventSessionStarted  Event = 150
+	EventSessionCanceled Event = 151
+	EventSessionFinished Event = 152
+	EventSessionFailed   Event = 153
+
+	EventTaskRequest Event = 200
+
+	EventTTSSentenceStart Event = 350
+	EventTTSSentenceEnd   Event = 351
+	EventTTSResponse      Event = 352
+)
+
+// isConnectionLevel reports whether the event belongs to the connection instead of a session, such
+// frames don't carry a session ID.
+func (e Event) isConnectionLevel() bool {
+	return e == EventStartConnection ||
+		e == EventFinishConnection ||
+		e == EventConnectionStarted ||
+		e == EventConnectionFailed ||
+		e == EventConnectionFinished
+}
+
+// hasConnectID reports whether the frame of the event carries the connection ID.
+func (e Event) hasConnectID() bool {
+	return e == EventConnectionStarted || e == EventConnectionFailed || e == EventConnectionFinished
+}
+
+type Serialization byte
+
+const (
+	SerializationNone Serialization = 0b0000
+	SerializationJSON Serialization = 0b0001
+)
+
+type Compression byte
+
+const (
+	CompressionNone Compression = 0b0000
+	CompressionGzip Compression = 0b0001
+)
+
+const (
+	protocolVersion = 0b0001
+	headerSize      = 0b0001 // in 4 bytes
+)
+
+type Frame struct {
+	Type          MessageType
+	Flags         MessageFlags
+	Serialization Serialization
+	Compression   Compression
+
+	Sequence  int32
+	ErrorCode uint32
+	Payload   []byte
+
+	// Only present when the frame is flagged with MessageFlagsWithEvent.
+	Event     Event
+	SessionID string
+	ConnectID string
+}
+
+func (f Frame) hasSequence() bool {
+	return f.Flags&MessageFlagsPositiveSequence != 0
+}
+
+func (f Frame) hasEvent() bool {
+	return f.Flags&MessageFlagsWithEvent != 0
+}
+
+func readString(reader *bytes.Reader) (string, error) {
+	var size uint32
+
+	err := binary.Read(reader, binary.BigEndian, &size)
+	if err != nil {
+		return "", err
+	}
+
+	if int(size) > reader.Len() {
+		return "", errors.New("string size exceeds frame size")
+	}
+
+	data := make([]byte, size)
+	_, _ = reader.Read(data)
+
+	return string(data), nil
+}
+
+// IsLast reports whether the frame is the last one of the session.
+func (f Frame) IsLast() bool {
+	return f.Flags&MessageFlagsLast != 0
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	writer := gzip.NewWriter(buffer)
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = reader.Close() }()
+
+	return io.ReadAll(reader)
+}
+
+// MarshalBinary encodes the frame, the payload is compressed according to the Compression field.
+func (f Frame) MarshalBinary() ([]byte, error) {
+	payload := f.Payload
+
+	if f.Compression == CompressionGzip {
+		compressed, err := gzipBytes(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		payload = compressed
+	}
+
+	buffer := new(bytes.Buffer)
+
+	buffer.WriteByte(protocolVersion<<4 | headerSize)
+	buffer.WriteByte(byte(f.Type)<<4 | byte(f.Flags))
+	buffer.WriteByte(byte(f.Serialization)<<4 | byte(f.Compression))
+	buffer.WriteByte(0)
+
+	if f.Type == MessageTypeError {
+		_ = binary.Write(buffer, binary.BigEndian, f.ErrorCode)
+	} else if f.hasSequence() {
+		_ = binary.Write(buffer, binary.BigEndian, f.Sequence)
+	}
+
+	if f.hasEvent() {
+		_ = binary.Write(buffer, binary.BigEndian, f.Event)
+
+		if !f.Event.isConnectionLevel() {
+			_ = binary.Write(buffer, binary.BigEndian, uint32(len(f.SessionID))) //nolint:gosec
+			buffer.WriteString(f.SessionID)
+		}
+
+		if f.Event.hasConnectID() {
+			_ = binary.Write(buffer, binary.BigEndian, uint32(len(f.ConnectID))) //nolint:gosec
+			buffer.WriteString(f.ConnectID)
+		}
+	}
+
+	_ = binary.Write(buffer, binary.BigEndian, uint32(len(payload))) //nolint:gosec
+	buffer.Write(payload)
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the frame, the payload is decompressed according to the Compression field.
+func (f *Frame) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 { //nolint:mnd
+		return errors.New("frame too short")
+	}
+
+	size := int(data[0]&0x0f) * 4 //nolint:mnd
+	if len(data) < size {
+		return errors.New("frame too short")
+	}
+
+	f.Type = MessageType(data[1] >> 4)            //nolint:mnd
+	f.Flags = MessageFlags(data[1] & 0x0f)        //nolint:mnd
+	f.Serialization = Serialization(data[2] >> 4) //nolint:mnd
+	f.Compression = Compression(data[2] & 0x0f)   //nolint:mnd
+
+	reader := bytes.NewReader(data[size:])
+
+	if f.Type == MessageTypeError {
+		err := binary.Read(reader, binary.BigEndian, &f.ErrorCode)
+		if err != nil {
+			return fmt.Errorf("failed to read error code: %w", err)
+		}
+	} else if f.hasSequence() {
+		err := binary.Read(reader, binary.BigEndian, &f.Sequence)
+		if err != nil {
+			return fmt.Errorf("failed to read sequence: %w", err)
+		}
+	}
+
+	if f.hasEvent() {
+		err := binary.Read(reader, binary.BigEndian, &f.Event)
+		if err != nil {
+			return fmt.Errorf("failed to read event: %w", err)
+		}
+
+		if !f.Event.isConnectionLevel() {
+			f.SessionID, err = readString(reader)
+			if err != nil {
+				return fmt.Errorf("failed to read session id: %w", err)
+			}
+		}
+
+		if f.Event.hasConnectID() {
+			f.ConnectID, err = readString(reader)
+			if err != nil {
+				return fmt.Errorf("failed to read connect id: %w", err)
+			}
+		}
+	}
+
+	var payloadSize uint32
+
+	err := binary.Read(reader, binary.BigEndian, &payloadSize)
+	if err != nil {
+		return fmt.Errorf("failed to read payload size: %w", err)
+	}
+
+	if int(payloadSize) > reader.Len() {
+		return errors.New("payload size exceeds frame size")
+	}
+
+	f.Payload = make([]byte, payloadSize)
+	_, _ = reader.Read(f.Payload)
+
+	if f.Compression == CompressionGzip {
+		f.Payload, err = gunzipBytes(f.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to decompress payload: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package volcengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrame(t *testing.T) {
+	t.Parallel()
+
+	// Frames laid out as in the examples of the documentation, header, optional sequence or error code,
+	// payload size and payload, all big-endian.
+	//
+	// 大模型流式语音识别API--豆包语音-火山引擎
+	// https://www.volcengine.com/docs/6561/1354869
+	for name, tc := range map[string]struct {
+		frame Frame
+		data  []byte
+	}{
+		"FullClientRequest": {
+			frame: Frame{Type: MessageTypeFullClientRequest, Serialization: SerializationJSON, Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x10, 0x10, 0x00, 0, 0, 0, 2, '{', '}'},
+		},
+		"AudioOnlyRequest": {
+			frame: Frame{Type: MessageTypeAudioOnlyRequest, Flags: MessageFlagsPositiveSequence, Sequence: 2, Payload: []byte{0xAA, 0xBB}},
+			data:  []byte{0x11, 0x21, 0x00, 0x00, 0, 0, 0, 2, 0, 0, 0, 2, 0xAA, 0xBB},
+		},
+		"LastAudioOnlyRequest": {
+			frame: Frame{Type: MessageTypeAudioOnlyRequest, Flags: MessageFlagsNegativeSequence, Sequence: -3, Payload: []byte{}},
+			data:  []byte{0x11, 0x23, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFD, 0, 0, 0, 0},
+		},
+		"LastAudioOnlyRequestWithoutSequence": {
+			frame: Frame{Type: MessageTypeAudioOnlyRequest, Flags: MessageFlagsLast, Payload: []byte{0xAA}},
+			data:  []byte{0x11, 0x22, 0x00, 0x00, 0, 0, 0, 1, 0xAA},
+		},
+		"FullServerResponse": {
+			frame: Frame{Type: MessageTypeFullServerResponse, Flags: MessageFlagsPositiveSequence, Serialization: SerializationJSON, Sequence: 1, Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x91, 0x10, 0x00, 0, 0, 0, 1, 0, 0, 0, 2, '{', '}'},
+		},
+		"Error": {
+			frame: Frame{Type: MessageTypeError, Serialization: SerializationJSON, ErrorCode: 45000001, Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0xF0, 0x10, 0x00, 0x02, 0xAE, 0xA5, 0x41, 0, 0, 0, 2, '{', '}'},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := tc.frame.MarshalBinary()
+			require.NoError(t, err)
+			assert.Equal(t, tc.data, data)
+
+			var frame Frame
+
+			err = frame.UnmarshalBinary(tc.data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.frame, frame)
+		})
+	}
+}
+
+func TestFrameWithEvent(t *testing.T) {
+	t.Parallel()
+
+	// Frames of the bidirectional speech synthesis service, the event number follows the header, then the
+	// length prefixed session ID for session level events, or connection ID for the responses to connection
+	// level ones.
+	//
+	// WebSocket 双向流式-V3--豆包语音-火山引擎
+	// https://www.volcengine.com/docs/6561/1329505
+	for name, tc := range map[string]struct {
+		frame Frame
+		data  []byte
+	}{
+		"StartConnection": {
+			frame: Frame{Type: MessageTypeFullClientRequest, Flags: MessageFlagsWithEvent, Serialization: SerializationJSON, Event: EventStartConnection, Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x14, 0x10, 0x00, 0, 0, 0, 1, 0, 0, 0, 2, '{', '}'},
+		},
+		"ConnectionStarted": {
+			frame: Frame{Type: MessageTypeFullServerResponse, Flags: MessageFlagsWithEvent, Serialization: SerializationJSON, Event: EventConnectionStarted, ConnectID: "conn", Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x94, 0x10, 0x00, 0, 0, 0, 50, 0, 0, 0, 4, 'c', 'o', 'n', 'n', 0, 0, 0, 2, '{', '}'},
+		},
+		"StartSession": {
+			frame: Frame{Type: MessageTypeFullClientRequest, Flags: MessageFlagsWithEvent, Serialization: SerializationJSON, Event: EventStartSession, SessionID: "sess", Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x14, 0x10, 0x00, 0, 0, 0, 100, 0, 0, 0, 4, 's', 'e', 's', 's', 0, 0, 0, 2, '{', '}'},
+		},
+		"TaskRequest": {
+			frame: Frame{Type: MessageTypeFullClientRequest, Flags: MessageFlagsWithEvent, Serialization: SerializationJSON, Event: EventTaskRequest, SessionID: "s", Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x14, 0x10, 0x00, 0, 0, 0, 200, 0, 0, 0, 1, 's', 0, 0, 0, 2, '{', '}'},
+		},
+		"TTSResponse": {
+			frame: Frame{Type: MessageTypeAudioOnlyResponse, Flags: MessageFlagsWithEvent, Event: EventTTSResponse, SessionID: "s", Payload: []byte{0xAA, 0xBB}},
+			data:  []byte{0x11, 0xB4, 0x00, 0x00, 0, 0, 0x01, 0x60, 0, 0, 0, 1, 's', 0, 0, 0, 2, 0xAA, 0xBB},
+		},
+		"SessionFinished": {
+			frame: Frame{Type: MessageTypeFullServerResponse, Flags: MessageFlagsWithEvent, Serialization: SerializationJSON, Event: EventSessionFinished, SessionID: "", Payload: []byte(`{}`)},
+			data:  []byte{0x11, 0x94, 0x10, 0x00, 0, 0, 0, 152, 0, 0, 0, 0, 0, 0, 0, 2, '{', '}'},
+		},
+		"WithSequence": {
+			frame: Frame{Type: MessageTypeAudioOnlyResponse, Flags: MessageFlagsWithEvent | MessageFlagsPositiveSequence, Event: EventTTSResponse, Sequence: 7, SessionID: "s", Payload: []byte{0xAA}},
+			data:  []byte{0x11, 0xB5, 0x00, 0x00, 0, 0, 0, 7, 0, 0, 0x01, 0x60, 0, 0, 0, 1, 's', 0, 0, 0, 1, 0xAA},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := tc.frame.MarshalBinary()
+			require.NoError(t, err)
+			assert.Equal(t, tc.data, data)
+
+			var frame Frame
+
+			err = frame.UnmarshalBinary(tc.data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.frame, frame)
+			assert.False(t, frame.IsLast())
+		})
+	}
+}
+
+func TestFrameGzip(t *testing.T) {
+	t.Parallel()
+
+	frame := Frame{
+		Type:          MessageTypeFullClientRequest,
+		Flags:         MessageFlagsPositiveSequence,
+		Serialization: SerializationJSON,
+		Compression:   CompressionGzip,
+		Sequence:      1,
+		Payload:       []byte(`{"audio":{"format":"pcm"}}`),
+	}
+
+	data, err := frame.MarshalBinary()
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte{0x11, 0x11, 0x11, 0x00}, data[:4])
+	// gzip magic number right after the sequence and payload size.
+	assert.Equal(t, []byte{0x1F, 0x8B}, data[12:14])
+
+	var decoded Frame
+
+	err = decoded.UnmarshalBinary(data)
+	require.NoError(t, err)
+	assert.Equal(t, frame, decoded)
+}
+
+func TestFrameMalformed(t *testing.T) {
+	t.Parallel()
+
+	for name, data := range map[string][]byte{
+		"Empty":               {},
+		"HeaderOnly":          {0x11, 0x91, 0x10},
+		"MissingSequence":     {0x11, 0x91, 0x10, 0x00, 0, 0},
+		"MissingPayloadSize":  {0x11, 0x10, 0x10, 0x00},
+		"PayloadSizeExceeded": {0x11, 0x10, 0x10, 0x00, 0, 0, 0, 3, '{', '}'},
+		"SessionIDExceeded":   {0x11, 0x94, 0x10, 0x00, 0, 0, 0, 152, 0, 0, 0, 9, 's'},
+		"InvalidGzip":         {0x11, 0x10, 0x11, 0x00, 0, 0, 0, 2, '{', '}'},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var frame Frame
+
+			assert.Error(t, frame.UnmarshalBinary(data))
+		})
+	}
+}
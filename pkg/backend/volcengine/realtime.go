@@ -0,0 +1,147 @@
+package volcengine
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+type realtimeUpstream struct {
+	conn     *websocket.Conn
+	sequence int32
+	finished bool
+	mutex    sync.Mutex
+}
+
+func (u *realtimeUpstream) writeAudio(audio []byte, last bool) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.sequence++
+
+	frame := Frame{
+		Type:          MessageTypeAudioOnlyRequest,
+		Flags:         MessageFlagsPositiveSequence,
+		Serialization: SerializationNone,
+		Compression:   CompressionGzip,
+		Sequence:      u.sequence,
+		Payload:       audio,
+	}
+
+	if last {
+		frame.Flags = MessageFlagsNegativeSequence
+		frame.Sequence = -u.sequence
+	}
+
+	return writeFrame(u.conn, frame)
+}
+
+func (u *realtimeUpstream) SendAudio(audio []byte) error {
+	return u.writeAudio(audio, false)
+}
+
+func (u *realtimeUpstream) Finish() error {
+	return u.writeAudio(make([]byte, 0), true)
+}
+
+func (u *realtimeUpstream) Receive() ([]types.RealtimeTranscriptionEvent, error) {
+	for {
+		if u.finished {
+			return nil, io.EOF
+		}
+
+		frame := readFrame(u.conn)
+		if frame.IsError() {
+			return nil, frame.Error()
+		}
+
+		u.finished = frame.MustGet().IsLast()
+
+		if frame.MustGet().Type != MessageTypeFullServerResponse || len(frame.MustGet().Payload) == 0 {
+			continue
+		}
+
+		var response RecognitionResponse
+
+		err := json.Unmarshal(frame.MustGet().Payload, &response)
+		if err != nil {
+			return nil, apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller()
+		}
+
+		events := make([]types.RealtimeTranscriptionEvent, 0, len(response.Result.Utterances))
+
+		for _, utterance := range response.Result.Utterances {
+			if utterance.Text == "" {
+				continue
+			}
+
+			events = append(events, types.RealtimeTranscriptionEvent{
+				Type: types.RealtimeTranscriptionEventTypeTranscript,
+				Transcript: &types.RealtimeTranscript{
+					Text:    utterance.Text,
+					IsFinal: utterance.Definite,
+					Start:   millisecondsToSeconds(utterance.StartTime),
+					End:     millisecondsToSeconds(utterance.EndTime),
+					Words: lo.Map(utterance.Words, func(item RecognitionWord, _ int) types.TranscriptionWord {
+						return types.TranscriptionWord{
+							Word:  item.Text,
+							Start: millisecondsToSeconds(item.StartTime),
+							End:   millisecondsToSeconds(item.EndTime),
+						}
+					}),
+				},
+			})
+		}
+
+		if len(events) > 0 {
+			return events, nil
+		}
+	}
+}
+
+func (u *realtimeUpstream) Close() error {
+	return u.conn.Close()
+}
+
+func HandleRealtimeTranscription(c echo.Context, options mo.Option[types.RealtimeTranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	request := newRecognitionRequest(nil, opts.Language)
+	request.Audio.Format = lo.Ternary(opts.Encoding == types.RealtimeAudioEncodingOpus, "ogg", "pcm")
+	request.Audio.Codec = lo.Ternary(opts.Encoding == types.RealtimeAudioEncodingOpus, "opus", "raw")
+	request.Audio.Rate = opts.SampleRate
+	request.Audio.Bits = 16
+	request.Audio.Channel = opts.Channels
+	// Only the current utterances are returned with single, instead of the whole text so far
+	request.Request.ResultType = "single"
+	request.Request.EnableITN = lo.CoalesceOrEmpty(opts.ExtraQuery.Get("enable_itn"), "true") == "true"
+	request.Request.EnablePunc = lo.CoalesceOrEmpty(opts.ExtraQuery.Get("enable_punc"), "true") == "true"
+	request.Request.EnableDDC = utils.FromStringOrEmpty[bool](opts.ExtraQuery.Get("enable_ddc"))
+	// VAD: silence duration in milliseconds to end an utterance
+	request.Request.EndWindowSize = utils.FromStringOrEmpty[int](opts.ExtraQuery.Get("end_window_size"))
+	request.Request.ForceToSpeechTime = utils.FromStringOrEmpty[int](opts.ExtraQuery.Get("force_to_speech_time"))
+
+	connResult := startStreamingRecognition(
+		c,
+		newAPIHeaders(
+			opts.Authorization(c),
+			opts.ExtraQuery.Get("appid"),
+			lo.CoalesceOrEmpty(opts.ExtraQuery.Get("cluster"), defaultStreamingRecognitionResourceID),
+		),
+		request,
+	)
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	// Sequence number 1 was taken by the full client request
+	return types.ServeRealtimeTranscription(c, &realtimeUpstream{conn: connResult.MustGet(), sequence: 1})
+}
@@ -0,0 +1,115 @@
+package volcengine
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+// accessKeyCredentials are the AK/SK of the Volcengine account, used to call the OpenAPI.
+type accessKeyCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// parseAccessKeyCredentials extracts the AK/SK from the Authorization header, which is expected to be in
+// the form of `Bearer <AccessKeyID>:<SecretAccessKey>`.
+func parseAccessKeyCredentials(authorization string) mo.Option[accessKeyCredentials] {
+	accessKeyID, secretAccessKey, ok := strings.Cut(strings.TrimPrefix(authorization, "Bearer "), ":")
+	if !ok || accessKeyID == "" || secretAccessKey == "" {
+		return mo.None[accessKeyCredentials]()
+	}
+
+	return mo.Some(accessKeyCredentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+	})
+}
+
+func hmacSHA256(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(content))
+
+	return mac.Sum(nil)
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// signRequest signs the OpenAPI request with the HMAC-SHA256 (V4) signature, the body must be the exact
+// bytes sent with the request.
+//
+// 签名方法--API签名调用指南-火山引擎
+// https://www.volcengine.com/docs/6369/67269
+func signRequest(req *http.Request, body []byte, creds accessKeyCredentials, region string, service string, now time.Time) {
+	xDate := now.UTC().Format("20060102T150405Z")
+	shortDate := xDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Date", xDate)
+	req.Header.Set("X-Content-Sha256", payloadHash)
+
+	signedHeaderNames := []string{"content-type", "host", "x-content-sha256", "x-date"}
+	canonicalHeaders := new(strings.Builder)
+
+	for _, name := range signedHeaderNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	// Query parameters are sorted by key, and spaces are encoded as %20 instead of +.
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	canonicalQuery := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, strings.ReplaceAll(url.QueryEscape(key)+"="+url.QueryEscape(value), "+", "%20"))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		lo.CoalesceOrEmpty(req.URL.EscapedPath(), "/"),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := strings.Join([]string{shortDate, region, service, "request"}, "/")
+	stringToSign := strings.Join([]string{"HMAC-SHA256", xDate, credentialScope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte(creds.SecretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "request")
+
+	req.Header.Set("Authorization", "HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+credentialScope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+hex.EncodeToString(hmacSHA256(signingKey, stringToSign)))
+}
@@ -0,0 +1,56 @@
+package volcengine
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignRequest(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"AppID":"123","SpeakerIDs":["S_1"]}`)
+
+	// Query parameters are out of order and with a space, to cover sorting and %20 encoding.
+	req, err := http.NewRequest(http.MethodPost, "https://open.volcengineapi.com/?Version=2023-11-07&Note=a+b&Action=ListMegaTTSTrainStatus", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Type", "application/json")
+
+	signRequest(req, body, accessKeyCredentials{AccessKeyID: "AKtest", SecretAccessKey: "SKtest"}, "cn-north-1", "speech_saas_prod", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	assert.Equal(t, "20240102T030405Z", req.Header.Get("X-Date"))
+	assert.Equal(t, "f4a3d5d62a6f71935a663cae09e5b8dfb11d3f3a3892ada3dda485aaeae882ed", req.Header.Get("X-Content-Sha256"))
+
+	// Computed independently following the documented steps, with the canonical request being:
+	//
+	//	POST
+	//	/
+	//	Action=ListMegaTTSTrainStatus&Note=a%20b&Version=2023-11-07
+	//	content-type:application/json
+	//	host:open.volcengineapi.com
+	//	x-content-sha256:f4a3d5d62a6f71935a663cae09e5b8dfb11d3f3a3892ada3dda485aaeae882ed
+	//	x-date:20240102T030405Z
+	//
+	//	content-type;host;x-content-sha256;x-date
+	//	f4a3d5d62a6f71935a663cae09e5b8dfb11d3f3a3892ada3dda485aaeae882ed
+	//
+	// 签名方法--API签名调用指南-火山引擎
+	// https://www.volcengine.com/docs/6369/67269
+	assert.Equal(t,
+		"HMAC-SHA256 Credential=AKtest/20240102/cn-north-1/speech_saas_prod/request, SignedHeaders=content-type;host;x-content-sha256;x-date, Signature=c2d4d2d709efa0fa9b5f1a8bf4e2e47ba8e66db0a1eed9c834206d926ef2c897",
+		req.Header.Get("Authorization"),
+	)
+}
+
+func TestParseAccessKeyCredentials(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, accessKeyCredentials{AccessKeyID: "AK", SecretAccessKey: "SK:with:colons"}, parseAccessKeyCredentials("Bearer AK:SK:with:colons").MustGet())
+	assert.True(t, parseAccessKeyCredentials("Bearer token").IsAbsent())
+	assert.True(t, parseAccessKeyCredentials("Bearer :SK").IsAbsent())
+}
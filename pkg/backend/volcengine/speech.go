@@ -11,12 +11,20 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/audio"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
 	"github.com/moeru-ai/unspeech/pkg/utils"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
+// MaxInputLength is the maximum number of characters in a request, the text is limited to 1024 bytes
+// in UTF-8, i.e. about 300 Chinese characters.
+//
+// HTTP 接口(一次性合成-非流式)--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/79823
+const MaxInputLength = 300
+
 type SpeechRequestOptionsApp struct {
 	AppID   string `json:"appid"`
 	Token   string `json:"token"`
@@ -62,9 +70,83 @@ type SpeechRequestOptions struct {
 	Request SpeechRequestOptionsRequest `json:"request"`
 }
 
+type speechEncoding struct {
+	Encoding    string
+	ContentType string
+}
+
+// Encodings Volcengine synthesizes natively, the others, e.g. flac and ulaw, are transcoded from wav.
+var speechEncodings = map[string]speechEncoding{
+	"mp3":      {Encoding: "mp3", ContentType: "audio/mpeg"},
+	"wav":      {Encoding: "wav", ContentType: "audio/wav"},
+	"pcm":      {Encoding: "pcm", ContentType: "audio/pcm"},
+	"opus":     {Encoding: "ogg_opus", ContentType: "audio/ogg"},
+	"ogg_opus": {Encoding: "ogg_opus", ContentType: "audio/ogg"},
+}
+
+// Sample rates Volcengine synthesizes at, 24kHz by default, always in mono.
+var speechSampleRates = []int{8000, 16000, 24000} //nolint:mnd
+
+// HTTP非流式-V1--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1257584
+var prosodyRanges = types.ProsodyRanges{
+	Speed:  mo.Some(types.ProsodyRange{Min: 0.8, Max: 2.0}), //nolint:mnd
+	Volume: mo.Some(types.ProsodyRange{Min: 0.5, Max: 2.0}), //nolint:mnd
+}
+
+// ProsodyRanges returns the prosody the model supports, v3 takes the rates of the bidirectional API.
+func ProsodyRanges(model string) types.ProsodyRanges {
+	return lo.Ternary(model == "v3", bidirectionalProsodyRanges, prosodyRanges)
+}
+
 func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions]) mo.Result[any] {
 	opts := options.MustGet()
 
+	// extra_body.audio.rate is the same as sample_rate.
+	output, err := opts.Output.WithProviderSampleRate(utils.GetByJSONPath[*int](opts.ExtraBody, "{ .audio.rate }"), "/extra_body/audio/rate")
+	if err != nil {
+		return mo.Err[any](err)
+	}
+
+	opts.Output = output
+
+	// volcengine/v3 streams the audio through the bidirectional WebSocket API, which is required by the
+	// Doubao big-model voices and mixed voices.
+	if opts.Model == "v3" {
+		return handleBidirectionalSpeech(c, opts)
+	}
+
+	responseFormat := lo.CoalesceOrEmpty(opts.ResponseFormat, "mp3")
+
+	encoding, ok := speechEncodings[responseFormat]
+	if !ok {
+		if !audio.CanEncode(responseFormat) {
+			return mo.Err[any](apierrors.
+				NewErrInvalidArgument().
+				WithDetail("unsupported response_format, supported formats are mp3, wav, pcm, opus, flac, ulaw and alaw").
+				WithSourcePointer("/response_format"))
+		}
+
+		encoding = speechEncodings["wav"]
+	}
+
+	var rate *int
+	if opts.Output.SampleRate != 0 {
+		rate = lo.ToPtr(opts.Output.NativeSampleRate(speechSampleRates, 24000)) //nolint:mnd
+	}
+
+	// Other sample rates and stereo are transcoded from wav as well.
+	native := audio.Spec{Format: responseFormat, SampleRate: lo.FromPtrOr(rate, 24000), Channels: 1} //nolint:mnd
+	if ok && !native.Matches(opts.Output.Spec(responseFormat)) {
+		err := types.CheckOutput(native, responseFormat, opts.Output)
+		if err != nil {
+			return mo.Err[any](err)
+		}
+
+		encoding = speechEncodings["wav"]
+		ok = false
+	}
+
 	token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
 
 	cluster := utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.cluster }")
@@ -89,7 +171,12 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 
 	speedRatio := utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .audio.speed_ratio }")
 	if speedRatio == nil || *speedRatio == 0 {
-		speedRatio = lo.ToPtr(1.0)
+		speedRatio = lo.ToPtr(opts.Prosody.Speed.OrElse(1))
+	}
+
+	loudnessRatio := utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .audio.loudness_ratio }")
+	if loudnessRatio == nil && opts.Prosody.Volume.IsPresent() {
+		loudnessRatio = lo.ToPtr(opts.Prosody.Volume.MustGet())
 	}
 
 	newReqParams := &SpeechRequestOptions{
@@ -106,13 +193,13 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 			Emotion:          utils.GetByJSONPath[*string](opts.ExtraBody, "{ .audio.emotion }"),
 			EnableEmotion:    utils.GetByJSONPath[*bool](opts.ExtraBody, "{ .audio.enable_emotion }"),
 			EmotionScale:     utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .audio.emotion_scale }"),
-			Encoding:         lo.Ternary(opts.ResponseFormat != "", lo.ToPtr(opts.ResponseFormat), lo.ToPtr("mp3")),
+			Encoding:         lo.ToPtr(encoding.Encoding),
 			SpeedRatio:       speedRatio,
-			Rate:             utils.GetByJSONPath[*int](opts.ExtraBody, "{ .audio.rate }"),
+			Rate:             rate,
 			BitRate:          utils.GetByJSONPath[*int](opts.ExtraBody, "{ .audio.bit_rate }"),
 			ExplicitLanguage: utils.GetByJSONPath[*string](opts.ExtraBody, "{ .audio.explicit_language }"),
 			ContextLanguage:  utils.GetByJSONPath[*string](opts.ExtraBody, "{ .audio.context_language }"),
-			LoudnessRatio:    utils.GetByJSONPath[*float64](opts.ExtraBody, "{ .audio.loudness_ratio }"),
+			LoudnessRatio:    loudnessRatio,
 		},
 		Request: SpeechRequestOptionsRequest{
 			RequestID:             requestID,
@@ -183,5 +270,9 @@ func HandleSpeech(c echo.Context, options mo.Option[types.SpeechRequestOptions])
 		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithError(err).WithCaller())
 	}
 
-	return mo.Ok[any](c.Blob(http.StatusOK, "audio/mp3", audioBytes))
+	if !ok {
+		return types.RespondTranscoded(c, audioBytes, audio.Spec{Format: audio.FormatWAV}, responseFormat, opts.Output)
+	}
+
+	return mo.Ok[any](c.Blob(http.StatusOK, encoding.ContentType, audioBytes))
 }
@@ -0,0 +1,517 @@
+package volcengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/moeru-ai/unspeech/pkg/apierrors"
+	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/moeru-ai/unspeech/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+)
+
+const (
+	defaultStreamingRecognitionResourceID = "volc.bigasr.sauc.duration"
+	defaultFileRecognitionResourceID      = "volc.bigasr.auc"
+
+	// Size of each audio only request, 200ms of 16kHz 16-bit mono PCM as what the documentation recommends.
+	recognitionFrameSize = 6400
+
+	// Status codes returned within the X-Api-Status-Code header of the file recognition API.
+	fileRecognitionStatusSucceeded  = "20000000"
+	fileRecognitionStatusProcessing = "20000001"
+	fileRecognitionStatusQueued     = "20000002"
+)
+
+type RecognitionRequestAudio struct {
+	URL      string `json:"url,omitempty"`
+	Format   string `json:"format"`
+	Codec    string `json:"codec,omitempty"`
+	Rate     int    `json:"rate,omitempty"`
+	Bits     int    `json:"bits,omitempty"`
+	Channel  int    `json:"channel,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+type RecognitionRequestRequest struct {
+	ModelName         string `json:"model_name"`
+	EnableITN         bool   `json:"enable_itn"`
+	EnablePunc        bool   `json:"enable_punc"`
+	EnableDDC         bool   `json:"enable_ddc,omitempty"`
+	EnableSpeakerInfo bool   `json:"enable_speaker_info,omitempty"`
+	ShowUtterances    bool   `json:"show_utterances"`
+	ResultType        string `json:"result_type,omitempty"`
+	EndWindowSize     int    `json:"end_window_size,omitempty"`
+	ForceToSpeechTime int    `json:"force_to_speech_time,omitempty"`
+}
+
+type RecognitionRequest struct {
+	User    SpeechRequestOptionsUser  `json:"user"`
+	Audio   RecognitionRequestAudio   `json:"audio"`
+	Request RecognitionRequestRequest `json:"request"`
+}
+
+type RecognitionWord struct {
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+	Text      string `json:"text"`
+}
+
+type RecognitionUtterance struct {
+	StartTime int64             `json:"start_time"`
+	EndTime   int64             `json:"end_time"`
+	Text      string            `json:"text"`
+	Definite  bool              `json:"definite"`
+	Words     []RecognitionWord `json:"words"`
+	Additions map[string]any    `json:"additions"`
+}
+
+type RecognitionResponse struct {
+	AudioInfo struct {
+		Duration int64 `json:"duration"`
+	} `json:"audio_info"`
+	Result struct {
+		Text       string                 `json:"text"`
+		Utterances []RecognitionUtterance `json:"utterances"`
+	} `json:"result"`
+}
+
+// recognitionFormats maps the extension of uploaded files to the format and codec of the audio.
+var recognitionFormats = map[string][2]string{
+	".wav":  {"wav", "raw"},
+	".pcm":  {"pcm", "raw"},
+	".mp3":  {"mp3", "raw"},
+	".ogg":  {"ogg", "opus"},
+	".opus": {"ogg", "opus"},
+}
+
+func millisecondsToSeconds(milliseconds int64) float64 {
+	return float64(milliseconds) / 1000 //nolint:mnd
+}
+
+func newRecognitionRequest(extraBody map[string]any, language string) RecognitionRequest {
+	userID := utils.GetByJSONPath[string](extraBody, "{ .user.uid }")
+	if userID == "" {
+		userID = uuid.New().String()
+	}
+
+	enableITN := utils.GetByJSONPath[*bool](extraBody, "{ .request.enable_itn }")
+	enablePunc := utils.GetByJSONPath[*bool](extraBody, "{ .request.enable_punc }")
+
+	return RecognitionRequest{
+		User: SpeechRequestOptionsUser{
+			UserID: userID,
+		},
+		Audio: RecognitionRequestAudio{
+			Language: language,
+		},
+		Request: RecognitionRequestRequest{
+			ModelName:         "bigmodel",
+			EnableITN:         lo.FromPtrOr(enableITN, true),
+			EnablePunc:        lo.FromPtrOr(enablePunc, true),
+			EnableDDC:         utils.GetByJSONPath[bool](extraBody, "{ .request.enable_ddc }"),
+			EnableSpeakerInfo: utils.GetByJSONPath[bool](extraBody, "{ .request.enable_speaker_info }"),
+			ShowUtterances:    true,
+		},
+	}
+}
+
+// newAPIHeaders returns the authentication headers shared by the v3 APIs.
+func newAPIHeaders(token string, appID string, resourceID string) http.Header {
+	headers := http.Header{}
+
+	headers.Set("X-Api-App-Key", appID)
+	headers.Set("X-Api-Access-Key", token)
+	headers.Set("X-Api-Resource-Id", resourceID)
+
+	return headers
+}
+
+func toVerboseTranscription(response RecognitionResponse, language string) types.VerboseTranscriptionResponse {
+	result := types.VerboseTranscriptionResponse{
+		Task:     "transcribe",
+		Language: language,
+		Duration: millisecondsToSeconds(response.AudioInfo.Duration),
+		Text:     response.Result.Text,
+		Segments: make([]types.TranscriptionSegment, 0, len(response.Result.Utterances)),
+	}
+
+	for _, utterance := range response.Result.Utterances {
+		speaker, _ := utterance.Additions["speaker"].(string)
+
+		result.Segments = append(result.Segments, types.TranscriptionSegment{
+			ID:      len(result.Segments),
+			Start:   millisecondsToSeconds(utterance.StartTime),
+			End:     millisecondsToSeconds(utterance.EndTime),
+			Text:    utterance.Text,
+			Speaker: speaker,
+		})
+
+		for _, word := range utterance.Words {
+			result.Words = append(result.Words, types.TranscriptionWord{
+				Word:    word.Text,
+				Start:   millisecondsToSeconds(word.StartTime),
+				End:     millisecondsToSeconds(word.EndTime),
+				Speaker: speaker,
+			})
+		}
+	}
+
+	return result
+}
+
+func writeFrame(conn *websocket.Conn, frame Frame) error {
+	data, err := frame.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+func readFrame(conn *websocket.Conn) mo.Result[Frame] {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return mo.Err[Frame](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	var frame Frame
+
+	err = frame.UnmarshalBinary(message)
+	if err != nil {
+		return mo.Err[Frame](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	if frame.Type == MessageTypeError {
+		return mo.Err[Frame](apierrors.
+			NewErrBadGateway().
+			WithDetailf("upstream returned error, code: %d, message: %s", frame.ErrorCode, string(frame.Payload)))
+	}
+
+	return mo.Ok(frame)
+}
+
+// sendAudio streams the audio as audio only requests, the last one is marked with a negative sequence number.
+func sendAudio(conn *websocket.Conn, audio []byte) error {
+	chunks := lo.Chunk(audio, recognitionFrameSize)
+	if len(chunks) == 0 {
+		chunks = [][]byte{{}}
+	}
+
+	for i, chunk := range chunks {
+		// Sequence number 1 was taken by the full client request
+		sequence := int32(i + 2) //nolint:gosec,mnd
+		flags := MessageFlagsPositiveSequence
+
+		if i == len(chunks)-1 {
+			sequence = -sequence
+			flags = MessageFlagsNegativeSequence
+		}
+
+		err := writeFrame(conn, Frame{
+			Type:          MessageTypeAudioOnlyRequest,
+			Flags:         flags,
+			Serialization: SerializationNone,
+			Compression:   CompressionGzip,
+			Sequence:      sequence,
+			Payload:       chunk,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dial connects to the WebSocket API with a new connection ID, handshake failures are returned as upstream errors.
+func dial(c echo.Context, reqURL string, headers http.Header) mo.Result[*websocket.Conn] {
+	headers.Set("X-Api-Connect-Id", uuid.New().String())
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(c.Request().Context(), reqURL, headers)
+	if err != nil {
+		if resp == nil {
+			return mo.Err[*websocket.Conn](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		return mo.Err[*websocket.Conn](apierrors.
+			NewUpstreamError(resp.StatusCode).
+			WithDetail(utils.NewTextResponseError(resp.StatusCode, resp.Body).OrEmpty().Error()).
+			WithCaller())
+	}
+
+	return mo.Ok(conn)
+}
+
+// startStreamingRecognition connects to the streaming recognition service, sends the full client request
+// and waits for it to be acknowledged, audio only requests are expected to follow with sequence from 2.
+//
+// 大模型流式语音识别API--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1354869
+func startStreamingRecognition(c echo.Context, headers http.Header, request RecognitionRequest) mo.Result[*websocket.Conn] {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return mo.Err[*websocket.Conn](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	connResult := dial(c, "wss://openspeech.bytedance.com/api/v3/sauc/bigmodel", headers)
+	if connResult.IsError() {
+		return connResult
+	}
+
+	conn := connResult.MustGet()
+
+	err = writeFrame(conn, Frame{
+		Type:          MessageTypeFullClientRequest,
+		Flags:         MessageFlagsPositiveSequence,
+		Serialization: SerializationJSON,
+		Compression:   CompressionGzip,
+		Sequence:      1,
+		Payload:       payload,
+	})
+	if err != nil {
+		_ = conn.Close()
+		return mo.Err[*websocket.Conn](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	ack := readFrame(conn)
+	if ack.IsError() {
+		_ = conn.Close()
+		return mo.Err[*websocket.Conn](ack.Error())
+	}
+
+	return mo.Ok(conn)
+}
+
+func HandleTranscription(c echo.Context, options mo.Option[types.TranscriptionRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	audio, err := opts.ReadFile()
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadRequest().WithDetail(err.Error()))
+	}
+
+	formatAndCodec, ok := recognitionFormats[strings.ToLower(filepath.Ext(opts.File.Filename))]
+	if !ok {
+		formatAndCodec = [2]string{"wav", "raw"}
+	}
+
+	request := newRecognitionRequest(opts.ExtraBody, opts.Language)
+	request.Audio.Format = lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .audio.format }"), formatAndCodec[0])
+	request.Audio.Codec = lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .audio.codec }"), formatAndCodec[1])
+	request.Audio.Rate = lo.CoalesceOrEmpty(utils.GetByJSONPath[int](opts.ExtraBody, "{ .audio.rate }"), 16000) //nolint:mnd
+	request.Audio.Bits = 16
+	request.Audio.Channel = 1
+	request.Request.ResultType = "full"
+
+	headers := newAPIHeaders(
+		strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "),
+		utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.appid }"),
+		lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.cluster }"), defaultStreamingRecognitionResourceID),
+	)
+
+	connResult := startStreamingRecognition(c, headers, request)
+	if connResult.IsError() {
+		return mo.Err[any](connResult.Error())
+	}
+
+	conn := connResult.MustGet()
+
+	defer func() { _ = conn.Close() }()
+
+	chanSendError := make(chan error, 1)
+
+	go func() {
+		chanSendError <- sendAudio(conn, audio)
+	}()
+
+	var response RecognitionResponse
+
+	for {
+		frame := readFrame(conn)
+		if frame.IsError() {
+			return mo.Err[any](frame.Error())
+		}
+
+		if frame.MustGet().Type != MessageTypeFullServerResponse || len(frame.MustGet().Payload) == 0 {
+			continue
+		}
+
+		// Results are full snapshots as result_type is set to full, the last one is what we want
+		err = json.Unmarshal(frame.MustGet().Payload, &response)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+		}
+
+		if frame.MustGet().IsLast() {
+			break
+		}
+	}
+
+	err = <-chanSendError
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	return types.WriteTranscription(c, opts.ResponseFormat, toVerboseTranscription(response, opts.Language))
+}
+
+// doFileRecognitionRequest calls submit or query of the file recognition API, which report the status
+// within the X-Api-Status-Code and X-Api-Message headers.
+//
+// 大模型录音文件识别标准版API--豆包语音-火山引擎
+// https://www.volcengine.com/docs/6561/1354868
+func doFileRecognitionRequest(c echo.Context, endpoint string, headers http.Header, body any) mo.Result[*http.Response] {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return mo.Err[*http.Response](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, "https://openspeech.bytedance.com/api/v3/auc/bigmodel/"+endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return mo.Err[*http.Response](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req.Header = headers
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[*http.Response](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	if res.StatusCode >= 400 && res.StatusCode < 600 {
+		defer func() { _ = res.Body.Close() }()
+
+		switch {
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "application/json"):
+			return mo.Err[*http.Response](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewJSONResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		case strings.HasPrefix(res.Header.Get("Content-Type"), "text/"):
+			return mo.Err[*http.Response](apierrors.
+				NewUpstreamError(res.StatusCode).
+				WithDetail(utils.NewTextResponseError(res.StatusCode, res.Body).OrEmpty().Error()))
+		default:
+			slog.Warn("unknown upstream error with unknown Content-Type",
+				slog.Int("status", res.StatusCode),
+				slog.String("content_type", res.Header.Get("Content-Type")),
+				slog.String("content_length", res.Header.Get("Content-Length")),
+			)
+
+			return mo.Err[*http.Response](apierrors.NewUpstreamError(res.StatusCode).WithDetail(res.Status))
+		}
+	}
+
+	return mo.Ok(res)
+}
+
+func HandleCreateTranscriptionJob(c echo.Context, options mo.Option[types.TranscriptionJobRequestOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	if len(opts.ContentURLs) != 1 {
+		return mo.Err[any](apierrors.
+			NewErrInvalidArgument().
+			WithDetail("exactly one of content_urls is supported for each job").
+			WithSourcePointer("/content_urls"))
+	}
+
+	contentURL := opts.ContentURLs[0]
+	formatAndCodec, ok := recognitionFormats[strings.ToLower(filepath.Ext(strings.SplitN(contentURL, "?", 2)[0]))] //nolint:mnd
+	if !ok {
+		formatAndCodec = [2]string{"mp3", "raw"}
+	}
+
+	request := newRecognitionRequest(opts.ExtraBody, opts.Language)
+	request.Audio.URL = contentURL
+	request.Audio.Format = lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .audio.format }"), formatAndCodec[0])
+	request.Audio.Codec = utils.GetByJSONPath[string](opts.ExtraBody, "{ .audio.codec }")
+
+	requestID := uuid.New().String()
+
+	headers := newAPIHeaders(
+		strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "),
+		utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.appid }"),
+		lo.CoalesceOrEmpty(utils.GetByJSONPath[string](opts.ExtraBody, "{ .app.cluster }"), defaultFileRecognitionResourceID),
+	)
+	headers.Set("X-Api-Request-Id", requestID)
+	headers.Set("X-Api-Sequence", "-1")
+
+	res := doFileRecognitionRequest(c, "submit", headers, request)
+	if res.IsError() {
+		return mo.Err[any](res.Error())
+	}
+
+	_ = res.MustGet().Body.Close()
+
+	statusCode := res.MustGet().Header.Get("X-Api-Status-Code")
+	if statusCode != fileRecognitionStatusSucceeded {
+		return mo.Err[any](apierrors.
+			NewErrBadGateway().
+			WithDetailf("failed to submit task, code: %s, message: %s", statusCode, res.MustGet().Header.Get("X-Api-Message")))
+	}
+
+	return mo.Ok[any](c.JSON(http.StatusAccepted, types.TranscriptionJob{
+		ID:       requestID,
+		Provider: "volcengine",
+		Status:   types.TranscriptionJobStatusQueued,
+	}))
+}
+
+func HandleGetTranscriptionJob(c echo.Context, options mo.Option[types.TranscriptionJobQueryOptions]) mo.Result[any] {
+	opts := options.MustGet()
+
+	headers := newAPIHeaders(
+		strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer "),
+		opts.ExtraQuery.Get("appid"),
+		lo.CoalesceOrEmpty(opts.ExtraQuery.Get("cluster"), defaultFileRecognitionResourceID),
+	)
+	headers.Set("X-Api-Request-Id", opts.ID)
+
+	res := doFileRecognitionRequest(c, "query", headers, map[string]any{})
+	if res.IsError() {
+		return mo.Err[any](res.Error())
+	}
+
+	defer func() { _ = res.MustGet().Body.Close() }()
+
+	job := types.TranscriptionJob{
+		ID:       opts.ID,
+		Provider: "volcengine",
+	}
+
+	statusCode := res.MustGet().Header.Get("X-Api-Status-Code")
+
+	switch statusCode {
+	case fileRecognitionStatusQueued:
+		job.Status = types.TranscriptionJobStatusQueued
+	case fileRecognitionStatusProcessing:
+		job.Status = types.TranscriptionJobStatusRunning
+	case fileRecognitionStatusSucceeded:
+		var response RecognitionResponse
+
+		err := json.NewDecoder(res.MustGet().Body).Decode(&response)
+		if err != nil {
+			return mo.Err[any](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+		}
+
+		job.Status = types.TranscriptionJobStatusSucceeded
+		job.Results = []types.VerboseTranscriptionResponse{toVerboseTranscription(response, "")}
+	default:
+		job.Status = types.TranscriptionJobStatusFailed
+		job.Error = fmt.Sprintf("code: %s, message: %s", statusCode, res.MustGet().Header.Get("X-Api-Message"))
+	}
+
+	return mo.Ok[any](c.JSON(http.StatusOK, job))
+}
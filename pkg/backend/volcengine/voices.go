@@ -1,12 +1,17 @@
 package volcengine
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/moeru-ai/unspeech/pkg/apierrors"
 	"github.com/moeru-ai/unspeech/pkg/backend/types"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 )
 
@@ -52,48 +57,237 @@ type VoicesResponse struct {
 	Data   VoicesResponseData `json:"data"`
 }
 
-func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
-	var voicesData VoicesResponse
+// https://www.volcengine.com/docs/6561/1257584
+var formats = []types.VoiceFormat{
+	{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, Bitrate: 16, FormatCode: "wav"},         //nolint:mnd
+	{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, Bitrate: 16, FormatCode: "pcm"},         //nolint:mnd
+	{Name: "Opus", Extension: ".opus", MimeType: "audio/opus", SampleRate: 24000, Bitrate: 16, FormatCode: "ogg_opus"}, //nolint:mnd
+	{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 24000, Bitrate: 16, FormatCode: "mp3"},         //nolint:mnd
+	// Synthesized as WAV and transcoded.
+	{Name: "FLAC", Extension: ".flac", MimeType: "audio/flac", SampleRate: 24000, Bitrate: 16, FormatCode: "wav"},         //nolint:mnd
+	{Name: "μ-law", Extension: ".ulaw", MimeType: "audio/basic", SampleRate: 24000, Bitrate: 8, FormatCode: "wav"},        //nolint:mnd
+	{Name: "A-law", Extension: ".alaw", MimeType: "audio/x-alaw-basic", SampleRate: 24000, Bitrate: 8, FormatCode: "wav"}, //nolint:mnd
+}
 
-	err := json.Unmarshal([]byte(voicesJSON), &voicesData)
+const (
+	openAPIEndpoint = "https://open.volcengineapi.com"
+	openAPIRegion   = "cn-north-1"
+	openAPIService  = "speech_saas_prod"
+	openAPIVersion  = "2023-11-07"
+)
+
+type OpenAPIResponseMetadata struct {
+	RequestID string `json:"RequestId"`
+	Action    string `json:"Action"`
+	Error     *struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	} `json:"Error,omitempty"`
+}
+
+type OpenAPIResponse[T any] struct {
+	ResponseMetadata OpenAPIResponseMetadata `json:"ResponseMetadata"`
+	Result           T                       `json:"Result"`
+}
+
+type OpenAPIResourcePack struct {
+	ResourceID        string `json:"ResourceID"`
+	Code              string `json:"Code"`
+	ConfigurationCode string `json:"ConfigurationCode"`
+	ResourceDisplay   string `json:"ResourceDisplay"`
+	Type              string `json:"Type"`
+	Expires           string `json:"Expires"`
+	Details           struct {
+		DemoLink            string `json:"DemoLink"`
+		Language            string `json:"Language"`
+		RecommendedScenario string `json:"RecommendedScenario"`
+		ToneNumber          string `json:"ToneNumber"`
+		VoiceType           string `json:"VoiceType"`
+	} `json:"Details"`
+	Alias string `json:"Alias"`
+	State string `json:"State"`
+}
+
+type OpenAPIResourcePacksStatusResult struct {
+	ResourcePacks []OpenAPIResourcePack `json:"ResourcePacks"`
+}
+
+type OpenAPIMegaTTSTrainStatus struct {
+	SpeakerID  string `json:"SpeakerID"`
+	State      string `json:"State"`
+	Alias      string `json:"Alias"`
+	DemoAudio  string `json:"DemoAudio"`
+	Version    string `json:"Version"`
+	ExpireTime int64  `json:"ExpireTime"`
+}
+
+type OpenAPIListMegaTTSTrainStatusResult struct {
+	Statuses []OpenAPIMegaTTSTrainStatus `json:"Statuses"`
+}
+
+// callOpenAPI calls the action of the speech OpenAPI with the V4 signature.
+func callOpenAPI[T any](c echo.Context, creds accessKeyCredentials, action string, body any) mo.Result[T] {
+	payload, err := json.Marshal(body)
 	if err != nil {
-		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+		return mo.Err[T](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	query := url.Values{}
+	query.Set("Action", action)
+	query.Set("Version", openAPIVersion)
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, openAPIEndpoint+"/?"+query.Encode(), bytes.NewBuffer(payload))
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	signRequest(req, payload, creds, openAPIRegion, openAPIService, time.Now())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithError(err).WithCaller())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	var response OpenAPIResponse[T]
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return mo.Err[T](apierrors.NewErrBadGateway().WithDetail(err.Error()).WithCaller())
+	}
+
+	if response.ResponseMetadata.Error != nil {
+		return mo.Err[T](apierrors.
+			NewUpstreamError(lo.Ternary(res.StatusCode >= 400, res.StatusCode, http.StatusBadGateway)).
+			WithDetailf("failed to call %s, code: %s, message: %s, request_id: %s",
+				action,
+				response.ResponseMetadata.Error.Code,
+				response.ResponseMetadata.Error.Message,
+				response.ResponseMetadata.RequestID,
+			))
+	}
+
+	return mo.Ok(response.Result)
+}
+
+func resourcePackToVoice(voice VoicesResponseDataResourcePack) types.Voice {
+	return types.Voice{
+		ID:          voice.Code,
+		Name:        voice.ResourceDisplay,
+		Description: voice.Details.Language + " " + voice.Details.RecommendedScenario,
+		Labels: map[string]any{
+			types.VoiceLabelKeyType:   voice.Details.VoiceType,
+			types.VoiceLabelKeyAccent: voice.Details.Language,
+			"configuration_code":      voice.ConfigurationCode,
+			"tone_number":             voice.Details.ToneNumber,
+			"tailoredScenarios":       voice.Details.RecommendedScenario,
+		},
+		Tags:             make([]string, 0),
+		Formats:          formats,
+		CompatibleModels: []string{"v1", "v3"},
+		PreviewAudioURL:  voice.Details.DemoLink,
+		Languages: []types.VoiceLanguage{
+			{
+				Title: voice.Details.Language,
+				Code:  voice.Details.Language,
+			},
+		},
+	}
+}
+
+// listLiveVoices lists the voices of the resource packs purchased by the account, and the cloned speakers
+// of the app.
+//
+// ResourcePacksStatus, ListMegaTTSTrainStatus - 豆包语音 OpenAPI - 火山引擎
+func listLiveVoices(c echo.Context, creds accessKeyCredentials, appID string) mo.Result[[]types.Voice] {
+	resourcePacks := callOpenAPI[OpenAPIResourcePacksStatusResult](c, creds, "ResourcePacksStatus", map[string]any{"AppID": appID})
+	if resourcePacks.IsError() {
+		return mo.Err[[]types.Voice](resourcePacks.Error())
 	}
 
-	voices := make([]types.Voice, 0, len(voicesData.Data.ResourcePacks))
+	voices := make([]types.Voice, 0, len(resourcePacks.MustGet().ResourcePacks))
+
+	for _, pack := range resourcePacks.MustGet().ResourcePacks {
+		// Resource packs of other services, e.g. recognition, don't come with a voice.
+		if pack.Details.VoiceType == "" && pack.Details.ToneNumber == "" {
+			continue
+		}
+
+		voice := VoicesResponseDataResourcePack{
+			ResourceID:        pack.ResourceID,
+			Code:              lo.CoalesceOrEmpty(pack.Code, pack.Details.VoiceType),
+			ConfigurationCode: pack.ConfigurationCode,
+			ResourceDisplay:   lo.CoalesceOrEmpty(pack.Alias, pack.ResourceDisplay),
+			Type:              pack.Type,
+			Expires:           pack.Expires,
+			Alias:             pack.Alias,
+			State:             pack.State,
+			// Same fields in PascalCase
+			Details: VoicesResponseDataResourcePackDetails(pack.Details),
+		}
+
+		voices = append(voices, resourcePackToVoice(voice))
+	}
+
+	if appID == "" {
+		return mo.Ok(voices)
+	}
+
+	// Cloned voices only work with the v3 API.
+	clonedSpeakers := callOpenAPI[OpenAPIListMegaTTSTrainStatusResult](c, creds, "ListMegaTTSTrainStatus", map[string]any{"AppID": appID, "SpeakerIDs": []string{}})
+	if clonedSpeakers.IsError() {
+		return mo.Err[[]types.Voice](clonedSpeakers.Error())
+	}
 
-	for _, voice := range voicesData.Data.ResourcePacks {
+	for _, speaker := range clonedSpeakers.MustGet().Statuses {
 		voices = append(voices, types.Voice{
-			ID:          voice.Code,
-			Name:        voice.ResourceDisplay,
-			Description: voice.Details.Language + " " + voice.Details.RecommendedScenario,
+			ID:          speaker.SpeakerID,
+			Name:        lo.CoalesceOrEmpty(speaker.Alias, speaker.SpeakerID),
+			Description: "cloned voice",
 			Labels: map[string]any{
-				types.VoiceLabelKeyType:   voice.Details.VoiceType,
-				types.VoiceLabelKeyAccent: voice.Details.Language,
-				"configuration_code":      voice.ConfigurationCode,
-				"tone_number":             voice.Details.ToneNumber,
-				"tailoredScenarios":       voice.Details.RecommendedScenario,
-			},
-			Tags: make([]string, 0),
-			Formats: []types.VoiceFormat{
-				// https://www.volcengine.com/docs/6561/1257584
-				{Name: "WAV", Extension: ".wav", MimeType: "audio/wav", SampleRate: 24000, Bitrate: 16, FormatCode: "wav"},         //nolint:mnd
-				{Name: "PCM", Extension: ".pcm", MimeType: "audio/pcm", SampleRate: 24000, Bitrate: 16, FormatCode: "pcm"},         //nolint:mnd
-				{Name: "Opus", Extension: ".opus", MimeType: "audio/opus", SampleRate: 24000, Bitrate: 16, FormatCode: "ogg_opus"}, //nolint:mnd
-				{Name: "MP3", Extension: ".mp3", MimeType: "audio/mp3", SampleRate: 24000, Bitrate: 16, FormatCode: "mp3"},         //nolint:mnd
-			},
-			CompatibleModels: []string{"v1"},
-			PreviewAudioURL:  voice.Details.DemoLink,
-			Languages: []types.VoiceLanguage{
-				{
-					Title: voice.Details.Language,
-					Code:  voice.Details.Language,
-				},
+				types.VoiceLabelKeyType: "cloned",
+				"state":                 speaker.State,
+				"version":               speaker.Version,
 			},
+			Tags:             make([]string, 0),
+			Formats:          formats,
+			CompatibleModels: []string{"v3"},
+			PreviewAudioURL:  speaker.DemoAudio,
+			Languages:        make([]types.VoiceLanguage, 0),
+		})
+	}
+
+	return mo.Ok(voices)
+}
+
+// HandleVoices lists the voices of the account when the Authorization header carries the AK/SK in the form
+// of `Bearer <AccessKeyID>:<SecretAccessKey>`, appid query parameter is needed for cloned voices. Otherwise
+// the embedded snapshot of the public voices is returned.
+func HandleVoices(c echo.Context, options mo.Option[types.VoicesRequestOptions]) mo.Result[any] {
+	creds := parseAccessKeyCredentials(c.Request().Header.Get("Authorization"))
+	if creds.IsPresent() {
+		voices := listLiveVoices(c, creds.MustGet(), options.MustGet().ExtraQuery.Get("appid"))
+		if voices.IsError() {
+			return mo.Err[any](voices.Error())
+		}
+
+		return mo.Ok[any](types.ListVoicesResponse{
+			Voices: voices.MustGet(),
 		})
 	}
 
+	var voicesData VoicesResponse
+
+	err := json.Unmarshal([]byte(voicesJSON), &voicesData)
+	if err != nil {
+		return mo.Err[any](apierrors.NewErrInternal().WithDetail(err.Error()).WithCaller())
+	}
+
 	return mo.Ok[any](types.ListVoicesResponse{
-		Voices: voices,
+		Voices: lo.Map(voicesData.Data.ResourcePacks, func(item VoicesResponseDataResourcePack, _ int) types.Voice {
+			return resourcePackToVoice(item)
+		}),
 	})
 }
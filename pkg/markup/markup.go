@@ -0,0 +1,415 @@
+// Package markup parses the subset of SSML accepted in the input of every backend, i.e. <break>,
+// <emphasis>, <say-as> and <sub> within plain text, and renders it into what a provider understands:
+// SSML, break tags, or plain text split at the pauses.
+package markup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samber/lo"
+
+	"github.com/moeru-ai/unspeech/pkg/utils"
+)
+
+const (
+	ElementBreak    = "break"
+	ElementEmphasis = "emphasis"
+	ElementSayAs    = "say-as"
+	ElementSub      = "sub"
+)
+
+// Element wrapping the input while parsing, as the input is a fragment without a root element.
+const markupRoot = "unspeech-markup"
+
+// Pauses longer than this are rejected, the same limit as Azure AI Speech.
+const MaxPause = 20 * time.Second
+
+var (
+	elementPattern = regexp.MustCompile(`<(break|emphasis|say-as|sub)[\s/>]`)
+
+	// Durations of the break strengths, <break/> alone is a medium one.
+	//
+	// Customize voice and sound with SSML - Speech service - Azure AI services | Microsoft Learn
+	// https://learn.microsoft.com/en-us/azure/ai-services/speech-service/speech-synthesis-markup-structure#add-a-break
+	breakStrengths = map[string]time.Duration{
+		"none":     0,
+		"x-weak":   250 * time.Millisecond, //nolint:mnd
+		"weak":     500 * time.Millisecond, //nolint:mnd
+		"medium":   750 * time.Millisecond, //nolint:mnd
+		"strong":   time.Second,
+		"x-strong": 1250 * time.Millisecond, //nolint:mnd
+	}
+
+	breakStrengthNames = []string{"none", "x-weak", "weak", "medium", "strong", "x-strong"}
+
+	emphasisLevels = []string{"strong", "moderate", "reduced", "none"}
+)
+
+// Node is either text or an element of the markup.
+type Node struct {
+	// Text of a text node, empty for elements.
+	Text string
+	// Name of an element, empty for text.
+	Element  string
+	Children []Node
+
+	// Pause of <break>.
+	Pause time.Duration
+	// Level of <emphasis>.
+	Level string
+	// Interpretation and format of <say-as>, e.g. characters, cardinal, date and telephone.
+	InterpretAs string
+	Format      string
+	// Alias of <sub>, read instead of the text.
+	Alias string
+}
+
+// Part is either text to synthesize or a pause between the text.
+type Part struct {
+	Text  string
+	Pause time.Duration
+}
+
+// Contains tells whether the input uses the markup. Whole SSML documents are left to the backends that
+// understand them.
+func Contains(input string) bool {
+	return !IsSSML(input) && elementPattern.MatchString(input)
+}
+
+// IsSSML tells whether the input is a whole SSML document, i.e. well-formed XML with <speak> as its only
+// root element, the same as what Microsoft takes as SSML.
+func IsSSML(input string) bool {
+	if !strings.Contains(input, "<speak") {
+		return false
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(input))
+	depth := 0
+	hasRoot := false
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return hasRoot
+		}
+		if err != nil {
+			return false
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if depth == 0 && (hasRoot || element.Name.Local != "speak") {
+				return false
+			}
+
+			hasRoot = true
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(element)) != "" {
+				return false
+			}
+		}
+	}
+}
+
+// Parse parses the markup in the input, other elements are rejected. Entities are decoded, a bare & is
+// kept as it is, <break> doesn't need to be self-closing, and elements left open are closed where their
+// parent ends.
+func Parse(input string) ([]Node, error) {
+	decoder := xml.NewDecoder(strings.NewReader("<" + markupRoot + ">" + input + "</" + markupRoot + ">"))
+	decoder.Strict = false
+	decoder.AutoClose = []string{ElementBreak}
+	decoder.Entity = xml.HTMLEntity
+
+	// Elements being parsed along with the children parsed so far, the first one is the root.
+	elements := []Node{{}}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Local == markupRoot && elements[0].Element == "" {
+				elements[0].Element = markupRoot
+
+				continue
+			}
+
+			element, err := parseElement(token)
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		case xml.EndElement:
+			if len(elements) == 1 {
+				continue
+			}
+
+			element := elements[len(elements)-1]
+			if element.Element == ElementBreak && len(element.Children) > 0 {
+				return nil, errors.New("<break> must be empty")
+			}
+
+			elements = elements[:len(elements)-1]
+			elements[len(elements)-1].Children = append(elements[len(elements)-1].Children, element)
+		case xml.CharData:
+			elements[len(elements)-1].Children = append(elements[len(elements)-1].Children, Node{Text: string(token)})
+		}
+	}
+
+	return elements[0].Children, nil
+}
+
+func parseElement(token xml.StartElement) (Node, error) {
+	attributes := lo.SliceToMap(token.Attr, func(item xml.Attr) (string, string) {
+		return item.Name.Local, item.Value
+	})
+
+	node := Node{Element: token.Name.Local}
+
+	switch node.Element {
+	case ElementBreak:
+		pause, ok := breakStrengths[lo.CoalesceOrEmpty(attributes["strength"], "medium")]
+		if !ok {
+			return node, fmt.Errorf("unsupported strength %q of <break>, supported ones are %s", attributes["strength"], strings.Join(breakStrengthNames, ", "))
+		}
+
+		if value, ok := attributes["time"]; ok {
+			var err error
+
+			pause, err = time.ParseDuration(value)
+			if err != nil || pause < 0 {
+				return node, fmt.Errorf("invalid time %q of <break>, e.g. 500ms or 1.5s", value)
+			}
+		}
+
+		if pause > MaxPause {
+			return node, fmt.Errorf("time of <break> must not exceed %s", MaxPause)
+		}
+
+		node.Pause = pause
+	case ElementEmphasis:
+		node.Level = lo.CoalesceOrEmpty(attributes["level"], "moderate")
+		if !lo.Contains(emphasisLevels, node.Level) {
+			return node, fmt.Errorf("unsupported level %q of <emphasis>, supported ones are %s", node.Level, strings.Join(emphasisLevels, ", "))
+		}
+	case ElementSayAs:
+		node.InterpretAs = attributes["interpret-as"]
+		node.Format = attributes["format"]
+
+		if node.InterpretAs == "" {
+			return node, errors.New("interpret-as of <say-as> is required")
+		}
+	case ElementSub:
+		node.Alias = attributes["alias"]
+		if node.Alias == "" {
+			return node, errors.New("alias of <sub> is required")
+		}
+	default:
+		return node, fmt.Errorf("unsupported element <%s>, supported ones are <break>, <emphasis>, <say-as> and <sub>", node.Element)
+	}
+
+	return node, nil
+}
+
+// SSML renders the nodes as the content of an SSML element, text is escaped.
+//
+// Speech Synthesis Markup Language (SSML) Version 1.1
+// https://www.w3.org/TR/speech-synthesis11/
+func SSML(nodes []Node) string {
+	buffer := new(strings.Builder)
+
+	for _, node := range nodes {
+		switch node.Element {
+		case "":
+			buffer.WriteString(escape(node.Text))
+		case ElementBreak:
+			fmt.Fprintf(buffer, "<break time='%dms'/>", node.Pause.Milliseconds())
+		case ElementEmphasis:
+			buffer.WriteString("<emphasis level='" + escape(node.Level) + "'>" + SSML(node.Children) + "</emphasis>")
+		case ElementSayAs:
+			buffer.WriteString("<say-as interpret-as='" + escape(node.InterpretAs) + "'")
+
+			if node.Format != "" {
+				buffer.WriteString(" format='" + escape(node.Format) + "'")
+			}
+
+			buffer.WriteString(">" + SSML(node.Children) + "</say-as>")
+		case ElementSub:
+			buffer.WriteString("<sub alias='" + escape(node.Alias) + "'>" + SSML(node.Children) + "</sub>")
+		}
+	}
+
+	return buffer.String()
+}
+
+// TextWithBreaks renders the nodes as plain text with the pauses rendered by breakTag, e.g. into the break
+// tags of a provider. <sub> is read as its alias, the other elements as their text.
+func TextWithBreaks(nodes []Node, breakTag func(pause time.Duration) string) string {
+	buffer := new(strings.Builder)
+
+	for _, node := range nodes {
+		switch node.Element {
+		case "":
+			buffer.WriteString(node.Text)
+		case ElementBreak:
+			buffer.WriteString(breakTag(node.Pause))
+		case ElementSub:
+			buffer.WriteString(node.Alias)
+		default:
+			buffer.WriteString(TextWithBreaks(node.Children, breakTag))
+		}
+	}
+
+	return buffer.String()
+}
+
+// PlainText renders the nodes as plain text without the pauses.
+func PlainText(nodes []Node) string {
+	return TextWithBreaks(nodes, func(time.Duration) string { return "" })
+}
+
+// Split renders the nodes as plain text split at the pauses, so that the pauses can be inserted as silence
+// between the audio of the text. Consecutive pauses are merged, text with nothing to read is dropped.
+func Split(nodes []Node) []Part {
+	parts := make([]Part, 0)
+	text := new(strings.Builder)
+
+	flush := func() {
+		if strings.TrimSpace(text.String()) != "" {
+			parts = append(parts, Part{Text: text.String()})
+		}
+
+		text.Reset()
+	}
+
+	var walk func(nodes []Node)
+
+	walk = func(nodes []Node) {
+		for _, node := range nodes {
+			switch node.Element {
+			case "":
+				text.WriteString(node.Text)
+			case ElementBreak:
+				flush()
+
+				if node.Pause == 0 {
+					continue
+				}
+
+				if len(parts) > 0 && parts[len(parts)-1].Text == "" {
+					parts[len(parts)-1].Pause += node.Pause
+				} else {
+					parts = append(parts, Part{Pause: node.Pause})
+				}
+			case ElementSub:
+				text.WriteString(node.Alias)
+			default:
+				walk(node.Children)
+			}
+		}
+	}
+
+	walk(nodes)
+	flush()
+
+	return parts
+}
+
+// Chunk groups the nodes into chunks of at most maxLength characters of plain text, so that longer input
+// can be synthesized in pieces without losing the markup. Text is split at sentences and <emphasis> into
+// several ones of the same level, the other elements are never split. Pauses stay with the text around
+// them, a maxLength of 0 means no limit.
+func Chunk(nodes []Node, maxLength int) [][]Node {
+	if maxLength <= 0 {
+		return [][]Node{nodes}
+	}
+
+	chunks := make([][]Node, 0)
+	current := make([]Node, 0)
+	length := 0
+
+	// Pauses alone are carried over to the next chunk, as there is nothing to read in them.
+	flush := func() {
+		if strings.TrimSpace(PlainText(current)) == "" {
+			return
+		}
+
+		chunks = append(chunks, current)
+		current = make([]Node, 0)
+		length = 0
+	}
+
+	for _, node := range nodes {
+		nodeLength := utf8.RuneCountInString(PlainText([]Node{node}))
+		if length+nodeLength > maxLength {
+			flush()
+		}
+
+		pieces := lo.Ternary(nodeLength > maxLength, splitNode(node, maxLength), [][]Node{{node}})
+		for i, piece := range pieces {
+			current = append(current, piece...)
+			length += utf8.RuneCountInString(PlainText(piece))
+
+			if i < len(pieces)-1 {
+				flush()
+			}
+		}
+	}
+
+	switch {
+	case strings.TrimSpace(PlainText(current)) != "":
+		chunks = append(chunks, current)
+	case len(chunks) > 0:
+		chunks[len(chunks)-1] = append(chunks[len(chunks)-1], current...)
+	}
+
+	return chunks
+}
+
+// splitNode splits text and <emphasis> longer than maxLength into pieces short enough, the whitespace
+// around the text is kept to separate it from the nodes next to it.
+func splitNode(node Node, maxLength int) [][]Node {
+	switch node.Element {
+	case "":
+		texts := utils.ChunkText(node.Text, maxLength)
+		texts[0] = node.Text[:len(node.Text)-len(strings.TrimLeftFunc(node.Text, unicode.IsSpace))] + texts[0]
+		texts[len(texts)-1] += node.Text[len(strings.TrimRightFunc(node.Text, unicode.IsSpace)):]
+
+		return lo.Map(texts, func(item string, _ int) []Node { return []Node{{Text: item}} })
+	case ElementEmphasis:
+		return lo.Map(Chunk(node.Children, maxLength), func(item []Node, _ int) []Node {
+			emphasis := node
+			emphasis.Children = item
+
+			return []Node{emphasis}
+		})
+	default:
+		return [][]Node{{node}}
+	}
+}
+
+func escape(text string) string {
+	buffer := new(bytes.Buffer)
+	_ = xml.EscapeText(buffer, []byte(text))
+
+	return buffer.String()
+}
@@ -0,0 +1,175 @@
+package markup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContains(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Contains(`Hello <break time="1s"/> world`))
+	assert.True(t, Contains(`<emphasis>Hello</emphasis>`))
+	assert.False(t, Contains(`Hello world`))
+	assert.False(t, Contains(`a <b and <breakfast>`))
+	assert.False(t, Contains(`<speak version="1.0"><break time="1s"/></speak>`))
+	assert.True(t, Contains(`<speaker>Hello <break time="1s"/> world`))
+}
+
+func TestIsSSML(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsSSML(`<speak version="1.0"><voice name="en-US-AvaNeural">Hello</voice></speak>`))
+	assert.True(t, IsSSML("<?xml version=\"1.0\"?>\n<speak>Hello</speak>\n"))
+	assert.False(t, IsSSML(`Hello world`))
+	assert.False(t, IsSSML(`<speaker>Hello</speaker>`))
+	assert.False(t, IsSSML(`Say <speak>Hello</speak>`))
+	assert.False(t, IsSSML(`<speak>Hello</speak><speak>World</speak>`))
+	assert.False(t, IsSSML(`<speak>Hello`))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Elements", func(t *testing.T) {
+		t.Parallel()
+
+		nodes, err := Parse(`Hi &amp; AT&T <break time="500ms"/><emphasis level="strong">now</emphasis>, <say-as interpret-as="date" format="ymd">2024-01-02</say-as> <sub alias="World Wide Web">WWW</sub><break strength="weak">`)
+		require.NoError(t, err)
+		assert.Equal(t, []Node{
+			{Text: "Hi & AT&T "},
+			{Element: ElementBreak, Pause: 500 * time.Millisecond},
+			{Element: ElementEmphasis, Level: "strong", Children: []Node{{Text: "now"}}},
+			{Text: ", "},
+			{Element: ElementSayAs, InterpretAs: "date", Format: "ymd", Children: []Node{{Text: "2024-01-02"}}},
+			{Text: " "},
+			{Element: ElementSub, Alias: "World Wide Web", Children: []Node{{Text: "WWW"}}},
+			{Element: ElementBreak, Pause: 500 * time.Millisecond},
+		}, nodes)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		t.Parallel()
+
+		nodes, err := Parse(`<break/><emphasis>a</emphasis>`)
+		require.NoError(t, err)
+		assert.Equal(t, 750*time.Millisecond, nodes[0].Pause)
+		assert.Equal(t, "moderate", nodes[1].Level)
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		t.Parallel()
+
+		nodes, err := Parse(`1 &lt; 2 & <emphasis>3`)
+		require.NoError(t, err)
+		assert.Equal(t, "1 < 2 & 3", PlainText(nodes))
+	})
+
+	for name, input := range map[string]string{
+		"UnsupportedElement": `<voice name="x">a</voice>`,
+		"InvalidTime":        `<break time="soon"/>`,
+		"TooLong":            `<break time="1m"/>`,
+		"InvalidStrength":    `<break strength="loud"/>`,
+		"InvalidLevel":       `<emphasis level="loud">a</emphasis>`,
+		"MissingAlias":       `<sub>a</sub>`,
+		"MissingInterpretAs": `<say-as>a</say-as>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	nodes, err := Parse(`<break time="1s"/>A <emphasis>&lt;b&gt;</emphasis> <break/><break time="250ms"/><say-as interpret-as="characters">c</say-as> <sub alias="dee">d</sub>`)
+	require.NoError(t, err)
+
+	assert.Equal(t, `<break time='1000ms'/>A <emphasis level='moderate'>&lt;b&gt;</emphasis> <break time='750ms'/><break time='250ms'/><say-as interpret-as='characters'>c</say-as> <sub alias='dee'>d</sub>`, SSML(nodes))
+	assert.Equal(t, `A <b> c dee`, PlainText(nodes))
+	assert.Equal(t, `[1s]A <b> [750ms][250ms]c dee`, TextWithBreaks(nodes, func(pause time.Duration) string { return "[" + pause.String() + "]" }))
+	assert.Equal(t, []Part{
+		{Pause: time.Second},
+		{Text: "A <b> "},
+		{Pause: time.Second},
+		{Text: "c dee"},
+	}, Split(nodes))
+}
+
+func TestChunk(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input     string
+		maxLength int
+		expected  []string
+	}{
+		"fits": {
+			input:     `One <emphasis>two</emphasis>.`,
+			maxLength: 100,
+			expected:  []string{`One <emphasis level='moderate'>two</emphasis>.`},
+		},
+		"no limit": {
+			input:     `One <emphasis>two</emphasis>.`,
+			maxLength: 0,
+			expected:  []string{`One <emphasis level='moderate'>two</emphasis>.`},
+		},
+		"between elements": {
+			input:     `<say-as interpret-as="characters">abc</say-as> <break time="1s"/><sub alias="World Wide Web">WWW</sub>`,
+			maxLength: 14,
+			expected: []string{
+				`<say-as interpret-as='characters'>abc</say-as> <break time='1000ms'/>`,
+				`<sub alias='World Wide Web'>WWW</sub>`,
+			},
+		},
+		"text split at sentences": {
+			input:     `One. Two. Three. <emphasis>Four.</emphasis>`,
+			maxLength: 10,
+			expected: []string{
+				`One. Two.`,
+				`Three. `,
+				`<emphasis level='moderate'>Four.</emphasis>`,
+			},
+		},
+		"emphasis split": {
+			input:     `<emphasis level="strong">One. Two.</emphasis> Six.`,
+			maxLength: 5,
+			expected: []string{
+				`<emphasis level='strong'>One.</emphasis>`,
+				`<emphasis level='strong'>Two.</emphasis>`,
+				` Six.`,
+			},
+		},
+		"pauses carried over": {
+			input:     `<break time="1s"/>One. Two.<break time="2s"/>`,
+			maxLength: 5,
+			expected: []string{
+				`<break time='1000ms'/>One.`,
+				`Two.<break time='2000ms'/>`,
+			},
+		},
+		"elements never split": {
+			input:     `<sub alias="World Wide Web">WWW</sub>`,
+			maxLength: 5,
+			expected:  []string{`<sub alias='World Wide Web'>WWW</sub>`},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := Parse(tc.input)
+			require.NoError(t, err)
+
+			chunks := Chunk(nodes, tc.maxLength)
+			assert.Equal(t, tc.expected, lo.Map(chunks, func(item []Node, _ int) string { return SSML(item) }))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StreamWithFlush works like echo.Context.Stream, but flushes the response after every chunk
+// read from r, so that the client receives audio as soon as the upstream produces it.
+//
+// NOTICE: status code and headers are committed with the first write, errors occurred after
+// that can no longer be reported to the client, therefore they are logged and swallowed.
+func StreamWithFlush(c echo.Context, code int, contentType string, r io.Reader) error {
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().WriteHeader(code)
+	c.Response().Flush()
+
+	buffer := make([]byte, 32*1024) //nolint:mnd
+
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			_, writeErr := c.Response().Write(buffer[:n])
+			if writeErr != nil {
+				slog.Warn("failed to write streamed response", slog.String("error", writeErr.Error()))
+				return nil
+			}
+
+			c.Response().Flush()
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			slog.Error("stream interrupted after response was committed", slog.String("error", err.Error()))
+			return nil
+		}
+	}
+}
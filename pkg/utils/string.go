@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -351,6 +352,30 @@ func FromStringOrEmpty[T any](str string) T {
 	return val
 }
 
+// ToString formats the value as a form field, objects and arrays are encoded as JSON, and numbers are never
+// in exponent notation, e.g. the values of extra_body decoded from JSON. nil has no form, callers should
+// leave the field out instead.
+func ToString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	}
+
+	switch reflect.ValueOf(value).Kind() { //nolint:exhaustive
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		data, err := json.Marshal(value)
+		if err == nil {
+			return string(data)
+		}
+	}
+
+	return fmt.Sprint(value)
+}
+
 func IsNumber(str string) bool {
 	_, err := strconv.ParseFloat(str, 64)
 
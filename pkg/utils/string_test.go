@@ -622,3 +622,20 @@ func TestFromStringOrEmpty(t *testing.T) {
 		assert.Equal(t, "abcd", FromStringOrEmpty[*strings.Builder]("abcd").String())
 	})
 }
+
+func TestToString(t *testing.T) {
+	t.Parallel()
+
+	for expected, value := range map[string]any{
+		"hello":                 "hello",
+		"true":                  true,
+		"2":                     2,
+		"1000000":               float64(1000000),
+		"0.5":                   0.5,
+		`["en","ja"]`:           []any{"en", "ja"},
+		`{"speakers":2}`:        map[string]any{"speakers": 2},
+		`[{"text":"unspeech"}]`: []map[string]string{{"text": "unspeech"}},
+	} {
+		assert.Equal(t, expected, ToString(value))
+	}
+}
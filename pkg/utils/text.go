@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samber/lo"
+)
+
+const (
+	// Full-width terminators end a sentence wherever they are, as CJK text has no spaces between sentences.
+	fullWidthTerminators = "。！？；…\n"
+	// ASCII terminators only end a sentence when followed by a space, so that numbers like 3.14 stay intact.
+	asciiTerminators = ".!?;"
+	// Closing quotes and brackets right after a terminator belong to the sentence.
+	sentenceClosers = "\"')]}」』）】》〉”’"
+	// Clauses are where long sentences are split.
+	clauseSeparators = "，、,：:—"
+)
+
+// SplitSentences splits text after sentence terminators, both CJK full-width ones and ASCII ones, the
+// sentences joined back together are the text.
+func SplitSentences(text string) []string {
+	runes := []rune(text)
+	sentences := make([]string, 0)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		terminated := strings.ContainsRune(fullWidthTerminators, runes[i]) ||
+			(strings.ContainsRune(asciiTerminators, runes[i]) && (i == len(runes)-1 || unicode.IsSpace(runes[i+1]) || strings.ContainsRune(sentenceClosers, runes[i+1])))
+		if !terminated {
+			continue
+		}
+
+		for i+1 < len(runes) && (strings.ContainsRune(sentenceClosers, runes[i+1]) || runes[i+1] == runes[i]) {
+			i++
+		}
+
+		if strings.TrimSpace(string(runes[start:i+1])) != "" {
+			sentences = append(sentences, string(runes[start:i+1]))
+			start = i + 1
+		}
+	}
+
+	if strings.TrimSpace(string(runes[start:])) != "" {
+		sentences = append(sentences, string(runes[start:]))
+	} else if len(sentences) > 0 {
+		sentences[len(sentences)-1] += string(runes[start:])
+	}
+
+	return sentences
+}
+
+// ChunkText groups the sentences of text into chunks of at most maxLength characters. Sentences longer
+// than that are split at clauses, then at spaces, and cut anywhere as the last resort. Chunks are trimmed,
+// a maxLength of 0 means no limit.
+func ChunkText(text string, maxLength int) []string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return []string{text}
+	}
+
+	chunks := make([]string, 0)
+	current := new(strings.Builder)
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		current.Reset()
+	}
+
+	for _, sentence := range SplitSentences(text) {
+		for _, piece := range splitLonger(sentence, maxLength, 0) {
+			if utf8.RuneCountInString(strings.TrimSpace(current.String()+piece)) > maxLength {
+				flush()
+			}
+
+			current.WriteString(piece)
+		}
+	}
+
+	flush()
+
+	return chunks
+}
+
+// splitLonger splits text longer than maxLength at the boundaries of the level, clauses first, then
+// spaces, pieces still too long go down to the next level, the last of which cuts anywhere.
+func splitLonger(text string, maxLength int, level int) []string {
+	if utf8.RuneCountInString(strings.TrimSpace(text)) <= maxLength {
+		return []string{text}
+	}
+
+	runes := []rune(text)
+
+	if level >= 2 { //nolint:mnd
+		pieces := make([]string, 0, len(runes)/maxLength+1)
+
+		for start := 0; start < len(runes); start += maxLength {
+			pieces = append(pieces, string(runes[start:min(len(runes), start+maxLength)]))
+		}
+
+		return pieces
+	}
+
+	isBoundary := lo.Ternary(level == 0, func(r rune) bool { return strings.ContainsRune(clauseSeparators, r) }, unicode.IsSpace)
+	pieces := make([]string, 0)
+	start := 0
+
+	for i, r := range runes {
+		if isBoundary(r) || i == len(runes)-1 {
+			pieces = append(pieces, splitLonger(string(runes[start:i+1]), maxLength, level+1)...)
+			start = i + 1
+		}
+	}
+
+	return pieces
+}
+
+// isCJK tells whether the rune is written without spaces around it, i.e. Chinese characters, kana, and
+// CJK or full-width punctuation.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK Symbols and Punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // Halfwidth and Fullwidth Forms
+}
+
+// JoinSentences joins sentences recognized one by one into text, separated by a space unless both sides
+// are CJK, which has no spaces between sentences.
+func JoinSentences(sentences []string) string {
+	text := new(strings.Builder)
+
+	for _, sentence := range sentences {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+
+		if text.Len() > 0 {
+			last, _ := utf8.DecodeLastRuneInString(text.String())
+			first, _ := utf8.DecodeRuneInString(sentence)
+
+			if !isCJK(last) || !isCJK(first) {
+				text.WriteString(" ")
+			}
+		}
+
+		text.WriteString(sentence)
+	}
+
+	return text.String()
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSentences(t *testing.T) {
+	t.Parallel()
+
+	for text, expected := range map[string][]string{
+		"Hello world. It costs 3.14 dollars! Really?": {"Hello world.", " It costs 3.14 dollars!", " Really?"},
+		"你好。今天天气怎么样？很好！":                              {"你好。", "今天天气怎么样？", "很好！"},
+		"「こんにちは。」と言った。":                               {"「こんにちは。」", "と言った。"},
+		"He said \"Stop.\" Then left":                 {"He said \"Stop.\"", " Then left"},
+		"Wait... what?!":                              {"Wait...", " what?!"},
+		"え……そうなの？\n\nはい。 ":                            {"え……", "そうなの？", "\n\nはい。 "},
+		"":                                            {},
+	} {
+		sentences := SplitSentences(text)
+		assert.Equal(t, expected, sentences, text)
+		assert.Equal(t, text, strings.Join(sentences, ""))
+	}
+}
+
+func TestJoinSentences(t *testing.T) {
+	t.Parallel()
+
+	for expected, sentences := range map[string][]string{
+		"Hello world. How are you?": {"Hello world.", "How are you?"},
+		"你好。今天天气怎么样？":               {"你好。", "今天天气怎么样？"},
+		"こんにちは。 Hello.":             {"こんにちは。", "Hello."},
+		"Hello. 你好。":                {"Hello.", "你好。"},
+		"One. Two.":                 {" One. ", "", "Two."},
+		"":                          {},
+	} {
+		assert.Equal(t, expected, JoinSentences(sentences))
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Short", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{" Hello. "}, ChunkText(" Hello. ", 10))
+		assert.Equal(t, []string{"Hello. World."}, ChunkText("Hello. World.", 0))
+	})
+
+	t.Run("Sentences", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"One. Two.", "Three. Four.", "Five."}, ChunkText("One. Two. Three. Four. Five.", 12))
+		assert.Equal(t, []string{"第一句。第二句。", "第三句。"}, ChunkText("第一句。第二句。第三句。", 8))
+	})
+
+	t.Run("Clauses", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"春眠不觉晓，处处闻啼鸟，", "夜来风雨声，花落知多少。"}, ChunkText("春眠不觉晓，处处闻啼鸟，夜来风雨声，花落知多少。", 12))
+		assert.Equal(t, []string{"one two three", "four five six."}, ChunkText("one two three four five six.", 14))
+	})
+
+	t.Run("Cut", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"あいう", "えおか", "きく"}, ChunkText("あいうえおかきく", 3))
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		t.Parallel()
+
+		text := strings.Repeat("The quick brown fox jumps over the lazy dog, again and again. 敏捷的棕色狐狸跳过了懒狗。", 20)
+
+		chunks := ChunkText(text, 50)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, utf8.RuneCountInString(chunk), 50)
+			assert.NotEmpty(t, chunk)
+		}
+
+		assert.Equal(t, strings.ReplaceAll(text, " ", ""), strings.ReplaceAll(strings.Join(chunks, ""), " ", ""))
+	})
+}